@@ -5,23 +5,39 @@ package collector
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"sync"
 
 	"github.com/carabiner-dev/attestation"
 	"github.com/carabiner-dev/signer/key"
 	"github.com/nozzle/throttler"
+	"github.com/sigstore/sigstore-go/pkg/root"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/carabiner-dev/collector/envelope"
 	"github.com/carabiner-dev/collector/filters"
+	"github.com/carabiner-dev/collector/internal/readlimit"
 	"github.com/carabiner-dev/collector/repository"
 )
 
 var (
 	ErrNoFetcherConfigured = errors.New("no repository with fetch capabilities configured")
 	ErrNoStorerConfigured  = errors.New("no repository with store capabilities configured")
+
+	// ErrOfflineMode is returned when an operation requires network access
+	// but the agent runs in offline mode.
+	ErrOfflineMode = repository.ErrOfflineMode
+
+	// ErrReadBudgetExhausted is returned by streams when the read budget
+	// ran out and some sources were not read completely.
+	ErrReadBudgetExhausted = errors.New("read budget exhausted")
 )
 
 // New returns a new agent with the default options
@@ -32,7 +48,7 @@ func New(funcs ...InitFunction) (*Agent, error) {
 			return nil, err
 		}
 	}
-	agent.distributeKeysTo(agent.Repositories...)
+	agent.configureRepos(agent.Repositories...)
 	return agent, nil
 }
 
@@ -55,6 +71,50 @@ type Agent struct {
 	Options      Options
 	Cache        Cache
 	Repositories []attestation.Repository
+
+	// repoInits stores the init strings of the repositories to identify
+	// them in reports and envelope sources.
+	repoInits  map[any]string
+	initsMutex sync.Mutex
+
+	// repoTiers stores the priority tier of the repositories, see
+	// SetRepositoryTier.
+	repoTiers map[any]int
+
+	// repoPolicies stores the call policies of the repositories, see
+	// SetRepositoryPolicy. defaultPolicy enforces Options.RepositoryPolicy.
+	repoPolicies  map[any]*repoPolicy
+	defaultPolicy *repoPolicy
+
+	// fetchGroup deduplicates concurrent fetches of the same query
+	fetchGroup singleflight.Group
+}
+
+// configureRepos sends the agent's settings to the repositories implementing
+// the optional interfaces of the repository package.
+func (agent *Agent) configureRepos(repos ...attestation.Repository) {
+	agent.distributeKeysTo(repos...)
+	for _, r := range repos {
+		if ts, ok := r.(repository.TrustedRootSetter); ok && agent.Options.TrustedRoot != nil {
+			ts.SetTrustedRoot(agent.Options.TrustedRoot)
+		}
+		if oa, ok := r.(repository.OfflineAware); ok && agent.Options.Offline {
+			oa.SetOffline(true)
+		}
+	}
+}
+
+// checkOffline returns ErrOfflineMode when the agent runs in offline mode and
+// the repository does not implement the repository.OfflineAware interface,
+// as it can't guarantee it won't access the network.
+func (agent *Agent) checkOffline(r any) error {
+	if !agent.Options.Offline {
+		return nil
+	}
+	if _, ok := r.(repository.OfflineAware); !ok {
+		return fmt.Errorf("repository does not support offline mode: %w", ErrOfflineMode)
+	}
+	return nil
 }
 
 // distributeKeysTo sends the agent's verification keys to the given
@@ -71,12 +131,20 @@ func (agent *Agent) distributeKeysTo(repos ...attestation.Repository) {
 }
 
 func (agent *Agent) AddRepositoryFromString(init string) error {
-	repo, err := RepositoryFromString(init)
+	return agent.AddRepositoryFromStringWithOptions(init, nil)
+}
+
+// AddRepositoryFromStringWithOptions builds a repository from an init string
+// and a set of driver options and adds it to the agent. See
+// RepositoryFromStringWithOptions for details on the options.
+func (agent *Agent) AddRepositoryFromStringWithOptions(init string, opts map[string]string) error {
+	repo, err := RepositoryFromStringWithOptions(init, opts)
 	if err != nil {
 		return fmt.Errorf("building repo: %w", err)
 	}
 	agent.Repositories = append(agent.Repositories, repo)
-	agent.distributeKeysTo(repo)
+	agent.setRepoInit(repo, init)
+	agent.configureRepos(repo)
 	return nil
 }
 
@@ -90,284 +158,583 @@ func (agent *Agent) AddKeys(keys ...key.PublicKeyProvider) {
 // AddRepsitory adds a new repository to collect attestations
 func (agent *Agent) AddRepository(repos ...attestation.Repository) error {
 	agent.Repositories = append(agent.Repositories, repos...)
-	agent.distributeKeysTo(repos...)
+	agent.configureRepos(repos...)
 	return nil
 }
 
 // Fetch is a general attestation fetcher. It is intended to return attestations
 // in the preferred order of the driver without any optimization whatsoever.
+//
+// If any repository fails, Fetch returns the attestations collected from the
+// rest along with an error. Use FetchWithReport to get the details of each
+// repository.
 func (agent *Agent) Fetch(ctx context.Context, optFn ...FetchOptionsFunc) ([]attestation.Envelope, error) {
-	mutex := sync.Mutex{}
-	ret := []attestation.Envelope{}
+	ret, report, err := agent.FetchWithReport(ctx, optFn...)
+	if err != nil {
+		return nil, err
+	}
+	return ret, report.Err()
+}
 
+// FetchWithReport works like Fetch but it does not fail when a repository
+// returns an error. Instead, it returns the attestations collected from the
+// healthy repositories and a FetchReport with the outcome of each one. The
+// returned error is only set when the fetch could not run at all.
+func (agent *Agent) FetchWithReport(ctx context.Context, optFn ...FetchOptionsFunc) ([]attestation.Envelope, *FetchReport, error) {
 	// Filter the repos to get the fetchers
 	repos := agent.fetcherRepos()
 	if len(repos) == 0 {
-		return nil, ErrNoFetcherConfigured
+		return nil, nil, ErrNoFetcherConfigured
 	}
 
-	opts := agent.Options.Fetch
-	opts.MaxReadSize = agent.Options.MaxReadSize
-	if len(optFn) > 0 {
-		for _, o := range optFn {
-			o(&opts)
+	opts := agent.fetchOptions(optFn...)
+
+	ret, report := agent.fetchFrom(ctx, repos, opts, fetchAll, anyResults)
+	return agent.finalizeResults(ret, opts), report, nil
+}
+
+// FetchAttestationsBySubject requests all attestations about a list of subjects
+// from the configured repositories. It is understood that the repos will return
+// all attestations available about the specified subjects.
+//
+// If any repository fails, an error is returned. Use
+// FetchAttestationsBySubjectWithReport to get partial results.
+func (agent *Agent) FetchAttestationsBySubject(ctx context.Context, subjects []attestation.Subject, optFn ...FetchOptionsFunc) ([]attestation.Envelope, error) {
+	ret, report, err := agent.FetchAttestationsBySubjectWithReport(ctx, subjects, optFn...)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.Err(); err != nil {
+		return nil, fmt.Errorf("fetching attestations: %w", err)
+	}
+	return ret, nil
+}
+
+// FetchAttestationsBySubjectWithReport works like FetchAttestationsBySubject
+// but it returns the attestations collected from the healthy repositories
+// along with a FetchReport detailing the outcome of each repository. Partial
+// results are never stored in the cache.
+func (agent *Agent) FetchAttestationsBySubjectWithReport(ctx context.Context, subjects []attestation.Subject, optFn ...FetchOptionsFunc) ([]attestation.Envelope, *FetchReport, error) {
+	// Filter the repos to get the fetchers
+	repos := agent.fetcherRepos()
+	if len(repos) == 0 {
+		if agent.Options.FailIfNoFetchers {
+			return nil, nil, ErrNoFetcherConfigured
 		}
+		logrus.Debugf("WARN: No fetcher repos configured")
+		return []attestation.Envelope{}, &FetchReport{Repositories: []RepositoryReport{}}, nil
 	}
 
-	t := throttler.New(agent.Options.ParallelFetches, len(repos))
+	opts := agent.fetchOptions(optFn...)
+	ret, report, err := agent.fetchCached(
+		ctx, opts, "FetchAttestationsBySubject", cacheQuerySubject+"\n"+subjectsToKey(subjects),
+		func(ctx context.Context) (*[]attestation.Envelope, error) {
+			return agent.Cache.GetAttestationsBySubject(ctx, subjects)
+		},
+		func(ctx context.Context, atts *[]attestation.Envelope) error {
+			return agent.Cache.StoreAttestationsBySubject(ctx, subjects, atts)
+		},
+		func(ctx context.Context) ([]attestation.Envelope, *FetchReport) {
+			return agent.fetchFrom(ctx, repos, noQueryNoLimit(opts), subjectFetchFunc(subjects), anyResults)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	for _, r := range repos {
-		go func(r attestation.Fetcher) {
-			// Call the repo driver's fetch method
-			atts, err := r.Fetch(ctx, opts)
-			if err != nil {
-				t.Done(err)
-				return
-			}
+	return agent.finalizeResults(ret, opts), report, nil
+}
 
-			mutex.Lock()
-			ret = append(ret, atts...)
-			mutex.Unlock()
-			t.Done(nil)
-		}(r)
-		t.Throttle()
+// FetchAttestationsByPredicateType requests all attestations of a particular type
+// from the configured repositories.
+//
+// If any repository fails, an error is returned. Use
+// FetchAttestationsByPredicateTypeWithReport to get partial results.
+func (agent *Agent) FetchAttestationsByPredicateType(ctx context.Context, pt []attestation.PredicateType, optFn ...FetchOptionsFunc) ([]attestation.Envelope, error) {
+	ret, report, err := agent.FetchAttestationsByPredicateTypeWithReport(ctx, pt, optFn...)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.Err(); err != nil {
+		return nil, fmt.Errorf("fetching attestations: %w", err)
 	}
+	return ret, nil
+}
 
-	if opts.Query != nil {
-		ret = opts.Query.Run(ret)
+// FetchAttestationsByPredicateTypeWithReport works like
+// FetchAttestationsByPredicateType but it returns the attestations collected
+// from the healthy repositories along with a FetchReport detailing the outcome
+// of each repository. Partial results are never stored in the cache.
+func (agent *Agent) FetchAttestationsByPredicateTypeWithReport(ctx context.Context, pt []attestation.PredicateType, optFn ...FetchOptionsFunc) ([]attestation.Envelope, *FetchReport, error) {
+	// Filter the repos to get the fetchers
+	repos := agent.fetcherRepos()
+	if len(repos) == 0 {
+		return nil, nil, ErrNoFetcherConfigured
 	}
 
-	if opts.Limit != 0 {
-		ret = ret[0:opts.Limit]
+	opts := agent.fetchOptions(optFn...)
+	ret, report, err := agent.fetchCached(
+		ctx, opts, "FetchAttestationsByPredicateType", cacheQueryPredicateType+"\n"+buildKey(pt),
+		func(ctx context.Context) (*[]attestation.Envelope, error) {
+			return agent.Cache.GetAttestationsByPredicateType(ctx, pt)
+		},
+		func(ctx context.Context, atts *[]attestation.Envelope) error {
+			return agent.Cache.StoreAttestationsByPredicateType(ctx, pt, atts)
+		},
+		func(ctx context.Context) ([]attestation.Envelope, *FetchReport) {
+			return agent.fetchFrom(ctx, repos, noQueryNoLimit(opts), predicateTypeFetchFunc(pt), coversPredicateTypes(pt))
+		},
+	)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return ret, t.Err()
+	return agent.finalizeResults(ret, opts), report, nil
 }
 
-// fetchMutex protects the entire fetch operation to prevent concurrent fetches
-// from seeing partial cache results
-var fetchMutex sync.Mutex
-
-// FetchAttestationsBySubject requests all attestations about a list of subjects
-// from the configured repositories. It is understood that the repos will return
-// all attestations available about the specified subjects.
-func (agent *Agent) FetchAttestationsBySubject(ctx context.Context, subjects []attestation.Subject, optFn ...FetchOptionsFunc) ([]attestation.Envelope, error) {
-	// Lock the entire fetch operation to prevent cache races
-	mutex := sync.Mutex{}
-	ret := []attestation.Envelope{}
+// FetchAttestationsBySubjectAndPredicateType requests the attestations of any
+// of the predicate types about any of the subjects from the configured
+// repositories. Repositories implementing the combined
+// attestation.FetcherByPredicateTypeAndSubject interface are queried
+// directly, the results of the rest are filtered by the agent.
+//
+// If any repository fails, an error is returned. Use
+// FetchAttestationsBySubjectAndPredicateTypeWithReport to get partial results.
+func (agent *Agent) FetchAttestationsBySubjectAndPredicateType(ctx context.Context, subjects []attestation.Subject, pt []attestation.PredicateType, optFn ...FetchOptionsFunc) ([]attestation.Envelope, error) {
+	ret, report, err := agent.FetchAttestationsBySubjectAndPredicateTypeWithReport(ctx, subjects, pt, optFn...)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.Err(); err != nil {
+		return nil, fmt.Errorf("fetching attestations: %w", err)
+	}
+	return ret, nil
+}
 
+// FetchAttestationsBySubjectAndPredicateTypeWithReport works like
+// FetchAttestationsBySubjectAndPredicateType but it returns the attestations
+// collected from the healthy repositories along with a FetchReport detailing
+// the outcome of each repository. Partial results are never stored in the
+// cache.
+func (agent *Agent) FetchAttestationsBySubjectAndPredicateTypeWithReport(ctx context.Context, subjects []attestation.Subject, pt []attestation.PredicateType, optFn ...FetchOptionsFunc) ([]attestation.Envelope, *FetchReport, error) {
 	// Filter the repos to get the fetchers
 	repos := agent.fetcherRepos()
 	if len(repos) == 0 {
-		if agent.Options.FailIfNoFetchers {
-			return nil, ErrNoFetcherConfigured
-		}
-		logrus.Debugf("WARN: No fetcher repos configured")
-		return ret, nil
+		return nil, nil, ErrNoFetcherConfigured
 	}
 
-	opts := agent.Options.Fetch
-	opts.MaxReadSize = agent.Options.MaxReadSize
-	for _, f := range optFn {
-		f(&opts)
+	opts := agent.fetchOptions(optFn...)
+	ret, report, err := agent.fetchCached(
+		ctx, opts, "FetchAttestationsBySubjectAndPredicateType",
+		cacheQuerySubjectPredicateType+"\n"+subjectAndPredicateTypeKey(subjects, pt),
+		func(ctx context.Context) (*[]attestation.Envelope, error) {
+			return agent.Cache.GetAttestationsBySubjectAndPredicateType(ctx, subjects, pt)
+		},
+		func(ctx context.Context, atts *[]attestation.Envelope) error {
+			return agent.Cache.StoreAttestationsBySubjectAndPredicateType(ctx, subjects, pt, atts)
+		},
+		func(ctx context.Context) ([]attestation.Envelope, *FetchReport) {
+			return agent.fetchFrom(ctx, repos, noQueryNoLimit(opts), subjectAndPredicateTypeFetchFunc(subjects, pt), coversPredicateTypes(pt))
+		},
+	)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	return agent.finalizeResults(ret, opts), report, nil
+}
+
+// sharedFetch is the outcome of a fetch shared by concurrent callers.
+type sharedFetch struct {
+	atts     []attestation.Envelope
+	report   *FetchReport
+	canceled bool
+}
+
+// fetchCached serves a query from the cache or, on a miss, fetches it from
+// the repositories and stores the complete results in the cache. Concurrent
+// callers of the same query share a single fetch while different queries
+// run in parallel. The shared fetch runs with the context of the first
+// caller, if it is canceled the rest fetch again. Both the cache and the
+// shared fetches are keyed by the query and the scope of the request, see
+// CacheScope.
+//
+// The results are returned without applying the query and limit in opts.
+func (agent *Agent) fetchCached(
+	ctx context.Context, opts attestation.FetchOptions, method, key string,
+	get func(context.Context) (*[]attestation.Envelope, error),
+	store func(context.Context, *[]attestation.Envelope) error,
+	fetch func(context.Context) ([]attestation.Envelope, *FetchReport),
+) ([]attestation.Envelope, *FetchReport, error) {
+	useCache := agent.Options.UseCache && agent.Cache != nil
+	ctx = withCacheScope(ctx, agent.cacheScope(opts))
+
 	// Query the cache to see if we have cached attestations
-	if agent.Options.UseCache && agent.Cache != nil {
-		cachedAtts, err := agent.Cache.GetAttestationsBySubject(ctx, subjects)
+	if useCache {
+		rctx, read := withCacheRead(ctx)
+		cachedAtts, err := get(rctx)
 		if err != nil {
-			return nil, fmt.Errorf("querying attestations cache: %w", err)
+			return nil, nil, fmt.Errorf("querying attestations cache: %w", err)
 		}
-
-		if cachedAtts != nil {
-			ret = *cachedAtts
+		ret, ok := agent.cachedEnvelopes(cachedAtts, read)
+		agent.observeCache(ctx, method, ok)
+		if ok {
+			return ret, &FetchReport{Repositories: []RepositoryReport{}, FromCache: true}, nil
 		}
 	}
 
-	// If the cache returned data, skip fetching
-	if len(ret) == 0 {
-		fetchMutex.Lock()
-		defer fetchMutex.Unlock()
-
-		// Re-check cache after acquiring the lock — another goroutine may
-		// have populated it while we were waiting.
-		if agent.Options.UseCache && agent.Cache != nil {
-			cachedAtts, err := agent.Cache.GetAttestationsBySubject(ctx, subjects)
-			if err != nil {
-				return nil, fmt.Errorf("querying attestations cache: %w", err)
-			}
-			if cachedAtts != nil {
-				ret = *cachedAtts
-			}
-		}
-
-		// Only fetch from repositories if cache re-check still empty.
-		if len(ret) == 0 {
-			m := []map[string]string{}
-			for _, s := range subjects {
-				m = append(m, s.GetDigest())
+	key = scopedKey(ctx, key)
+	for {
+		led := false
+		v, err, _ := agent.fetchGroup.Do(key, func() (any, error) {
+			led = true
+			// Check the cache again, a previous fetch may have stored
+			// the results while we waited.
+			if useCache {
+				rctx, read := withCacheRead(ctx)
+				cachedAtts, err := get(rctx)
+				if err != nil {
+					return nil, fmt.Errorf("querying attestations cache: %w", err)
+				}
+				if ret, ok := agent.cachedEnvelopes(cachedAtts, read); ok {
+					return &sharedFetch{
+						atts:   ret,
+						report: &FetchReport{Repositories: []RepositoryReport{}, FromCache: true},
+					}, nil
+				}
 			}
 
-			q := attestation.NewQuery().WithFilter(&filters.SubjectHashMatcher{
-				HashSets: m,
-			})
-
-			t := throttler.New(agent.Options.ParallelFetches, len(repos))
-
-			for _, r := range repos {
-				go func(r attestation.Fetcher) {
-					var err error
-					var atts []attestation.Envelope
-					if fr, ok := r.(attestation.FetcherBySubject); ok {
-						atts, err = fr.FetchBySubject(ctx, opts, subjects)
-					} else {
-						atts, err = r.Fetch(ctx, opts)
-						if err == nil {
-							atts = q.Run(atts)
-						}
-					}
-					if err != nil {
-						t.Done(err)
-						return
-					}
-
-					mutex.Lock()
-					ret = append(ret, atts...)
-					mutex.Unlock()
-					t.Done(nil)
-				}(r)
-				t.Throttle()
-			}
-			if err := t.Err(); err != nil {
-				return nil, fmt.Errorf("fetch throttler error: %w", err)
-			}
-			if agent.Options.UseCache && agent.Cache != nil {
-				err := agent.Cache.StoreAttestationsBySubject(ctx, subjects, &ret)
-				if err != nil {
+			ret, report := fetch(ctx)
+			if useCache && report.complete() {
+				if err := store(ctx, &ret); err != nil {
 					return nil, fmt.Errorf("storing data in cache: %w", err)
 				}
 			}
+			return &sharedFetch{atts: ret, report: report, canceled: ctx.Err() != nil}, nil
+		})
+		if err != nil {
+			return nil, nil, err
 		}
-	}
 
-	if opts.Query != nil {
-		ret = opts.Query.Run(ret)
-	}
+		res := v.(*sharedFetch) //nolint:errcheck,forcetypeassert
+		if res.canceled && !led && ctx.Err() == nil {
+			continue
+		}
 
-	// Limit the returned attestations. Mmmh....
-	if opts.Limit != 0 {
-		ret = ret[0:opts.Limit]
+		// Each caller gets its own copy of the shared results
+		report := *res.report
+		report.Repositories = slices.Clone(res.report.Repositories)
+		return slices.Clone(res.atts), &report, nil
 	}
-
-	return ret, nil
 }
 
-// FetchAttestationsByPredicateType requests all attestations of a particular type
-// from the configured repositories.
-func (agent *Agent) FetchAttestationsByPredicateType(ctx context.Context, pt []attestation.PredicateType, optFn ...FetchOptionsFunc) ([]attestation.Envelope, error) {
-	mutex := sync.Mutex{}
-	ret := []attestation.Envelope{}
+// cachedEnvelopes returns the envelopes read from the cache and true if they
+// answer the query, including known empty results. The signatures of the
+// envelopes the cache read from storage are verified again, as their
+// verification results are not stored and the data may have been modified
+// outside the agent. The repositories verify the envelopes they fetch with
+// the agent keys, so this is done even when the agent applies no
+// verification policy. The caches return copies of those envelopes, the
+// rest may be shared and are not modified.
+func (agent *Agent) cachedEnvelopes(atts *[]attestation.Envelope, read *cacheRead) ([]attestation.Envelope, bool) {
+	if atts == nil {
+		return nil, false
+	}
+	if len(*atts) == 0 {
+		return []attestation.Envelope{}, true
+	}
+	ret := *atts
+	if read.persistent.Load() {
+		if agent.Options.VerifySignatures {
+			ret = agent.verifyEnvelopes(ret)
+		} else {
+			agent.checkSignatures(ret)
+		}
+	}
+	// The policy may drop all the envelopes, fetch them again in that case
+	return ret, len(ret) > 0
+}
 
-	// Filter the repos to get the fetchers
-	repos := agent.fetcherRepos()
-	if len(repos) == 0 {
-		return nil, ErrNoFetcherConfigured
+// cacheScope returns a digest of the agent settings that change the
+// results of a query: the fetchers with their priority tiers, the maximum
+// read size, the verification keys and trust root, and the verification
+// policy. See CacheScope.
+func (agent *Agent) cacheScope(opts attestation.FetchOptions) string {
+	h := sha256.New()
+	for _, r := range agent.fetcherRepos() {
+		fmt.Fprintf(h, "repository %d %T %s\n", agent.repoTier(r), r, agent.repoName(r))
 	}
+	fmt.Fprintf(h, "maxReadSize %d\n", opts.MaxReadSize)
+	// The keys and the trust root change the verification results that the
+	// repositories record in the envelopes, even when the agent does not
+	// apply a verification policy.
+	for _, k := range agent.Options.Keys {
+		fmt.Fprintf(h, "key %s\n", keyIdentity(k))
+	}
+	if agent.Options.TrustedRoot != nil {
+		fmt.Fprintf(h, "trustedRoot %s\n", trustedRootDigest(agent.Options.TrustedRoot))
+	}
+	if agent.Options.VerifySignatures {
+		fmt.Fprintf(h, "verification %s\n", agent.Options.VerificationPolicy)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
 
-	opts := agent.Options.Fetch
-	opts.MaxReadSize = agent.Options.MaxReadSize
-	for _, f := range optFn {
-		f(&opts)
+// keyIdentity returns a string identifying a verification key: its ID,
+// scheme and a digest of its data.
+func keyIdentity(k key.PublicKeyProvider) string {
+	pub, err := k.PublicKey()
+	if err != nil || pub == nil {
+		// Without the public data, fall back to the identity of the provider
+		return fmt.Sprintf("%T %p", k, k)
 	}
+	return fmt.Sprintf("%s %s %x", pub.ID(), pub.Scheme, sha256.Sum256([]byte(pub.Data)))
+}
 
-	// Query the cache to see if we have cached attestations
-	if agent.Options.UseCache && agent.Cache != nil {
-		cachedAtts, err := agent.Cache.GetAttestationsByPredicateType(ctx, pt)
-		if err != nil {
-			return nil, fmt.Errorf("querying attestations cache: %w", err)
+// trustedRootDigest returns a digest of a sigstore trust root. Trust roots
+// that can't be serialized are identified by their type and address.
+func trustedRootDigest(tm root.TrustedMaterial) string {
+	if m, ok := tm.(json.Marshaler); ok {
+		if data, err := m.MarshalJSON(); err == nil {
+			return fmt.Sprintf("%x", sha256.Sum256(data))
 		}
+	}
+	if v := reflect.ValueOf(tm); v.Kind() == reflect.Pointer {
+		return fmt.Sprintf("%T %x", tm, v.Pointer())
+	}
+	return fmt.Sprintf("%T %v", tm, tm)
+}
 
-		if cachedAtts != nil {
-			ret = *cachedAtts
-		}
+// workers returns the number of goroutines to run for a parallelism
+// option. Values under 1 run the operations one at a time.
+func workers(n int) int {
+	return max(n, 1)
+}
+
+// fetchFrom runs fetch on the repositories in parallel (up to
+// ParallelFetches at a time), one priority tier after the other until the
+// results of a tier pass the done check. It returns the attestations
+// returned by the repositories that succeeded and a report with the outcome
+// of each one. The query and limit in opts are not applied.
+func (agent *Agent) fetchFrom(ctx context.Context, repos []attestation.Fetcher, opts attestation.FetchOptions, fetch repoFetchFunc, done tierCheck) ([]attestation.Envelope, *FetchReport) {
+	// Results are stored by index to keep them in registration order
+	results := make([][]attestation.Envelope, len(repos))
+	report := &FetchReport{
+		Repositories: make([]RepositoryReport, len(repos)),
 	}
 
-	// If the cache returned data, skip fetching here
-	if len(ret) == 0 {
-		t := throttler.New(agent.Options.ParallelFetches, len(repos))
-		m := map[attestation.PredicateType]struct{}{}
-		for _, predType := range pt {
-			m[predType] = struct{}{}
-		}
-		q := attestation.NewQuery().WithFilter(&filters.PredicateTypeMatcher{
-			PredicateTypes: m,
-		})
-		for _, r := range repos {
-			go func(r attestation.Fetcher) {
-				var err error
-				var atts []attestation.Envelope
-
-				if fr, ok := r.(attestation.FetcherByPredicateType); ok {
-					atts, err = fr.FetchByPredicateType(ctx, opts, pt)
-				} else {
-					atts, err = r.Fetch(ctx, opts)
-					if err == nil {
-						atts = q.Run(atts)
-					}
-				}
-				if err != nil {
-					t.Done(err)
-					return
-				}
+	// Repositories return all their results, the limit is applied after
+	// sorting them.
+	repoOpts := opts
+	repoOpts.Limit = 0
 
-				mutex.Lock()
-				ret = append(ret, atts...)
-				mutex.Unlock()
+	// All the repositories share the read budget of the fetch
+	ctx = readlimit.WithBudget(ctx, agent.readBudget())
+
+	ret := []attestation.Envelope{}
+	tiers := agent.fetchTiers(repos)
+	for n, tier := range tiers {
+		t := throttler.New(workers(agent.Options.ParallelFetches), len(tier))
+		for _, i := range tier {
+			go func(i int, r attestation.Fetcher) {
+				atts, rr := agent.fetchRepo(ctx, r, repoOpts, fetch)
+				report.Repositories[i] = rr
+				if rr.Error == nil {
+					agent.annotateSources(r, atts)
+					atts = agent.verifyEnvelopes(atts)
+					atts = sortByDigest(atts)
+					results[i] = atts
+					report.Repositories[i].Count = len(atts)
+				}
 				t.Done(nil)
-			}(r)
+			}(i, repos[i])
 			t.Throttle()
 		}
-		if err := t.Err(); err != nil {
-			return nil, fmt.Errorf("fetch throttler error: %w", err)
+
+		for _, i := range tier {
+			ret = append(ret, results[i]...)
 		}
-		if agent.Options.UseCache && agent.Cache != nil {
-			err := agent.Cache.StoreAttestationsByPredicateType(ctx, pt, &ret)
-			if err != nil {
-				return nil, fmt.Errorf("storing data in cache: %w", err)
+
+		if n < len(tiers)-1 && done(ret) {
+			// Record the repositories of the tiers not queried
+			for _, rest := range tiers[n+1:] {
+				for _, i := range rest {
+					report.Repositories[i] = RepositoryReport{
+						Repository: agent.repoName(repos[i]), Skipped: true,
+					}
+				}
 			}
+			break
 		}
 	}
+	return ret, report
+}
 
+// finalizeResults runs the agent post-processing on the fetched attestations
+// before returning them to the caller: deduplication, sorting, query and
+// limit.
+func (agent *Agent) finalizeResults(atts []attestation.Envelope, opts attestation.FetchOptions) []attestation.Envelope {
+	if agent.Options.Deduplicate {
+		atts = deduplicate(atts)
+	}
+	return applyQueryAndLimit(agent.sortResults(atts), opts)
+}
+
+// applyQueryAndLimit runs the query in the fetch options on a set of
+// attestations and trims the result to the configured limit.
+func applyQueryAndLimit(atts []attestation.Envelope, opts attestation.FetchOptions) []attestation.Envelope {
 	if opts.Query != nil {
-		ret = opts.Query.Run(ret)
+		atts = opts.Query.Run(atts)
 	}
 
-	// Limit the returnes attestations. Mmmh....
-	if opts.Limit != 0 {
-		ret = ret[0:opts.Limit]
+	if opts.Limit > 0 && len(atts) > opts.Limit {
+		atts = atts[0:opts.Limit]
 	}
+	return atts
+}
 
-	return ret, nil
+// fetchOptions builds the fetch options for a call from the agent defaults
+// and the functional options passed by the caller.
+func (agent *Agent) fetchOptions(optFn ...FetchOptionsFunc) attestation.FetchOptions {
+	opts := agent.Options.Fetch
+	opts.MaxReadSize = agent.Options.MaxReadSize
+	for _, f := range optFn {
+		f(&opts)
+	}
+	return opts
 }
 
-// Store stores a list of envelopes in the configured storer repos
-func (agent *Agent) Store(ctx context.Context, envelopes []attestation.Envelope, optFn ...StoreOptionsFunc) error {
-	repos := agent.storerRepos()
-	if len(repos) == 0 {
-		return fmt.Errorf("no storer repositories configured")
+// readBudget returns a new read budget for a fetch call with the agent
+// limits, nil when there are none.
+func (agent *Agent) readBudget() *readlimit.Budget {
+	return readlimit.NewBudget(agent.Options.MaxFetchBytes, agent.Options.MaxFetchEnvelopes)
+}
+
+// repoFetchFunc is a function that retrieves attestations from a single
+// repository. The agent's fetch methods build one and run it on all the
+// configured fetchers.
+type repoFetchFunc func(context.Context, attestation.Fetcher, attestation.FetchOptions) ([]attestation.Envelope, error)
+
+// fetchAll is the repoFetchFunc used by the general Fetch methods.
+func fetchAll(ctx context.Context, r attestation.Fetcher, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
+	return r.Fetch(ctx, opts)
+}
+
+// subjectQuery returns a query that matches attestations about any of the
+// subjects.
+func subjectQuery(subjects []attestation.Subject) *attestation.Query {
+	m := []map[string]string{}
+	for _, s := range subjects {
+		m = append(m, s.GetDigest())
 	}
 
-	opts := agent.Options.Store
-	for _, f := range optFn {
-		f(&opts)
+	return attestation.NewQuery().WithFilter(&filters.SubjectHashMatcher{
+		HashSets: m,
+	})
+}
+
+// predicateTypeQuery returns a query that matches attestations of any of the
+// predicate types.
+func predicateTypeQuery(pt []attestation.PredicateType) *attestation.Query {
+	m := map[attestation.PredicateType]struct{}{}
+	for _, predType := range pt {
+		m[predType] = struct{}{}
 	}
+	return attestation.NewQuery().WithFilter(&filters.PredicateTypeMatcher{
+		PredicateTypes: m,
+	})
+}
 
-	for _, repo := range repos {
-		if err := repo.Store(ctx, opts, envelopes); err != nil {
-			return fmt.Errorf("storing attestation: %w", err)
+// subjectFetchFunc returns a repoFetchFunc that calls FetchBySubject on the
+// repositories that support it and falls back to filtering the results of
+// Fetch on those that don't.
+func subjectFetchFunc(subjects []attestation.Subject) repoFetchFunc {
+	q := subjectQuery(subjects)
+	return func(ctx context.Context, r attestation.Fetcher, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
+		if fr, ok := r.(attestation.FetcherBySubject); ok {
+			return fr.FetchBySubject(ctx, opts, subjects)
+		}
+		atts, err := r.Fetch(ctx, opts)
+		if err != nil {
+			return nil, err
 		}
+		return q.Run(atts), nil
 	}
-	return nil
+}
+
+// predicateTypeFetchFunc returns a repoFetchFunc that calls FetchByPredicateType
+// on the repositories that support it and falls back to filtering the results
+// of Fetch on those that don't.
+func predicateTypeFetchFunc(pt []attestation.PredicateType) repoFetchFunc {
+	q := predicateTypeQuery(pt)
+	return func(ctx context.Context, r attestation.Fetcher, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
+		if fr, ok := r.(attestation.FetcherByPredicateType); ok {
+			return fr.FetchByPredicateType(ctx, opts, pt)
+		}
+		atts, err := r.Fetch(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return q.Run(atts), nil
+	}
+}
+
+// subjectAndPredicateTypeFetchFunc returns a repoFetchFunc that calls
+// FetchByPredicateTypeAndSubject on the repositories that support it. On the
+// rest, it falls back to FetchBySubject, FetchByPredicateType or Fetch (in
+// that order) and filters the results. A fallback is also used when a
+// repository returns attestation.ErrFetcherMethodNotImplemented.
+func subjectAndPredicateTypeFetchFunc(subjects []attestation.Subject, pt []attestation.PredicateType) repoFetchFunc {
+	sq := subjectQuery(subjects)
+	pq := predicateTypeQuery(pt)
+	return func(ctx context.Context, r attestation.Fetcher, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
+		// Filtered fetches can't be limited by the repository as the
+		// results are trimmed afterwards.
+		fopts := opts
+		fopts.Limit = 0
+
+		if fr, ok := r.(attestation.FetcherByPredicateTypeAndSubject); ok {
+			atts, err := fr.FetchByPredicateTypeAndSubject(ctx, opts, pt, subjects)
+			if !errors.Is(err, attestation.ErrFetcherMethodNotImplemented) {
+				return atts, err
+			}
+		}
+
+		if fr, ok := r.(attestation.FetcherBySubject); ok {
+			atts, err := fr.FetchBySubject(ctx, fopts, subjects)
+			if err == nil {
+				return pq.Run(atts), nil
+			}
+			if !errors.Is(err, attestation.ErrFetcherMethodNotImplemented) {
+				return nil, err
+			}
+		}
+
+		if fr, ok := r.(attestation.FetcherByPredicateType); ok {
+			atts, err := fr.FetchByPredicateType(ctx, fopts, pt)
+			if err == nil {
+				return sq.Run(atts), nil
+			}
+			if !errors.Is(err, attestation.ErrFetcherMethodNotImplemented) {
+				return nil, err
+			}
+		}
+
+		atts, err := r.Fetch(ctx, fopts)
+		if err != nil {
+			return nil, err
+		}
+		return pq.Run(sq.Run(atts)), nil
+	}
+}
+
+// Store stores a list of envelopes in the configured storer repos. The
+// repositories are written in parallel and the result is evaluated using the
+// agent's store policy. Use StoreWithReport to get the outcome of each
+// repository.
+func (agent *Agent) Store(ctx context.Context, envelopes []attestation.Envelope, optFn ...StoreOptionsFunc) error {
+	_, err := agent.StoreWithReport(ctx, envelopes, optFn...)
+	return err
 }
 
 // StoreFromFiles calls Store but takes a list of file paths which are parsed before
@@ -6,9 +6,14 @@ package collector
 import (
 	"context"
 	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/carabiner-dev/attestation"
+	intoto "github.com/in-toto/attestation/go/v1"
 	"github.com/stretchr/testify/require"
 
 	"github.com/carabiner-dev/collector/envelope/bare"
@@ -133,6 +138,21 @@ func TestFetch(t *testing.T) {
 	}
 }
 
+func TestFetchZeroParallelFetches(t *testing.T) {
+	t.Parallel()
+	agent, err := New(WithParallelFetches(0), WithRepository(&fakeFetcher{
+		fetchFunc: func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+			return []attestation.Envelope{&bare.Envelope{}}, nil
+		},
+	}))
+	require.NoError(t, err)
+
+	// Values under 1 fetch from one repository at a time
+	res, err := agent.Fetch(t.Context())
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+}
+
 func TestFetchAttestationsBySubject(t *testing.T) {
 	t.Parallel()
 
@@ -334,3 +354,206 @@ func TestFetchAttestationsByPredicateType(t *testing.T) {
 		})
 	}
 }
+
+var _ attestation.FetcherByPredicateTypeAndSubject = (*fakeCombinedFetcher)(nil)
+
+// fakeCombinedFetcher is a fake fetcher that also implements the combined
+// subject and predicate type interface.
+type fakeCombinedFetcher struct {
+	fakeFetcher
+	fetchByPredicateTypeAndSubjectFunc func(context.Context, attestation.FetchOptions, []attestation.PredicateType, []attestation.Subject) ([]attestation.Envelope, error)
+}
+
+func (ff *fakeCombinedFetcher) FetchByPredicateTypeAndSubject(ctx context.Context, fo attestation.FetchOptions, pt []attestation.PredicateType, subs []attestation.Subject) ([]attestation.Envelope, error) {
+	return ff.fetchByPredicateTypeAndSubjectFunc(ctx, fo, pt, subs)
+}
+
+// testDSSEOfType returns a DSSE envelope wrapping testStatement with its
+// predicate type replaced.
+func testDSSEOfType(pt string) attestation.Envelope {
+	return testDSSE(strings.Replace(testStatement, "https://example.com/test/v1", pt, 1))
+}
+
+func TestFetchAttestationsBySubjectAndPredicateType(t *testing.T) {
+	t.Parallel()
+	subjects := []attestation.Subject{
+		&intoto.ResourceDescriptor{Digest: map[string]string{"sha256": "8b1a9953c4611296a827abf8c47804d7e6c49c6b0b1e1d5e8b0e0ea0eb7bd9a6"}},
+	}
+	pts := []attestation.PredicateType{"https://example.com/test/v1"}
+
+	for _, tc := range []struct {
+		name   string
+		repo   func(calls *int) attestation.Repository
+		expect int
+	}{
+		{
+			name: "combined",
+			repo: func(calls *int) attestation.Repository {
+				return &fakeCombinedFetcher{
+					fetchByPredicateTypeAndSubjectFunc: func(_ context.Context, _ attestation.FetchOptions, pt []attestation.PredicateType, subs []attestation.Subject) ([]attestation.Envelope, error) {
+						*calls++
+						require.Len(t, pt, 1)
+						require.Len(t, subs, 1)
+						return []attestation.Envelope{testDSSEOfType("https://example.com/test/v1")}, nil
+					},
+				}
+			},
+			expect: 1,
+		},
+		{
+			name: "combined-not-implemented",
+			repo: func(calls *int) attestation.Repository {
+				return &fakeCombinedFetcher{
+					fakeFetcher: fakeFetcher{
+						fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+							*calls++
+							return []attestation.Envelope{testDSSEOfType("https://example.com/test/v1"), testDSSEOfType("https://example.com/other/v1")}, nil
+						},
+					},
+					fetchByPredicateTypeAndSubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.PredicateType, []attestation.Subject) ([]attestation.Envelope, error) {
+						return nil, attestation.ErrFetcherMethodNotImplemented
+					},
+				}
+			},
+			expect: 1,
+		},
+		{
+			name: "subject-fallback",
+			repo: func(calls *int) attestation.Repository {
+				return &fakeFetcher{
+					fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+						*calls++
+						return []attestation.Envelope{
+							testDSSEOfType("https://example.com/test/v1"),
+							testDSSEOfType("https://example.com/other/v1"),
+							testDSSEOfType("https://example.com/test/v1"),
+						}, nil
+					},
+				}
+			},
+			expect: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			agent, err := New()
+			require.NoError(t, err)
+			require.NoError(t, agent.AddRepository(tc.repo(&calls)))
+
+			for range 2 {
+				res, err := agent.FetchAttestationsBySubjectAndPredicateType(t.Context(), subjects, pts)
+				require.NoError(t, err)
+				require.Len(t, res, tc.expect)
+			}
+
+			// The second call must be served from the cache
+			require.Equal(t, 1, calls)
+
+			// The stream variant reads the same cache entry
+			got := 0
+			for _, err := range agent.FetchAttestationsBySubjectAndPredicateTypeStream(t.Context(), subjects, pts) {
+				require.NoError(t, err)
+				got++
+			}
+			require.Equal(t, tc.expect, got)
+			require.Equal(t, 1, calls)
+		})
+	}
+}
+
+func TestSharedFetch(t *testing.T) {
+	t.Parallel()
+	pt := []attestation.PredicateType{"https://example.com/test/v1"}
+
+	for _, tc := range []struct {
+		name  string
+		fetch func(*Agent) error
+	}{
+		{
+			"subject", func(agent *Agent) error {
+				_, err := agent.FetchAttestationsBySubject(t.Context(), cacheSubject("a"))
+				return err
+			},
+		},
+		{
+			"predicate-type", func(agent *Agent) error {
+				_, err := agent.FetchAttestationsByPredicateType(t.Context(), pt)
+				return err
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var calls atomic.Int32
+			started := make(chan struct{})
+			release := make(chan struct{})
+			fetch := func() ([]attestation.Envelope, error) {
+				if calls.Add(1) == 1 {
+					close(started)
+				}
+				<-release
+				return []attestation.Envelope{testDSSE(testStatement), testDSSE(testStatement)}, nil
+			}
+
+			agent, err := New(WithRepository(&fakeFetcher{
+				fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+					return fetch()
+				},
+				fetchByPredicateTypeFunc: func(context.Context, attestation.FetchOptions, []attestation.PredicateType) ([]attestation.Envelope, error) {
+					return fetch()
+				},
+			}))
+			require.NoError(t, err)
+			agent.Options.UseCache = false
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 5)
+			for range 5 {
+				wg.Go(func() { errs <- tc.fetch(agent) })
+			}
+			<-started
+			// Give the rest of the callers time to join the fetch
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				require.NoError(t, err)
+			}
+			require.Equal(t, int32(1), calls.Load())
+		})
+	}
+}
+
+func TestFetchDifferentKeysInParallel(t *testing.T) {
+	t.Parallel()
+	started := map[string]chan struct{}{"a": make(chan struct{}), "b": make(chan struct{})}
+	other := map[string]string{"a": "b", "b": "a"}
+
+	agent, err := New(WithRepository(&fakeFetcher{
+		fetchBySubjectFunc: func(ctx context.Context, _ attestation.FetchOptions, subs []attestation.Subject) ([]attestation.Envelope, error) {
+			d := subs[0].GetDigest()["sha256"]
+			close(started[d])
+			// Each fetch waits for the other one to start
+			select {
+			case <-started[other[d]]:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			return []attestation.Envelope{&bare.Envelope{}}, nil
+		},
+	}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, d := range []string{"a", "b"} {
+		wg.Go(func() {
+			_, err := agent.FetchAttestationsBySubject(ctx, cacheSubject(d))
+			require.NoError(t, err)
+		})
+	}
+	wg.Wait()
+}
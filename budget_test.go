@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/bare"
+)
+
+func budgetFetchers(calls *int) []attestation.Repository {
+	fn := func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+		*calls++
+		return []attestation.Envelope{&bare.Envelope{}, &bare.Envelope{}}, nil
+	}
+	return []attestation.Repository{
+		&fakeFetcher{fetchBySubjectFunc: fn},
+		&fakeFetcher{fetchBySubjectFunc: fn},
+	}
+}
+
+func TestReadBudget(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	agent, err := New(WithReadBudget(0, 3), WithParallelFetches(1))
+	require.NoError(t, err)
+	agent.Repositories = append(agent.Repositories, budgetFetchers(&calls)...)
+
+	atts, report, err := agent.FetchAttestationsBySubjectWithReport(t.Context(), []attestation.Subject{})
+	require.NoError(t, err)
+	require.Len(t, atts, 3)
+	require.Len(t, report.Truncated(), 1)
+	require.Equal(t, 2, calls)
+
+	// Truncated results are not cached
+	atts, report, err = agent.FetchAttestationsBySubjectWithReport(t.Context(), []attestation.Subject{})
+	require.NoError(t, err)
+	require.Len(t, atts, 3)
+	require.False(t, report.FromCache)
+	require.Equal(t, 4, calls)
+
+	_, err = New(WithReadBudget(-1, 0))
+	require.Error(t, err)
+}
+
+func TestReadBudgetStream(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	agent, err := New(WithReadBudget(0, 3), WithParallelFetches(1))
+	require.NoError(t, err)
+	agent.Repositories = append(agent.Repositories, budgetFetchers(&calls)...)
+
+	got := 0
+	var last error
+	for att, err := range agent.FetchAttestationsBySubjectStream(t.Context(), []attestation.Subject{}) {
+		if err != nil {
+			last = err
+			continue
+		}
+		require.NotNil(t, att)
+		got++
+	}
+	require.Equal(t, 3, got)
+	require.ErrorIs(t, last, ErrReadBudgetExhausted)
+}
@@ -4,40 +4,212 @@
 package collector
 
 import (
+	"container/list"
 	"context"
+	"encoding/json"
 	"fmt"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/carabiner-dev/attestation"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
 )
 
-var cacheMutex = sync.Mutex{}
-
+// Cache stores the results of the agent queries. The get methods return nil
+// on a miss, a non-nil empty list means the query is known to have no
+// results. The agent passes the scope of each request in the context, see
+// CacheScope.
 type Cache interface {
 	StoreAttestationsByPredicateType(context.Context, []attestation.PredicateType, *[]attestation.Envelope) error
 	GetAttestationsByPredicateType(context.Context, []attestation.PredicateType) (*[]attestation.Envelope, error)
 	StoreAttestationsBySubject(context.Context, []attestation.Subject, *[]attestation.Envelope) error
 	GetAttestationsBySubject(context.Context, []attestation.Subject) (*[]attestation.Envelope, error)
+	StoreAttestationsBySubjectAndPredicateType(context.Context, []attestation.Subject, []attestation.PredicateType, *[]attestation.Envelope) error
+	GetAttestationsBySubjectAndPredicateType(context.Context, []attestation.Subject, []attestation.PredicateType) (*[]attestation.Envelope, error)
 }
 
 // Ensure the memcache implements the cache interface
 var _ Cache = (*MemoryCache)(nil)
 
-func NewMemoryCache() *MemoryCache {
-	return &MemoryCache{
-		predicateType: map[string]*[]attestation.Envelope{},
-		subject:       map[string]*[]attestation.Envelope{},
-		times:         map[string]time.Time{},
+// CacheInvalidator is implemented by caches that can drop the results of
+// the queries involving some subjects or predicate types. The agent
+// invalidates the results affected by the attestations it stores.
+type CacheInvalidator interface {
+	Invalidate([]attestation.Subject, []attestation.PredicateType) error
+}
+
+// Ensure the memcache can be invalidated
+var _ CacheInvalidator = (*MemoryCache)(nil)
+
+type cacheScopeKey struct{}
+
+// withCacheScope returns a context carrying the scope of a cache request.
+func withCacheScope(ctx context.Context, scope string) context.Context {
+	return context.WithValue(ctx, cacheScopeKey{}, scope)
+}
+
+// CacheScope returns the scope of the cache request in the context. The
+// agent sets it to a digest of the settings that change the results of a
+// query (its repositories, their tiers, the read size, the verification
+// keys, trust root and policy). Caches include it in their keys so results fetched with
+// different settings are not mixed.
+func CacheScope(ctx context.Context) string {
+	scope, _ := ctx.Value(cacheScopeKey{}).(string) //nolint:errcheck
+	return scope
+}
+
+// scopedKey returns a cache key within the scope of the request in ctx.
+func scopedKey(ctx context.Context, key string) string {
+	return CacheScope(ctx) + "\n" + key
+}
+
+type cacheReadKey struct{}
+
+// cacheRead records the outcome of a cache lookup. Caches set persistent
+// when they return envelopes parsed from storage, without the results of
+// their signature verification. Those are copies the agent verifies again.
+type cacheRead struct {
+	persistent atomic.Bool
+}
+
+// withCacheRead returns a context to record the outcome of a cache lookup.
+func withCacheRead(ctx context.Context) (context.Context, *cacheRead) {
+	read := &cacheRead{}
+	return context.WithValue(ctx, cacheReadKey{}, read), read
+}
+
+// markPersistentRead records in the context of a cache lookup that the
+// envelopes returned were read from storage.
+func markPersistentRead(ctx context.Context) {
+	if read, ok := ctx.Value(cacheReadKey{}).(*cacheRead); ok {
+		read.persistent.Store(true)
+	}
+}
+
+// CacheOptions control the expiry and size of a cache. Zero values mean no
+// limit.
+type CacheOptions struct {
+	// TTL is the time cached results are served before they are considered
+	// stale. Stale results are dropped and fetched again.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of queries cached. When full, the
+	// least recently used results are evicted.
+	MaxEntries int
+
+	// MaxBytes is the maximum approximate size of the cached envelopes.
+	// When full, the least recently used results are evicted.
+	MaxBytes int64
+
+	// NegativeTTL is the time queries known to have no results are cached.
+	// When zero, empty results are not cached and are fetched every time.
+	NegativeTTL time.Duration
+}
+
+// CacheOption is a function that configures a cache.
+type CacheOption func(*CacheOptions)
+
+// WithCacheTTL sets the time cached results are served.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(opts *CacheOptions) {
+		opts.TTL = ttl
+	}
+}
+
+// WithCacheNegativeTTL sets the time queries without results are cached.
+func WithCacheNegativeTTL(ttl time.Duration) CacheOption {
+	return func(opts *CacheOptions) {
+		opts.NegativeTTL = ttl
 	}
 }
 
+// WithCacheMaxEntries sets the maximum number of queries cached.
+func WithCacheMaxEntries(n int) CacheOption {
+	return func(opts *CacheOptions) {
+		opts.MaxEntries = n
+	}
+}
+
+// WithCacheMaxBytes sets the maximum size of the cached envelopes.
+func WithCacheMaxBytes(n int64) CacheOption {
+	return func(opts *CacheOptions) {
+		opts.MaxBytes = n
+	}
+}
+
+// NewMemoryCache returns a new memory cache. By default it does not expire
+// or evict results.
+func NewMemoryCache(funcs ...CacheOption) *MemoryCache {
+	memcache := &MemoryCache{
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		now:     time.Now,
+	}
+	for _, f := range funcs {
+		f(&memcache.Options)
+	}
+	return memcache
+}
+
+// MemoryCache keeps the results of the agent queries in memory. Results
+// expire after the TTL and, when the cache is bounded, the least recently
+// used ones are evicted to make room for new ones.
 type MemoryCache struct {
-	predicateType map[string]*[]attestation.Envelope
-	subject       map[string]*[]attestation.Envelope
-	times         map[string]time.Time
+	Options CacheOptions
+
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	// lru holds the entries, the most recently used at the front
+	lru       *list.List
+	size      int64
+	lastSweep time.Time
+	now       func() time.Time
+
+	hits, misses uint64
+}
+
+// cacheEntry is a query result stored in the memory cache. The subjects and
+// predicate types of the query are kept to support invalidation.
+type cacheEntry struct {
+	key            string
+	query          string
+	scope          string
+	atts           []attestation.Envelope
+	size           int64
+	stored         time.Time
+	ttl            time.Duration
+	subjects       []attestation.Subject
+	predicateTypes []attestation.PredicateType
+	// imported is set on the entries loaded with Import
+	imported bool
+}
+
+// Kinds of the cached queries, used in the keys and in exported records
+const (
+	cacheQueryPredicateType        = "predicateType"
+	cacheQuerySubject              = "subject"
+	cacheQuerySubjectPredicateType = "subjectPredicateType"
+)
+
+// cacheQueryKey returns the key of a query of a kind, without its scope.
+func cacheQueryKey(query string, subjects []attestation.Subject, pt []attestation.PredicateType) string {
+	switch query {
+	case cacheQuerySubject:
+		return subjectsToKey(subjects)
+	case cacheQueryPredicateType:
+		return buildKey(pt)
+	default:
+		return subjectAndPredicateTypeKey(subjects, pt)
+	}
+}
+
+// memoryKey returns the key of a query in the memory cache.
+func memoryKey(ctx context.Context, query string, subjects []attestation.Subject, pt []attestation.PredicateType) string {
+	return query + "\n" + scopedKey(ctx, cacheQueryKey(query, subjects, pt))
 }
 
 func buildKey[T ~string](getters []T) string {
@@ -50,21 +222,12 @@ func buildKey[T ~string](getters []T) string {
 }
 
 func (memcache *MemoryCache) StoreAttestationsByPredicateType(ctx context.Context, pt []attestation.PredicateType, atts *[]attestation.Envelope) error {
-	k := buildKey(pt)
-	cacheMutex.Lock()
-	memcache.predicateType[k] = atts
-	memcache.times[k] = time.Now()
-	cacheMutex.Unlock()
+	memcache.store(ctx, cacheQueryPredicateType, nil, pt, atts)
 	return nil
 }
 
 func (memcache *MemoryCache) GetAttestationsByPredicateType(ctx context.Context, pt []attestation.PredicateType) (*[]attestation.Envelope, error) {
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
-	if v, ok := memcache.predicateType[buildKey(pt)]; ok {
-		return v, nil
-	}
-	return nil, nil
+	return memcache.get(ctx, memoryKey(ctx, cacheQueryPredicateType, nil, pt)), nil
 }
 
 // subjectToKey builds a cache key from a subject. Fields are length-prefixed
@@ -89,35 +252,243 @@ func subjectToKey(s attestation.Subject) string {
 	return b.String()
 }
 
-func (memcache *MemoryCache) StoreAttestationsBySubject(ctx context.Context, subjects []attestation.Subject, atts *[]attestation.Envelope) error {
+// subjectsToKey builds a cache key from a list of subjects.
+func subjectsToKey(subjects []attestation.Subject) string {
 	keys := make([]string, 0, len(subjects))
 	for _, subject := range subjects {
 		keys = append(keys, subjectToKey(subject))
 	}
-	k := buildKey(keys)
-	cacheMutex.Lock()
+	return buildKey(keys)
+}
+
+func (memcache *MemoryCache) StoreAttestationsBySubject(ctx context.Context, subjects []attestation.Subject, atts *[]attestation.Envelope) error {
+	memcache.store(ctx, cacheQuerySubject, subjects, nil, atts)
+	return nil
+}
+
+func (memcache *MemoryCache) GetAttestationsBySubject(ctx context.Context, subjects []attestation.Subject) (*[]attestation.Envelope, error) {
+	return memcache.get(ctx, memoryKey(ctx, cacheQuerySubject, subjects, nil)), nil
+}
+
+// subjectAndPredicateTypeKey builds the cache key of the combined subject and
+// predicate type queries. The subjects part is length-prefixed to avoid
+// collisions with the predicate types.
+func subjectAndPredicateTypeKey(subjects []attestation.Subject, pt []attestation.PredicateType) string {
+	sk := subjectsToKey(subjects)
+	return fmt.Sprintf("%d:%s\n%s", len(sk), sk, buildKey(pt))
+}
+
+func (memcache *MemoryCache) StoreAttestationsBySubjectAndPredicateType(ctx context.Context, subjects []attestation.Subject, pt []attestation.PredicateType, atts *[]attestation.Envelope) error {
+	memcache.store(ctx, cacheQuerySubjectPredicateType, subjects, pt, atts)
+	return nil
+}
+
+func (memcache *MemoryCache) GetAttestationsBySubjectAndPredicateType(ctx context.Context, subjects []attestation.Subject, pt []attestation.PredicateType) (*[]attestation.Envelope, error) {
+	return memcache.get(ctx, memoryKey(ctx, cacheQuerySubjectPredicateType, subjects, pt)), nil
+}
+
+// get returns a copy of the results cached under key. Expired results are
+// dropped and reported as a miss so the agent fetches them again.
+func (memcache *MemoryCache) get(ctx context.Context, key string) *[]attestation.Envelope {
+	memcache.mtx.Lock()
+	elem, ok := memcache.entries[key]
+	if !ok {
+		memcache.misses++
+		memcache.mtx.Unlock()
+		return nil
+	}
+	entry := elem.Value.(*cacheEntry) //nolint:errcheck,forcetypeassert
+	if memcache.expired(entry) {
+		memcache.remove(elem)
+		memcache.misses++
+		memcache.mtx.Unlock()
+		return nil
+	}
+	memcache.hits++
+	memcache.lru.MoveToFront(elem)
+	ret := slices.Clone(entry.atts)
+	memcache.mtx.Unlock()
+
+	// The agent verifies imported envelopes again, recording the results
+	// in them. Return copies to keep the cached envelopes unmodified.
+	if entry.imported {
+		for i, env := range ret {
+			c, err := cloneEnvelope(env)
+			if err != nil {
+				logrus.Debugf("copying cached envelope: %v", err)
+				return nil
+			}
+			ret[i] = c
+		}
+		markPersistentRead(ctx)
+	}
+	return &ret
+}
+
+// store adds the results of a query to the cache, replacing its previous
+// results.
+func (memcache *MemoryCache) store(ctx context.Context, query string, subjects []attestation.Subject, pt []attestation.PredicateType, atts *[]attestation.Envelope) {
+	entry := &cacheEntry{
+		key:            memoryKey(ctx, query, subjects, pt),
+		query:          query,
+		scope:          CacheScope(ctx),
+		subjects:       subjects,
+		predicateTypes: pt,
+	}
+	if atts != nil {
+		// Copy the slice to ensure the source is not modified.
+		entry.atts = slices.Clone(*atts)
+	}
+
+	memcache.mtx.Lock()
+	defer memcache.mtx.Unlock()
+	entry.stored = memcache.now()
+	entry.ttl = memcache.Options.TTL
+	if len(entry.atts) == 0 {
+		entry.ttl = memcache.Options.NegativeTTL
+	}
+	memcache.add(entry)
+}
+
+// add inserts an entry in the cache, replacing the previous results of the
+// query and evicting the least recently used entries when the cache is full.
+// The caller must hold the lock.
+func (memcache *MemoryCache) add(entry *cacheEntry) {
+	for _, att := range entry.atts {
+		entry.size += envelopeSize(att)
+	}
+	if elem, ok := memcache.entries[entry.key]; ok {
+		memcache.remove(elem)
+	}
+	memcache.sweep()
+
+	// Empty results are only kept when negative caching is enabled
+	if len(entry.atts) == 0 && memcache.Options.NegativeTTL <= 0 {
+		return
+	}
+
+	// Results larger than the whole cache are not stored
+	if memcache.Options.MaxBytes > 0 && entry.size > memcache.Options.MaxBytes {
+		return
+	}
 
-	// Copy the slice poiinter to ensure the source is not modified.
-	storecopy := make([]attestation.Envelope, 0, len(*atts))
-	storecopy = append(storecopy, *atts...)
+	memcache.entries[entry.key] = memcache.lru.PushFront(entry)
+	memcache.size += entry.size
+	for memcache.lru.Len() > 0 &&
+		((memcache.Options.MaxEntries > 0 && memcache.lru.Len() > memcache.Options.MaxEntries) ||
+			(memcache.Options.MaxBytes > 0 && memcache.size > memcache.Options.MaxBytes)) {
+		memcache.remove(memcache.lru.Back())
+	}
+}
 
-	memcache.subject[k] = &storecopy
-	memcache.times[k] = time.Now()
-	cacheMutex.Unlock()
+// Invalidate removes the cached results of all queries involving any of
+// the subjects or predicate types. Subjects match when they share a digest
+// or are identical.
+func (memcache *MemoryCache) Invalidate(subjects []attestation.Subject, pt []attestation.PredicateType) error {
+	memcache.mtx.Lock()
+	defer memcache.mtx.Unlock()
+	for elem := memcache.lru.Front(); elem != nil; {
+		next := elem.Next()
+		entry := elem.Value.(*cacheEntry) //nolint:errcheck,forcetypeassert
+		if slices.ContainsFunc(entry.predicateTypes, func(t attestation.PredicateType) bool {
+			return slices.Contains(pt, t)
+		}) || slices.ContainsFunc(entry.subjects, func(s attestation.Subject) bool {
+			return slices.ContainsFunc(subjects, func(o attestation.Subject) bool {
+				return subjectsMatch(s, o)
+			})
+		}) {
+			memcache.remove(elem)
+		}
+		elem = next
+	}
+	return nil
+}
 
+// Purge removes all the results in the cache.
+func (memcache *MemoryCache) Purge() error {
+	memcache.mtx.Lock()
+	defer memcache.mtx.Unlock()
+	memcache.entries = map[string]*list.Element{}
+	memcache.lru.Init()
+	memcache.size = 0
 	return nil
 }
 
-func (memcache *MemoryCache) GetAttestationsBySubject(ctx context.Context, subjects []attestation.Subject) (*[]attestation.Envelope, error) {
-	keys := make([]string, 0, len(subjects))
-	for _, subject := range subjects {
-		keys = append(keys, subjectToKey(subject))
+// Len returns the number of queries in the cache, including expired ones
+// not yet dropped.
+func (memcache *MemoryCache) Len() int {
+	memcache.mtx.Lock()
+	defer memcache.mtx.Unlock()
+	return memcache.lru.Len()
+}
+
+// Stats returns the usage statistics of the cache. The size of the results
+// is an estimate based on the size of the serialized envelopes.
+func (memcache *MemoryCache) Stats() (CacheStats, error) {
+	memcache.mtx.Lock()
+	defer memcache.mtx.Unlock()
+	stats := CacheStats{Hits: memcache.hits, Misses: memcache.misses, Bytes: memcache.size}
+	now := memcache.now()
+	for elem := memcache.lru.Front(); elem != nil; elem = elem.Next() {
+		stats.addAge(now, elem.Value.(*cacheEntry).stored) //nolint:errcheck,forcetypeassert
+	}
+	return stats, nil
+}
+
+func (memcache *MemoryCache) expired(entry *cacheEntry) bool {
+	return entry.ttl > 0 && memcache.now().Sub(entry.stored) > entry.ttl
+}
+
+func (memcache *MemoryCache) remove(elem *list.Element) {
+	entry := memcache.lru.Remove(elem).(*cacheEntry) //nolint:errcheck,forcetypeassert
+	delete(memcache.entries, entry.key)
+	memcache.size -= entry.size
+}
+
+// sweep drops the expired entries. To keep stores cheap, it runs at most
+// once per TTL period.
+func (memcache *MemoryCache) sweep() {
+	period := memcache.Options.TTL
+	if n := memcache.Options.NegativeTTL; n > 0 && (period <= 0 || n < period) {
+		period = n
+	}
+	if period <= 0 || memcache.now().Sub(memcache.lastSweep) < period {
+		return
+	}
+	memcache.lastSweep = memcache.now()
+	for elem := memcache.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if memcache.expired(elem.Value.(*cacheEntry)) { //nolint:errcheck,forcetypeassert
+			memcache.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// subjectsMatch returns true when two subjects share a digest or, when they
+// have no digests, are identical.
+func subjectsMatch(a, b attestation.Subject) bool {
+	for algo, val := range a.GetDigest() {
+		if val != "" && b.GetDigest()[algo] == val {
+			return true
+		}
+	}
+	return subjectToKey(a) == subjectToKey(b)
+}
+
+// envelopeSize returns the approximate size in bytes of an envelope: the
+// size of the serialized message for protobuf envelopes, otherwise the
+// size of its statement in JSON.
+func envelopeSize(env attestation.Envelope) int64 {
+	if msg, ok := env.(proto.Message); ok {
+		return int64(proto.Size(msg))
+	}
+	if env == nil || env.GetStatement() == nil {
+		return 0
 	}
-	k := buildKey(keys)
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
-	if v, ok := memcache.subject[k]; ok {
-		return v, nil
+	data, err := json.Marshal(env.GetStatement())
+	if err != nil {
+		return 0
 	}
-	return nil, nil
+	return int64(len(data))
 }
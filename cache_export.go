@@ -0,0 +1,346 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/carabiner-dev/jsonl"
+	intoto "github.com/in-toto/attestation/go/v1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// CacheStats are the usage statistics of a cache.
+type CacheStats struct {
+	// Hits and Misses count the lookups since the cache was created. Known
+	// empty results count as hits.
+	Hits   uint64
+	Misses uint64
+
+	// Entries is the number of cached queries, including expired ones not
+	// yet dropped.
+	Entries int
+
+	// Bytes is the approximate size of the cached data.
+	Bytes int64
+
+	// OldestAge and NewestAge are the ages of the oldest and the most
+	// recently stored entries.
+	OldestAge time.Duration
+	NewestAge time.Duration
+}
+
+// addAge records the storage time of an entry in the stats.
+func (stats *CacheStats) addAge(now, stored time.Time) {
+	age := now.Sub(stored)
+	if stats.Entries == 0 || age > stats.OldestAge {
+		stats.OldestAge = age
+	}
+	if stats.Entries == 0 || age < stats.NewestAge {
+		stats.NewestAge = age
+	}
+	stats.Entries++
+}
+
+// cacheRecord is a cached query result in an export file. Each record is
+// written as a line of JSON, the envelopes are embedded in the format used
+// by the disk cache, which the envelope parsers read back.
+type cacheRecord struct {
+	Query          string                      `json:"query"`
+	Scope          string                      `json:"scope,omitempty"`
+	Stored         time.Time                   `json:"stored"`
+	Expires        time.Time                   `json:"expires,omitzero"`
+	Subjects       []json.RawMessage           `json:"subjects,omitempty"`
+	PredicateTypes []attestation.PredicateType `json:"predicateTypes,omitempty"`
+	Envelopes      []cacheRecordEnvelope       `json:"envelopes"`
+
+	// The decoded subjects and envelopes of imported records
+	subjects []attestation.Subject
+	atts     []attestation.Envelope
+}
+
+// cacheRecordEnvelope is an envelope in a cache record with the predicate
+// origin recorded by the agent.
+type cacheRecordEnvelope struct {
+	Envelope json.RawMessage `json:"envelope"`
+	Origin   json.RawMessage `json:"origin,omitempty"`
+}
+
+// newCacheRecord returns the export record of a query result.
+func newCacheRecord(query, scope string, stored, expires time.Time, subjects []attestation.Subject, pt []attestation.PredicateType) (*cacheRecord, error) {
+	rec := &cacheRecord{
+		Query:          query,
+		Scope:          scope,
+		Stored:         stored.UTC(),
+		PredicateTypes: pt,
+		Envelopes:      []cacheRecordEnvelope{},
+	}
+	if !expires.IsZero() {
+		rec.Expires = expires.UTC()
+	}
+	for _, s := range subjects {
+		data, err := protojson.Marshal(toResourceDescriptor(s))
+		if err != nil {
+			return nil, fmt.Errorf("marshaling subject: %w", err)
+		}
+		rec.Subjects = append(rec.Subjects, data)
+	}
+	return rec, nil
+}
+
+// addEnvelope serializes an envelope into the record.
+func (rec *cacheRecord) addEnvelope(env attestation.Envelope) error {
+	data, err := marshalCachedEnvelope(env)
+	if err != nil {
+		return err
+	}
+	re := cacheRecordEnvelope{Envelope: data}
+	if rd := originDescriptor(env); rd != nil {
+		re.Origin, err = protojson.Marshal(rd)
+		if err != nil {
+			return fmt.Errorf("marshaling predicate origin: %w", err)
+		}
+	}
+	rec.Envelopes = append(rec.Envelopes, re)
+	return nil
+}
+
+// decode parses the subjects and envelopes of an imported record.
+func (rec *cacheRecord) decode() error {
+	if _, ok := diskCacheIndexes[rec.Query]; !ok {
+		return fmt.Errorf("unknown query kind %q", rec.Query)
+	}
+	if rec.Stored.IsZero() {
+		return errors.New("record has no storage time")
+	}
+	for _, data := range rec.Subjects {
+		rd := &intoto.ResourceDescriptor{}
+		if err := protojson.Unmarshal(data, rd); err != nil {
+			return fmt.Errorf("parsing subject: %w", err)
+		}
+		rec.subjects = append(rec.subjects, rd)
+	}
+	for i, re := range rec.Envelopes {
+		env, err := parseCachedEnvelope(re.Envelope, re.Origin)
+		if err != nil {
+			return fmt.Errorf("envelope #%d: %w", i, err)
+		}
+		rec.atts = append(rec.atts, env)
+	}
+	return nil
+}
+
+// expiry returns when an imported result expires: the expiry recorded in
+// the export or, if earlier, the end of the TTL of the importing cache. A
+// zero time means the result does not expire.
+func (rec *cacheRecord) expiry(ttl time.Duration) time.Time {
+	expires := rec.Expires
+	if ttl > 0 && (expires.IsZero() || rec.Stored.Add(ttl).Before(expires)) {
+		expires = rec.Stored.Add(ttl)
+	}
+	return expires
+}
+
+// newCacheRecordEncoder returns an encoder writing one record per line.
+func newCacheRecordEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc
+}
+
+// readCacheRecords reads the records of an export and calls fn with each
+// of them, decoded.
+func readCacheRecords(r io.Reader, fn func(*cacheRecord) error) error {
+	for i, line := range jsonl.IterateBundle(r) {
+		if line == nil {
+			return fmt.Errorf("line %d: invalid JSON data", i+1)
+		}
+		rec := &cacheRecord{}
+		if err := json.NewDecoder(line).Decode(rec); err != nil {
+			return fmt.Errorf("line %d: parsing cache record: %w", i+1, err)
+		}
+		if err := rec.decode(); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if err := fn(rec); err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Export writes the results in the cache to w as JSON lines, one query per
+// line, from the least to the most recently used. Expired results are not
+// exported. The data can be loaded into a memory or disk cache with Import.
+func (memcache *MemoryCache) Export(w io.Writer) error {
+	memcache.mtx.Lock()
+	entries := make([]*cacheEntry, 0, memcache.lru.Len())
+	for elem := memcache.lru.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*cacheEntry) //nolint:errcheck,forcetypeassert
+		if !memcache.expired(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	memcache.mtx.Unlock()
+
+	enc := newCacheRecordEncoder(w)
+	for _, entry := range entries {
+		var expires time.Time
+		if entry.ttl > 0 {
+			expires = entry.stored.Add(entry.ttl)
+		}
+		rec, err := newCacheRecord(entry.query, entry.scope, entry.stored, expires, entry.subjects, entry.predicateTypes)
+		if err != nil {
+			return err
+		}
+		for _, env := range entry.atts {
+			if err := rec.addEnvelope(env); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("writing cache record: %w", err)
+		}
+	}
+	return nil
+}
+
+// Import loads the results exported from a cache. Imported results keep
+// their original storage time and expire at the recorded expiry or when
+// the TTL of the cache runs out, whichever comes first. Results already
+// expired are skipped.
+//
+// The envelopes are parsed again from the export and lose the results of
+// their signature verification, the agent verifies them again when they
+// are served from the cache.
+func (memcache *MemoryCache) Import(r io.Reader) error {
+	return readCacheRecords(r, func(rec *cacheRecord) error {
+		memcache.mtx.Lock()
+		defer memcache.mtx.Unlock()
+
+		ttl := memcache.Options.TTL
+		if len(rec.atts) == 0 {
+			ttl = memcache.Options.NegativeTTL
+		}
+		expires := rec.expiry(ttl)
+		if !expires.IsZero() && !memcache.now().Before(expires) {
+			return nil
+		}
+
+		ctx := withCacheScope(context.Background(), rec.Scope)
+		entry := &cacheEntry{
+			key:            memoryKey(ctx, rec.Query, rec.subjects, rec.PredicateTypes),
+			query:          rec.Query,
+			scope:          rec.Scope,
+			atts:           rec.atts,
+			stored:         rec.Stored,
+			subjects:       rec.subjects,
+			predicateTypes: rec.PredicateTypes,
+			imported:       true,
+		}
+		if !expires.IsZero() {
+			entry.ttl = expires.Sub(rec.Stored)
+		}
+		memcache.add(entry)
+		return nil
+	})
+}
+
+// Export writes the results in the cache to w as JSON lines, one query per
+// line. Expired and damaged entries are not exported. The data can be
+// loaded into a memory or disk cache with Import.
+func (diskcache *DiskCache) Export(w io.Writer) error {
+	enc := newCacheRecordEncoder(w)
+	queries := []string{cacheQuerySubject, cacheQueryPredicateType, cacheQuerySubjectPredicateType}
+	for _, query := range queries {
+		paths, err := filepath.Glob(filepath.Join(diskcache.dir, diskCacheIndexes[query], "*.json"))
+		if err != nil {
+			return fmt.Errorf("listing index entries: %w", err)
+		}
+		slices.Sort(paths)
+		for _, path := range paths {
+			rec, err := diskcache.exportEntry(path)
+			if err != nil {
+				logrus.Debugf("not exporting cache entry %s: %v", path, err)
+				continue
+			}
+			if rec == nil {
+				continue
+			}
+			if err := enc.Encode(rec); err != nil {
+				return fmt.Errorf("writing cache record: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// exportEntry returns the export record of an index entry, or nil if the
+// entry expired or was removed.
+func (diskcache *DiskCache) exportEntry(path string) (*cacheRecord, error) {
+	entry, err := readIndexEntry(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if diskcache.expired(entry) {
+		return nil, nil
+	}
+	if entry.Query == "" {
+		return nil, errors.New("entry has no query kind")
+	}
+
+	rec := &cacheRecord{
+		Query:          entry.Query,
+		Scope:          entry.Scope,
+		Stored:         entry.Stored,
+		Expires:        entry.Expires,
+		Subjects:       entry.Subjects,
+		PredicateTypes: entry.PredicateTypes,
+		Envelopes:      []cacheRecordEnvelope{},
+	}
+	for _, ie := range entry.Envelopes {
+		if len(ie.Digest) < 2 {
+			return nil, fmt.Errorf("invalid envelope digest %q", ie.Digest)
+		}
+		data, err := os.ReadFile(diskcache.envelopePath(ie.Digest))
+		if err != nil {
+			return nil, fmt.Errorf("reading envelope: %w", err)
+		}
+		rec.Envelopes = append(rec.Envelopes, cacheRecordEnvelope{Envelope: data, Origin: ie.Origin})
+	}
+	return rec, nil
+}
+
+// Import loads the results exported from a cache. Imported results keep
+// their original storage time and expire at the recorded expiry or when
+// the TTL of the cache runs out, whichever comes first. Results already
+// expired are skipped.
+func (diskcache *DiskCache) Import(r io.Reader) error {
+	return readCacheRecords(r, func(rec *cacheRecord) error {
+		ttl := diskcache.Options.TTL
+		if len(rec.atts) == 0 {
+			ttl = diskcache.Options.NegativeTTL
+		}
+		expires := rec.expiry(ttl)
+		if !expires.IsZero() && !diskcache.now().Before(expires) {
+			return nil
+		}
+		ctx := withCacheScope(context.Background(), rec.Scope)
+		return diskcache.write(ctx, rec.Query, rec.subjects, rec.PredicateTypes, &rec.atts, rec.Stored, expires)
+	})
+}
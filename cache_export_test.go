@@ -0,0 +1,230 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+)
+
+// exportCache is implemented by the caches with stats and export support
+type exportCache interface {
+	Cache
+	Stats() (CacheStats, error)
+	Export(io.Writer) error
+	Import(io.Reader) error
+}
+
+func newTestCaches(t *testing.T, clock *fakeClock, funcs ...CacheOption) map[string]exportCache {
+	t.Helper()
+	memcache := NewMemoryCache(funcs...)
+	memcache.now = clock.now
+	diskcache := NewDiskCache(t.TempDir(), funcs...)
+	diskcache.now = clock.now
+	return map[string]exportCache{"memory": memcache, "disk": diskcache}
+}
+
+func TestCacheStats(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Now()}
+	for name, cache := range newTestCaches(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			stats := func() CacheStats {
+				t.Helper()
+				stats, err := cache.Stats()
+				require.NoError(t, err)
+				return stats
+			}
+			require.Equal(t, CacheStats{}, stats())
+
+			atts := []attestation.Envelope{testDSSE(testStatement)}
+			require.NoError(t, cache.StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &atts))
+			clock.t = clock.t.Add(time.Minute)
+			require.NoError(t, cache.StoreAttestationsBySubject(t.Context(), cacheSubject("b"), &atts))
+			clock.t = clock.t.Add(time.Minute)
+
+			for _, d := range []string{"a", "b", "c"} {
+				_, err := cache.GetAttestationsBySubject(t.Context(), cacheSubject(d))
+				require.NoError(t, err)
+			}
+
+			s := stats()
+			require.Equal(t, uint64(2), s.Hits)
+			require.Equal(t, uint64(1), s.Misses)
+			require.Equal(t, 2, s.Entries)
+			require.Positive(t, s.Bytes)
+			require.Equal(t, 2*time.Minute, s.OldestAge)
+			require.Equal(t, time.Minute, s.NewestAge)
+		})
+	}
+}
+
+func TestCacheExportImport(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Now()}
+	pt := []attestation.PredicateType{"https://example.com/test/v1"}
+	scoped := withCacheScope(t.Context(), "scope")
+	atts := []attestation.Envelope{testDSSE(testStatement), testBundle(testStatement, true)}
+	opts := []CacheOption{WithCacheTTL(time.Hour), WithCacheNegativeTTL(time.Minute)}
+
+	for from, src := range newTestCaches(t, clock, opts...) {
+		require.NoError(t, src.StoreAttestationsBySubject(scoped, cacheSubject("a"), &atts))
+		require.NoError(t, src.StoreAttestationsByPredicateType(t.Context(), pt, &atts))
+		require.NoError(t, src.StoreAttestationsBySubjectAndPredicateType(scoped, cacheSubject("a"), pt, &[]attestation.Envelope{}))
+		require.NoError(t, src.StoreAttestationsBySubject(scoped, cacheSubject("b"), &[]attestation.Envelope{}))
+
+		// The negative result of b expires before the export
+		clock.t = clock.t.Add(2 * time.Minute)
+		require.NoError(t, src.StoreAttestationsBySubjectAndPredicateType(scoped, cacheSubject("a"), pt, &[]attestation.Envelope{}))
+
+		var buf bytes.Buffer
+		require.NoError(t, src.Export(&buf))
+		require.Equal(t, 3, strings.Count(buf.String(), "\n"), from)
+
+		for to, dst := range newTestCaches(t, clock, opts...) {
+			t.Run(from+"-to-"+to, func(t *testing.T) {
+				require.NoError(t, dst.Import(bytes.NewReader(buf.Bytes())))
+				ctx, read := withCacheRead(scoped)
+				res, err := dst.GetAttestationsBySubject(ctx, cacheSubject("a"))
+				require.NoError(t, err)
+				require.Len(t, *res, 2)
+				require.True(t, read.persistent.Load())
+				require.Equal(t, envelopeKey(atts[0]), envelopeKey((*res)[0]))
+				require.Equal(t, envelopeKey(atts[1]), envelopeKey((*res)[1]))
+
+				res, err = dst.GetAttestationsByPredicateType(t.Context(), pt)
+				require.NoError(t, err)
+				require.Len(t, *res, 2)
+
+				res, err = dst.GetAttestationsBySubjectAndPredicateType(scoped, cacheSubject("a"), pt)
+				require.NoError(t, err)
+				require.NotNil(t, res)
+				require.Empty(t, *res)
+
+				// Scopes are kept
+				res, err = dst.GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+				require.NoError(t, err)
+				require.Nil(t, res)
+
+				res, err = dst.GetAttestationsBySubject(scoped, cacheSubject("b"))
+				require.NoError(t, err)
+				require.Nil(t, res)
+			})
+		}
+	}
+}
+
+func TestCacheImportErrors(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name string
+		data string
+	}{
+		{"invalid-json", "{\n"},
+		{"unknown-query", `{"query":"other","stored":"2026-01-01T00:00:00Z","envelopes":[]}` + "\n"},
+		{"no-stored", `{"query":"subject","envelopes":[]}` + "\n"},
+		{"invalid-envelope", `{"query":"subject","stored":"2026-01-01T00:00:00Z","envelopes":[{"envelope":{"a":1}}]}` + "\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Error(t, NewMemoryCache().Import(strings.NewReader(tc.data)))
+			require.Error(t, NewDiskCache(t.TempDir()).Import(strings.NewReader(tc.data)))
+		})
+	}
+}
+
+func TestMemoryCacheImportIsPersistent(t *testing.T) {
+	t.Parallel()
+	src := NewMemoryCache()
+	atts := []attestation.Envelope{testDSSE(testStatement)}
+	require.NoError(t, src.StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &atts))
+	var buf bytes.Buffer
+	require.NoError(t, src.Export(&buf))
+
+	memcache := NewMemoryCache()
+	require.NoError(t, memcache.StoreAttestationsBySubject(t.Context(), cacheSubject("b"), &atts))
+	require.NoError(t, memcache.Import(&buf))
+
+	// Imported envelopes are verified again by the agent, the rest of the
+	// entries are not
+	ctx, read := withCacheRead(t.Context())
+	b, err := memcache.GetAttestationsBySubject(ctx, cacheSubject("b"))
+	require.NoError(t, err)
+	require.Len(t, *b, 1)
+	require.Same(t, atts[0], (*b)[0])
+	require.False(t, read.persistent.Load())
+
+	// The agent annotates imported envelopes, each lookup gets copies
+	ctx, read = withCacheRead(t.Context())
+	a1, err := memcache.GetAttestationsBySubject(ctx, cacheSubject("a"))
+	require.NoError(t, err)
+	require.True(t, read.persistent.Load())
+	a2, err := memcache.GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.Len(t, *a1, 1)
+	require.Len(t, *a2, 1)
+	require.NotSame(t, (*a1)[0], (*a2)[0])
+	annotateVerificationFailure((*a1)[0], "unsigned")
+	require.Empty(t, VerificationFailure((*a2)[0]))
+}
+
+func TestImportedCacheKeepsSharedEntries(t *testing.T) {
+	t.Parallel()
+	src := NewMemoryCache()
+	atts := []attestation.Envelope{testDSSE(testStatement)}
+	require.NoError(t, src.StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &atts))
+	var buf bytes.Buffer
+	require.NoError(t, src.Export(&buf))
+
+	calls := 0
+	agent, err := New(
+		WithCache(NewMemoryCache()),
+		WithVerification(VerificationPolicyAnnotate),
+		WithRepository(&fakeFetcher{
+			fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+				calls++
+				return []attestation.Envelope{unsignedDSSE(testStatement)}, nil
+			},
+		}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, agent.Cache.(*MemoryCache).Import(&buf))
+
+	// Results fetched after the import are served as cached, without
+	// verifying and annotating the shared envelopes again
+	first, err := agent.FetchAttestationsBySubject(t.Context(), cacheSubject("b"))
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	require.Equal(t, "unsigned", VerificationFailure(first[0]))
+
+	var wg sync.WaitGroup
+	results := make(chan []attestation.Envelope, 16)
+	errs := make(chan error, 16)
+	for range 16 {
+		wg.Go(func() {
+			res, err := agent.FetchAttestationsBySubject(t.Context(), cacheSubject("b"))
+			errs <- err
+			results <- res
+		})
+	}
+	wg.Wait()
+	close(errs)
+	close(results)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	for res := range results {
+		require.Len(t, res, 1)
+		require.Equal(t, "unsigned", VerificationFailure(res[0]))
+	}
+	require.Equal(t, 1, calls)
+}
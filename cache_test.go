@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/carabiner-dev/signer/key"
+	intoto "github.com/in-toto/attestation/go/v1"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/bare"
+)
+
+// fakeClock is a manually advanced clock for the cache tests
+type fakeClock struct{ t time.Time }
+
+func (fc *fakeClock) now() time.Time { return fc.t }
+
+func cacheSubject(digest string) []attestation.Subject {
+	return []attestation.Subject{&intoto.ResourceDescriptor{Digest: map[string]string{"sha256": digest}}}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Now()}
+	memcache := NewMemoryCache(WithCacheTTL(time.Minute))
+	memcache.now = clock.now
+
+	atts := []attestation.Envelope{&bare.Envelope{}}
+	require.NoError(t, memcache.StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &atts))
+
+	clock.t = clock.t.Add(30 * time.Second)
+	res, err := memcache.GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.Len(t, *res, 1)
+
+	clock.t = clock.t.Add(time.Minute)
+	res, err = memcache.GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.Zero(t, memcache.Len())
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name   string
+		opts   []CacheOption
+		expect []string
+	}{
+		{"unbounded", nil, []string{"a", "b", "c"}},
+		{"max-entries", []CacheOption{WithCacheMaxEntries(2)}, []string{"a", "c"}},
+		{"max-bytes", []CacheOption{WithCacheMaxBytes(2 * envelopeSize(testDSSE(testStatement)))}, []string{"a", "c"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			memcache := NewMemoryCache(tc.opts...)
+			atts := []attestation.Envelope{testDSSE(testStatement)}
+			for _, d := range []string{"a", "b"} {
+				require.NoError(t, memcache.StoreAttestationsBySubject(t.Context(), cacheSubject(d), &atts))
+			}
+			// Reading a makes b the least recently used entry
+			_, err := memcache.GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+			require.NoError(t, err)
+			require.NoError(t, memcache.StoreAttestationsBySubject(t.Context(), cacheSubject("c"), &atts))
+
+			require.Equal(t, len(tc.expect), memcache.Len())
+			for _, d := range tc.expect {
+				res, err := memcache.GetAttestationsBySubject(t.Context(), cacheSubject(d))
+				require.NoError(t, err)
+				require.NotNil(t, res, d)
+			}
+		})
+	}
+}
+
+func TestMemoryCacheInvalidate(t *testing.T) {
+	t.Parallel()
+	memcache := NewMemoryCache()
+	atts := []attestation.Envelope{&bare.Envelope{}}
+	pt := []attestation.PredicateType{"https://example.com/test/v1"}
+	require.NoError(t, memcache.StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &atts))
+	require.NoError(t, memcache.StoreAttestationsBySubject(t.Context(), cacheSubject("b"), &atts))
+	require.NoError(t, memcache.StoreAttestationsByPredicateType(t.Context(), pt, &atts))
+	require.NoError(t, memcache.StoreAttestationsBySubjectAndPredicateType(t.Context(), cacheSubject("b"), pt, &atts))
+	require.Equal(t, 4, memcache.Len())
+
+	require.NoError(t, memcache.Invalidate(cacheSubject("a"), nil))
+	require.Equal(t, 3, memcache.Len())
+	res, err := memcache.GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	require.NoError(t, memcache.Invalidate(nil, pt))
+	require.Equal(t, 1, memcache.Len())
+
+	require.NoError(t, memcache.Purge())
+	require.Zero(t, memcache.Len())
+}
+
+func TestCacheRefreshesStaleResults(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Now()}
+	memcache := NewMemoryCache(WithCacheTTL(time.Minute))
+	memcache.now = clock.now
+
+	agent, err := New()
+	require.NoError(t, err)
+	agent.Cache = memcache
+
+	calls := 0
+	agent.Repositories = append(agent.Repositories, &fakeFetcher{
+		fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+			calls++
+			return []attestation.Envelope{&bare.Envelope{}}, nil
+		},
+	})
+
+	for _, advance := range []time.Duration{0, 10 * time.Second, 2 * time.Minute} {
+		clock.t = clock.t.Add(advance)
+		atts, err := agent.FetchAttestationsBySubject(t.Context(), cacheSubject("a"))
+		require.NoError(t, err)
+		require.Len(t, atts, 1)
+	}
+	require.Equal(t, 2, calls)
+}
+
+func TestNegativeCache(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name  string
+		opts  []CacheOption
+		calls int
+	}{
+		{"no-negative-ttl", nil, 2},
+		{"negative-ttl", []CacheOption{WithCacheNegativeTTL(time.Minute)}, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			agent, err := New(WithCache(NewMemoryCache(tc.opts...)), WithRepository(&fakeFetcher{
+				fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+					calls++
+					return []attestation.Envelope{}, nil
+				},
+			}))
+			require.NoError(t, err)
+
+			for i := range 2 {
+				atts, report, err := agent.FetchAttestationsBySubjectWithReport(t.Context(), cacheSubject("a"))
+				require.NoError(t, err)
+				require.Empty(t, atts)
+				require.Equal(t, i == 1 && tc.calls == 1, report.FromCache)
+			}
+			require.Equal(t, tc.calls, calls)
+		})
+	}
+}
+
+func TestCacheScope(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	fn := func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+		calls++
+		return []attestation.Envelope{&bare.Envelope{}}, nil
+	}
+	agent, err := New(WithRepository(&fakeFetcher{fetchBySubjectFunc: fn}))
+	require.NoError(t, err)
+
+	fetch := func(opts ...FetchOptionsFunc) *FetchReport {
+		t.Helper()
+		_, report, err := agent.FetchAttestationsBySubjectWithReport(t.Context(), cacheSubject("a"), opts...)
+		require.NoError(t, err)
+		return report
+	}
+	require.False(t, fetch().FromCache)
+	require.True(t, fetch().FromCache)
+	// Limits don't change the cached results
+	require.True(t, fetch(WithLimit(1)).FromCache)
+
+	// A new repository changes the results of the query
+	require.NoError(t, agent.AddRepository(&fakeFetcher{fetchBySubjectFunc: fn}))
+	require.False(t, fetch().FromCache)
+	require.Equal(t, 3, calls)
+
+	// So do new verification keys and trust roots
+	agent.AddKeys(&key.Public{Data: "key1"})
+	require.False(t, fetch().FromCache)
+	require.True(t, fetch().FromCache)
+	agent.AddKeys(&key.Public{Data: "key2"})
+	require.False(t, fetch().FromCache)
+	agent.Options.TrustedRoot = &root.BaseTrustedMaterial{}
+	require.False(t, fetch().FromCache)
+	require.True(t, fetch().FromCache)
+}
+
+func TestStoreInvalidatesCache(t *testing.T) {
+	t.Parallel()
+	repo := &memoryRepo{}
+	agent, err := New(
+		WithCache(NewMemoryCache(WithCacheNegativeTTL(time.Hour))),
+		WithRepository(repo),
+	)
+	require.NoError(t, err)
+
+	env := testDSSE(testStatement)
+	subjects := env.GetStatement().GetSubjects()
+	atts, err := agent.FetchAttestationsBySubject(t.Context(), subjects)
+	require.NoError(t, err)
+	require.Empty(t, atts)
+
+	require.NoError(t, agent.Store(t.Context(), []attestation.Envelope{env}))
+	atts, report, err := agent.FetchAttestationsBySubjectWithReport(t.Context(), subjects)
+	require.NoError(t, err)
+	require.Len(t, atts, 1)
+	require.False(t, report.FromCache)
+}
@@ -0,0 +1,343 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"go.yaml.in/yaml/v3"
+)
+
+// ConfigVersion is the version of the agent configuration format supported
+// by this module.
+const ConfigVersion = "v1"
+
+// Config is the declarative configuration of a collector agent. It is read
+// from a YAML or JSON document by LoadConfig. Settings left unset in the
+// document keep the agent defaults.
+type Config struct {
+	// Version of the configuration format, it must be set to ConfigVersion.
+	Version string `yaml:"version"`
+
+	// Repositories to read and write attestations.
+	Repositories []RepositoryConfig `yaml:"repositories"`
+
+	// Keys are paths to public key files used to verify signatures. Relative
+	// paths are resolved from the directory of the configuration file.
+	Keys []string `yaml:"keys"`
+
+	MaxReadSize       *int64 `yaml:"maxReadSize"`
+	MaxFetchBytes     *int64 `yaml:"maxFetchBytes"`
+	MaxFetchEnvelopes *int   `yaml:"maxFetchEnvelopes"`
+	ParallelFetches   *int   `yaml:"parallelFetches"`
+	ParallelStores    *int   `yaml:"parallelStores"`
+	FailIfNoFetchers  *bool  `yaml:"failIfNoFetchers"`
+	Deduplicate       *bool  `yaml:"deduplicate"`
+
+	Cache *CacheConfig `yaml:"cache"`
+	Store *StoreConfig `yaml:"store"`
+
+	// dir is the directory used to resolve relative paths
+	dir string
+}
+
+// RepositoryConfig configures a repository in the agent.
+type RepositoryConfig struct {
+	// Init is the repository init string, eg github:owner/repo
+	Init string `yaml:"init"`
+
+	// Options are the driver options. Secrets are not written inline,
+	// options of the secret kind (token-env, password-env) take the name of
+	// the environment variable holding the secret.
+	Options map[string]string `yaml:"options"`
+
+	// Tier is the priority tier of the repository, see
+	// Agent.SetRepositoryTier.
+	Tier int `yaml:"tier"`
+
+	// Timeout, RateLimit and Retries set the call policy of the
+	// repository, see RepositoryPolicy.
+	Timeout   time.Duration `yaml:"timeout"`
+	RateLimit float64       `yaml:"rateLimit"`
+	Retries   uint          `yaml:"retries"`
+}
+
+// policy returns the call policy set in the repository configuration.
+func (rc *RepositoryConfig) policy() RepositoryPolicy {
+	return RepositoryPolicy{Timeout: rc.Timeout, RateLimit: rc.RateLimit, Retries: rc.Retries}
+}
+
+// CacheConfig configures the agent cache.
+type CacheConfig struct {
+	Enabled *bool `yaml:"enabled"`
+
+	// TTL, NegativeTTL, MaxEntries and MaxBytes bound the memory cache,
+	// see CacheOptions.
+	TTL         time.Duration `yaml:"ttl"`
+	NegativeTTL time.Duration `yaml:"negativeTTL"`
+	MaxEntries  int           `yaml:"maxEntries"`
+	MaxBytes    int64         `yaml:"maxBytes"`
+}
+
+// options returns the cache options set in the configuration.
+func (cc *CacheConfig) options() CacheOptions {
+	return CacheOptions{TTL: cc.TTL, NegativeTTL: cc.NegativeTTL, MaxEntries: cc.MaxEntries, MaxBytes: cc.MaxBytes}
+}
+
+// StoreConfig configures how the agent stores attestations.
+type StoreConfig struct {
+	Policy StorePolicy        `yaml:"policy"`
+	Quorum int                `yaml:"quorum"`
+	Routes []StoreRouteConfig `yaml:"routes"`
+}
+
+// StoreRouteConfig is the configuration file representation of a StoreRoute.
+type StoreRouteConfig struct {
+	Repository       string   `yaml:"repository"`
+	PredicateTypes   []string `yaml:"predicateTypes"`
+	DigestAlgorithms []string `yaml:"digestAlgorithms"`
+	SubjectNames     []string `yaml:"subjectNames"`
+}
+
+// toStoreRoute converts the route configuration to a StoreRoute.
+func (rc *StoreRouteConfig) toStoreRoute() StoreRoute {
+	route := StoreRoute{
+		Repository:       rc.Repository,
+		DigestAlgorithms: rc.DigestAlgorithms,
+		SubjectNames:     rc.SubjectNames,
+	}
+	for _, pt := range rc.PredicateTypes {
+		route.PredicateTypes = append(route.PredicateTypes, attestation.PredicateType(pt))
+	}
+	return route
+}
+
+// LoadConfig reads an agent configuration document in YAML or JSON format
+// from r and validates it.
+func LoadConfig(r io.Reader) (*Config, error) {
+	conf := &Config{}
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(conf); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("configuration document is empty")
+		}
+		return nil, fmt.Errorf("parsing configuration: %w", err)
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return conf, nil
+}
+
+// Validate checks the configuration and returns an error describing all
+// the problems found.
+func (conf *Config) Validate() error {
+	errs := []error{}
+	switch conf.Version {
+	case ConfigVersion:
+	case "":
+		errs = append(errs, fmt.Errorf("configuration version not set (supported: %s)", ConfigVersion))
+	default:
+		errs = append(errs, fmt.Errorf("unsupported configuration version %q (supported: %s)", conf.Version, ConfigVersion))
+	}
+
+	if err := LoadDefaultRepositoryTypes(); err != nil {
+		errs = append(errs, fmt.Errorf("loading repository types: %w", err))
+	}
+
+	for i, rc := range conf.Repositories {
+		if err := rc.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("repositories[%d]: %w", i, err))
+		}
+	}
+
+	for i, k := range conf.Keys {
+		if k == "" {
+			errs = append(errs, fmt.Errorf("keys[%d]: key path is empty", i))
+		}
+	}
+
+	if conf.MaxReadSize != nil && *conf.MaxReadSize < 0 {
+		errs = append(errs, errors.New("maxReadSize cannot be negative"))
+	}
+	if conf.MaxFetchBytes != nil && *conf.MaxFetchBytes < 0 {
+		errs = append(errs, errors.New("maxFetchBytes cannot be negative"))
+	}
+	if conf.MaxFetchEnvelopes != nil && *conf.MaxFetchEnvelopes < 0 {
+		errs = append(errs, errors.New("maxFetchEnvelopes cannot be negative"))
+	}
+	if conf.ParallelFetches != nil && *conf.ParallelFetches < 1 {
+		errs = append(errs, errors.New("parallelFetches must be at least 1"))
+	}
+	if conf.ParallelStores != nil && *conf.ParallelStores < 1 {
+		errs = append(errs, errors.New("parallelStores must be at least 1"))
+	}
+
+	if conf.Cache != nil {
+		if conf.Cache.TTL < 0 {
+			errs = append(errs, errors.New("cache.ttl cannot be negative"))
+		}
+		if conf.Cache.NegativeTTL < 0 {
+			errs = append(errs, errors.New("cache.negativeTTL cannot be negative"))
+		}
+		if conf.Cache.MaxEntries < 0 {
+			errs = append(errs, errors.New("cache.maxEntries cannot be negative"))
+		}
+		if conf.Cache.MaxBytes < 0 {
+			errs = append(errs, errors.New("cache.maxBytes cannot be negative"))
+		}
+	}
+
+	if conf.Store != nil {
+		switch conf.Store.Policy {
+		case "", StorePolicyAllOrNothing, StorePolicyBestEffort, StorePolicyQuorum:
+		default:
+			errs = append(errs, fmt.Errorf(
+				"store.policy: unknown store policy %q (valid: %s, %s, %s)", conf.Store.Policy,
+				StorePolicyAllOrNothing, StorePolicyBestEffort, StorePolicyQuorum,
+			))
+		}
+		if conf.Store.Quorum < 0 {
+			errs = append(errs, errors.New("store.quorum cannot be negative"))
+		}
+		for i, rc := range conf.Store.Routes {
+			route := rc.toStoreRoute()
+			if err := route.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("store.routes[%d]: %w", i, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the repository configuration.
+func (rc *RepositoryConfig) Validate() error {
+	if rc.Init == "" {
+		return errors.New("repository init string is empty")
+	}
+	if !strings.Contains(rc.Init, ":") {
+		return fmt.Errorf("init string %q has no repository type (eg github:owner/repo)", rc.Init)
+	}
+	t, _, opts, err := splitInitString(rc.Init, rc.Options)
+	if err != nil {
+		return err
+	}
+	mtx.Lock()
+	_, known := repositoryTypes[t]
+	mtx.Unlock()
+	if !known {
+		return fmt.Errorf("repository type unknown: %q", t)
+	}
+	if len(opts) > 0 {
+		if _, err := parseDriverOptions(t, opts, false); err != nil {
+			return err
+		}
+	}
+	policy := rc.policy()
+	return policy.Validate()
+}
+
+// NewFromConfig returns a new agent configured from the YAML or JSON document
+// at path. Any additional init functions are applied after the configuration.
+func NewFromConfig(path string, funcs ...InitFunction) (*Agent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening configuration file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	conf, err := LoadConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	conf.dir = filepath.Dir(path)
+
+	return New(append([]InitFunction{WithConfig(conf)}, funcs...)...)
+}
+
+// WithConfig configures the agent from a configuration document.
+func WithConfig(conf *Config) InitFunction {
+	return func(agent *Agent) error {
+		if err := conf.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+
+		if conf.MaxReadSize != nil {
+			agent.Options.MaxReadSize = *conf.MaxReadSize
+		}
+		if conf.MaxFetchBytes != nil {
+			agent.Options.MaxFetchBytes = *conf.MaxFetchBytes
+		}
+		if conf.MaxFetchEnvelopes != nil {
+			agent.Options.MaxFetchEnvelopes = *conf.MaxFetchEnvelopes
+		}
+		if conf.ParallelFetches != nil {
+			agent.Options.ParallelFetches = *conf.ParallelFetches
+		}
+		if conf.ParallelStores != nil {
+			agent.Options.ParallelStores = *conf.ParallelStores
+		}
+		if conf.FailIfNoFetchers != nil {
+			agent.Options.FailIfNoFetchers = *conf.FailIfNoFetchers
+		}
+		if conf.Deduplicate != nil {
+			agent.Options.Deduplicate = *conf.Deduplicate
+		}
+		if conf.Cache != nil && conf.Cache.Enabled != nil {
+			agent.Options.UseCache = *conf.Cache.Enabled
+		}
+		if conf.Cache != nil && conf.Cache.options() != (CacheOptions{}) {
+			memcache := NewMemoryCache()
+			memcache.Options = conf.Cache.options()
+			agent.Cache = memcache
+		}
+
+		if conf.Store != nil {
+			if conf.Store.Policy != "" {
+				agent.Options.StorePolicy = conf.Store.Policy
+			}
+			agent.Options.StoreQuorum = conf.Store.Quorum
+			for _, rc := range conf.Store.Routes {
+				agent.Options.StoreRoutes = append(agent.Options.StoreRoutes, rc.toStoreRoute())
+			}
+		}
+
+		keys := make([]string, 0, len(conf.Keys))
+		for _, k := range conf.Keys {
+			if !filepath.IsAbs(k) && conf.dir != "" {
+				k = filepath.Join(conf.dir, k)
+			}
+			keys = append(keys, k)
+		}
+		if err := WithKeyFiles(keys...)(agent); err != nil {
+			return err
+		}
+
+		for i, rc := range conf.Repositories {
+			if err := agent.AddRepositoryFromStringWithOptions(rc.Init, rc.Options); err != nil {
+				return fmt.Errorf("adding repositories[%d] (%s): %w", i, rc.Init, err)
+			}
+			repo := agent.Repositories[len(agent.Repositories)-1]
+			if rc.Tier != 0 {
+				agent.SetRepositoryTier(rc.Tier, repo)
+			}
+			if policy := rc.policy(); policy != (RepositoryPolicy{}) {
+				if err := agent.SetRepositoryPolicy(policy, repo); err != nil {
+					return fmt.Errorf("repositories[%d]: %w", i, err)
+				}
+			}
+		}
+		return nil
+	}
+}
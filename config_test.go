@@ -0,0 +1,278 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/repository/git"
+	"github.com/carabiner-dev/collector/repository/http"
+	"github.com/carabiner-dev/collector/repository/jsonl"
+	"github.com/carabiner-dev/collector/repository/note"
+	"github.com/carabiner-dev/collector/repository/oci"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		doc     string
+		mustErr string
+	}{
+		{"yaml", "version: v1\nrepositories:\n  - init: jsonl:test.jsonl\n    options:\n      max-parallel: \"3\"\nparallelFetches: 2\n", ""},
+		{"json", `{"version":"v1","repositories":[{"init":"github:owner/repo","options":{"token-env":"MY_TOKEN"}}],"deduplicate":true}`, ""},
+		{"store", "version: v1\nstore:\n  policy: quorum\n  quorum: 2\n  routes:\n    - repository: dnote\n      digestAlgorithms: [gitCommit]\n", ""},
+		{"repository-policy", "version: v1\nrepositories:\n  - init: jsonl:test.jsonl\n    tier: 1\n    timeout: 30s\n    rateLimit: 2.5\n    retries: 3\n", ""},
+		{"negative-rate", "version: v1\nrepositories:\n  - init: jsonl:test.jsonl\n    rateLimit: -1\n", "rate limit"},
+		{"empty", "", "empty"},
+		{"no-version", "repositories: []\n", "version not set"},
+		{"bad-version", "version: v9\n", "unsupported configuration version"},
+		{"unknown-field", "version: v1\nparallel: 2\n", "parallel"},
+		{"no-init", "version: v1\nrepositories:\n  - options: {}\n", "repositories[0]: repository init string is empty"},
+		{"unknown-type", "version: v1\nrepositories:\n  - init: ftp:example\n", "repository type unknown"},
+		{"unknown-option", "version: v1\nrepositories:\n  - init: jsonl:test.jsonl\n    options:\n      workers: \"2\"\n", "valid options: max-parallel"},
+		{"inline-secret", "version: v1\nrepositories:\n  - init: github:owner/repo\n    options:\n      token: abc\n", "use token-env"},
+		{"bad-option-value", "version: v1\nrepositories:\n  - init: jsonl:test.jsonl\n    options:\n      max-parallel: many\n", "max-parallel"},
+		{"init-string-option", "version: v1\nrepositories:\n  - init: jsonl:test.jsonl;workers=2\n", "valid options: max-parallel"},
+		{"duplicate-option", "version: v1\nrepositories:\n  - init: jsonl:test.jsonl;max-parallel=2\n    options:\n      max-parallel: \"3\"\n", "more than once"},
+		{"negative-size", "version: v1\nmaxReadSize: -1\n", "maxReadSize"},
+		{"zero-parallel", "version: v1\nparallelStores: 0\n", "parallelStores"},
+		{"bad-policy", "version: v1\nstore:\n  policy: some\n", "store.policy"},
+		{"bad-route", "version: v1\nstore:\n  routes:\n    - predicateTypes: [a]\n", "store.routes[0]"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			conf, err := LoadConfig(strings.NewReader(tc.doc))
+			if tc.mustErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.mustErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, ConfigVersion, conf.Version)
+		})
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "collector.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`version: v1
+repositories:
+  - init: jsonl:repository/jsonl/testdata/single.jsonl
+    options:
+      max-parallel: "3"
+  - init: jsonl:repository/jsonl/testdata/multiple.jsonl
+maxReadSize: 1024
+maxFetchBytes: 4096
+maxFetchEnvelopes: 10
+parallelFetches: 2
+failIfNoFetchers: true
+cache:
+  enabled: false
+  ttl: 10m
+  negativeTTL: 1m
+  maxEntries: 100
+store:
+  policy: best-effort
+`), 0o600))
+
+	agent, err := NewFromConfig(path, WithParallelStores(1))
+	require.NoError(t, err)
+	require.Len(t, agent.Repositories, 2)
+	require.Equal(t, 3, agent.Repositories[0].(*jsonl.Collector).Options.MaxParallel)
+	require.Equal(t, int64(1024), agent.Options.MaxReadSize)
+	require.Equal(t, int64(4096), agent.Options.MaxFetchBytes)
+	require.Equal(t, 10, agent.Options.MaxFetchEnvelopes)
+	require.Equal(t, 2, agent.Options.ParallelFetches)
+	require.Equal(t, 1, agent.Options.ParallelStores)
+	require.True(t, agent.Options.FailIfNoFetchers)
+	require.False(t, agent.Options.UseCache)
+	require.Equal(t, CacheOptions{TTL: 10 * time.Minute, NegativeTTL: time.Minute, MaxEntries: 100}, agent.Cache.(*MemoryCache).Options)
+	require.Equal(t, StorePolicyBestEffort, agent.Options.StorePolicy)
+
+	_, err = NewFromConfig(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestParseDriverOptions(t *testing.T) {
+	t.Setenv("COLLECTOR_TEST_TOKEN", "secret")
+	require.NoError(t, LoadDefaultRepositoryTypes())
+
+	v, err := parseDriverOptions("github", map[string]string{"token-env": "COLLECTOR_TEST_TOKEN"}, true)
+	require.NoError(t, err)
+	s, ok := v.str("token-env")
+	require.True(t, ok)
+	require.Equal(t, "secret", s)
+
+	_, err = parseDriverOptions("github", map[string]string{"token-env": "COLLECTOR_TEST_UNSET"}, true)
+	require.ErrorContains(t, err, "COLLECTOR_TEST_UNSET is not set")
+
+	// Without resolving, only the variable name is checked
+	_, err = parseDriverOptions("github", map[string]string{"token-env": "COLLECTOR_TEST_UNSET"}, false)
+	require.NoError(t, err)
+
+	v, err = parseDriverOptions("release", map[string]string{"retries": "4", "tag": "v1.0.0"}, true)
+	require.NoError(t, err)
+	r, ok := v.uinteger("retries")
+	require.True(t, ok)
+	require.Equal(t, uint(4), r)
+
+	_, err = parseDriverOptions("release", map[string]string{"retries": "-1"}, true)
+	require.Error(t, err)
+
+	_, err = parseDriverOptions("sbomfs", map[string]string{"a": "b"}, true)
+	require.ErrorContains(t, err, "sbomfs repositories take no options")
+
+	_, err = parseDriverOptions("custom", map[string]string{"a": "b"}, true)
+	require.ErrorContains(t, err, "does not support options")
+}
+
+func TestSplitInitString(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, LoadDefaultRepositoryTypes())
+	for _, tc := range []struct {
+		name     string
+		init     string
+		opts     map[string]string
+		location string
+		expect   map[string]string
+		mustErr  bool
+	}{
+		{"no-options", "jsonl:a.jsonl", nil, "a.jsonl", map[string]string{}, false},
+		{"semicolon", "jsonl:a.jsonl;max-parallel=3", nil, "a.jsonl", map[string]string{"max-parallel": "3"}, false},
+		{"options", "https:example.com/atts.jsonl;retries=3;jsonl=true", nil, "example.com/atts.jsonl", map[string]string{"retries": "3", "jsonl": "true"}, false},
+		{"url-params", "https:example.com/atts.jsonl?sig=a%2Fb&retries=3", nil, "example.com/atts.jsonl?sig=a%2Fb&retries=3", map[string]string{}, false},
+		{"url-query-and-options", "https:example.com/x?retries=1;jsonl=true", nil, "example.com/x?retries=1", map[string]string{"jsonl": "true"}, false},
+		{"trailing-semicolon", "fs:/tmp/a?b;", nil, "/tmp/a?b", map[string]string{}, false},
+		{"escaped", "note:git+https://github.com/example/app@abc;username=bot%3Bci", nil, "git+https://github.com/example/app@abc", map[string]string{"username": "bot;ci"}, false},
+		{"merged", "jsonl:a.jsonl;max-parallel=3", map[string]string{"other": "1"}, "a.jsonl", map[string]string{"max-parallel": "3", "other": "1"}, false},
+		{"not-builtin", "custom:a;b=c", nil, "a;b=c", nil, false},
+		{"no-driver-options", "sbomfs:/data/sbom?v=1;a=b", nil, "/data/sbom?v=1", map[string]string{"a": "b"}, false},
+		{"path-question-mark", "fs:/tmp/what?", nil, "/tmp/what?", map[string]string{}, false},
+		{"path-query", "fs:/tmp/a?b=c", nil, "/tmp/a?b=c", map[string]string{}, false},
+		{"path-semicolon", "fs:/tmp/a;b", nil, "/tmp/a;b", map[string]string{}, false},
+		{"path-semicolon-pairs", "fs:/tmp/a;b;rekor-url=x", nil, "/tmp/a;b;rekor-url=x", map[string]string{}, false},
+		{"git-url-query", "git:https://example.com/repo.git?x=1@main#atts", nil, "https://example.com/repo.git?x=1@main#atts", map[string]string{}, false},
+		{"git-query", "git:https://example.com/repo.git?ref=main&path=atts", nil, "https://example.com/repo.git?ref=main&path=atts", map[string]string{}, false},
+		{"git-options", "git:https://example.com/repo.git;ref=main;path=atts", nil, "https://example.com/repo.git", map[string]string{"ref": "main", "path": "atts"}, false},
+		{"oci-options", "oci:localhost:5000/app:v1;insecure=true", nil, "localhost:5000/app:v1", map[string]string{"insecure": "true"}, false},
+		{"duplicate", "jsonl:a.jsonl;max-parallel=1", map[string]string{"max-parallel": "2"}, "", nil, true},
+		{"no-name", "jsonl:a.jsonl;=1", nil, "", nil, true},
+		{"bad-escape", "jsonl:a.jsonl;max-parallel=%zz", nil, "", nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, location, opts, err := splitInitString(tc.init, tc.opts)
+			if tc.mustErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.location, location)
+			require.Equal(t, tc.expect, opts)
+		})
+	}
+}
+
+func TestRepositoryFromStringOptions(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, LoadDefaultRepositoryTypes())
+
+	repo, err := RepositoryFromString("https:example.com/atts.jsonl;retries=3;jsonl=true")
+	require.NoError(t, err)
+	require.Equal(t, uint(3), repo.(*http.Collector).Options.Retries)
+	require.True(t, repo.(*http.Collector).Options.ReadJSONL)
+
+	repo, err = RepositoryFromString("note:git+https://github.com/example/app@abc123;push=false")
+	require.NoError(t, err)
+	require.NotNil(t, repo.(*note.Collector).Options.Push)
+	require.False(t, *repo.(*note.Collector).Options.Push)
+
+	for init, mustErr := range map[string]string{
+		"jsonl:a.jsonl;colour=red":         `unknown option "colour"`,
+		"http:example.com;retries=x":       `invalid value for http option "retries"`,
+		"git:https://example.com/repo;a=b": `unknown option "a"`,
+		"sbomfs:sbom.json;a=b":             `unknown option "a": sbomfs repositories take no options`,
+		"ossrebuild:;a=b":                  `unknown option "a": ossrebuild repositories take no options`,
+		"ftp:example.com;a=b":              "repository type unknown",
+	} {
+		_, err := RepositoryFromString(init)
+		require.ErrorContains(t, err, mustErr, init)
+	}
+
+	_, err = RepositoryFromStringWithOptions("sbomfs:sbom.json", map[string]string{"a": "b"})
+	require.ErrorContains(t, err, `unknown option "a"`)
+
+	// Query parameters named like options stay in the URL
+	repo, err = RepositoryFromString("https:example.com/atts.jsonl?retries=7")
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.com/atts.jsonl?retries=7"}, repo.(*http.Collector).Options.URLs)
+	require.NotEqual(t, uint(7), repo.(*http.Collector).Options.Retries)
+}
+
+func TestRepositoryFromStringOverride(t *testing.T) {
+	// Not parallel, the test replaces a built-in driver
+	require.NoError(t, LoadDefaultRepositoryTypes())
+	UnregisterCollectorType("jsonl")
+	t.Cleanup(func() {
+		UnregisterCollectorType("jsonl")
+		require.NoError(t, LoadDefaultRepositoryTypes())
+	})
+
+	var location string
+	require.NoError(t, RegisterCollectorType("jsonl", func(init string) (attestation.Repository, error) {
+		location = init
+		return &fakeFetcher{}, nil
+	}))
+
+	// The init string reaches the new factory unchanged
+	repo, err := RepositoryFromString("jsonl:a.jsonl;max-parallel=3")
+	require.NoError(t, err)
+	require.IsType(t, &fakeFetcher{}, repo)
+	require.Equal(t, "a.jsonl;max-parallel=3", location)
+
+	_, err = RepositoryFromStringWithOptions("jsonl:a.jsonl", map[string]string{"max-parallel": "3"})
+	require.ErrorContains(t, err, "does not support options")
+
+	i := slices.IndexFunc(ListCollectorTypes(), func(ct CollectorType) bool { return ct.Moniker == "jsonl" })
+	require.Empty(t, ListCollectorTypes()[i].Options)
+}
+
+func TestRepositoryFromStringUnchangedLocation(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, LoadDefaultRepositoryTypes())
+
+	repo, err := RepositoryFromString("git:https://example.com/repo.git#atts;ref=v1")
+	require.NoError(t, err)
+	require.Equal(t, "v1", repo.(*git.Collector).Options.Ref)
+
+	for _, init := range []string{
+		"git:https://example.com/repo.git?x=1#atts",
+		"fs:/data/what?",
+		"fs:/data/a;b",
+		"fs:/data/a?b=c",
+		"oci:ghcr.io/owner/image@sha256:" + strings.Repeat("a", 64),
+	} {
+		moniker, location, _ := strings.Cut(init, ":")
+		want, err := defaultRepositoryTypes[moniker].factory(location)
+		require.NoError(t, err, init)
+		repo, err := RepositoryFromString(init)
+		require.NoError(t, err, init)
+		require.Equal(t, want, repo, init)
+	}
+
+	repo, err = RepositoryFromString("oci:localhost:5000/app:v1;insecure=true")
+	require.NoError(t, err)
+	require.True(t, repo.(*oci.Collector).Options.Insecure)
+}
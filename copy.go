@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/sirupsen/logrus"
+)
+
+// CopyOptions control what is copied between repositories.
+type CopyOptions struct {
+	// Subjects and PredicateTypes restrict the copy to attestations about
+	// any of the subjects and of any of the predicate types. When both are
+	// empty, all the attestations in the source are copied.
+	Subjects       []attestation.Subject
+	PredicateTypes []attestation.PredicateType
+
+	// DryRun computes the summary without storing anything.
+	DryRun bool
+
+	Fetch attestation.FetchOptions
+	Store attestation.StoreOptions
+}
+
+// CopyOptionsFunc are functions to define options when copying
+type CopyOptionsFunc func(*CopyOptions)
+
+// WithCopySubjects copies only the attestations about the subjects.
+func WithCopySubjects(subjects ...attestation.Subject) CopyOptionsFunc {
+	return func(opts *CopyOptions) {
+		opts.Subjects = append(opts.Subjects, subjects...)
+	}
+}
+
+// WithCopyPredicateTypes copies only the attestations of the predicate types.
+func WithCopyPredicateTypes(pt ...attestation.PredicateType) CopyOptionsFunc {
+	return func(opts *CopyOptions) {
+		opts.PredicateTypes = append(opts.PredicateTypes, pt...)
+	}
+}
+
+// WithDryRun makes the copy report what would be stored without writing
+// to the destination.
+func WithDryRun(dryRun bool) CopyOptionsFunc {
+	return func(opts *CopyOptions) {
+		opts.DryRun = dryRun
+	}
+}
+
+// CopySummary is the outcome of a copy operation.
+type CopySummary struct {
+	// DryRun is true if the envelopes were not stored. In a dry run, Copied
+	// lists the envelopes that would be stored.
+	DryRun bool
+
+	// Copied are the envelopes stored in the destination.
+	Copied []attestation.Envelope
+
+	// Skipped are the envelopes that were already in the destination or
+	// were duplicated in the source.
+	Skipped []attestation.Envelope
+
+	// Failed are the envelopes that could not be stored. The envelopes
+	// are stored in a single call to the destination, if it fails all of
+	// them are listed here although the destination may have written some.
+	Failed []attestation.Envelope
+}
+
+// Copy fetches attestations from a repository and stores them in another.
+// Envelopes already present in the destination are skipped, they are
+// matched by the digest of their signed payload (or statement, if
+// unsigned). The destination is only checked if it implements
+// attestation.Fetcher and supports the fetch method of the copy, otherwise
+// all the envelopes are stored.
+//
+// Copy returns a summary of the copied, skipped and failed envelopes. The
+// returned error is set when the source or destination could not be read
+// or the envelopes could not be stored.
+func Copy(ctx context.Context, from attestation.Fetcher, to attestation.Storer, optFn ...CopyOptionsFunc) (*CopySummary, error) {
+	if from == nil || to == nil {
+		return nil, errors.New("copy needs a source and a destination repository")
+	}
+	opts := &CopyOptions{
+		Fetch: attestation.FetchOptions{MaxReadSize: DefaultMaxReadSize},
+	}
+	for _, f := range optFn {
+		f(opts)
+	}
+
+	envs, err := copyFetchFunc(opts)(ctx, from, opts.Fetch)
+	if err != nil {
+		return nil, fmt.Errorf("fetching attestations from source: %w", err)
+	}
+	return copyEnvelopes(ctx, envs, to, opts)
+}
+
+// Sync copies the attestations returned by the agent repositories to a
+// storer. It works like Copy but the envelopes are fetched with the agent,
+// so its cache, deduplication and verification settings apply.
+func (agent *Agent) Sync(ctx context.Context, to attestation.Storer, optFn ...CopyOptionsFunc) (*CopySummary, error) {
+	if to == nil {
+		return nil, errors.New("sync needs a destination repository")
+	}
+	opts := &CopyOptions{
+		Fetch: agent.fetchOptions(),
+		Store: agent.Options.Store,
+	}
+	for _, f := range optFn {
+		f(opts)
+	}
+
+	// Pass the copy fetch options to the agent
+	setOpts := func(o *attestation.FetchOptions) { *o = opts.Fetch }
+
+	var envs []attestation.Envelope
+	var err error
+	switch {
+	case len(opts.Subjects) > 0 && len(opts.PredicateTypes) > 0:
+		envs, err = agent.FetchAttestationsBySubjectAndPredicateType(ctx, opts.Subjects, opts.PredicateTypes, setOpts)
+	case len(opts.Subjects) > 0:
+		envs, err = agent.FetchAttestationsBySubject(ctx, opts.Subjects, setOpts)
+	case len(opts.PredicateTypes) > 0:
+		envs, err = agent.FetchAttestationsByPredicateType(ctx, opts.PredicateTypes, setOpts)
+	default:
+		envs, err = agent.Fetch(ctx, setOpts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching attestations: %w", err)
+	}
+	return copyEnvelopes(ctx, envs, to, opts)
+}
+
+// copyFetchFunc returns the repoFetchFunc matching the filters in the copy
+// options.
+func copyFetchFunc(opts *CopyOptions) repoFetchFunc {
+	switch {
+	case len(opts.Subjects) > 0 && len(opts.PredicateTypes) > 0:
+		return subjectAndPredicateTypeFetchFunc(opts.Subjects, opts.PredicateTypes)
+	case len(opts.Subjects) > 0:
+		return subjectFetchFunc(opts.Subjects)
+	case len(opts.PredicateTypes) > 0:
+		return predicateTypeFetchFunc(opts.PredicateTypes)
+	default:
+		return fetchAll
+	}
+}
+
+// copyEnvelopes stores in the destination the envelopes it does not have.
+func copyEnvelopes(ctx context.Context, envs []attestation.Envelope, to attestation.Storer, opts *CopyOptions) (*CopySummary, error) {
+	summary := &CopySummary{
+		DryRun:  opts.DryRun,
+		Copied:  []attestation.Envelope{},
+		Skipped: []attestation.Envelope{},
+		Failed:  []attestation.Envelope{},
+	}
+
+	// Index the envelopes already in the destination
+	seen := map[string]struct{}{}
+	if f, ok := to.(attestation.Fetcher); ok {
+		fopts := opts.Fetch
+		fopts.Limit = 0
+		fopts.Query = nil
+		existing, err := copyFetchFunc(opts)(ctx, f, fopts)
+		switch {
+		case errors.Is(err, attestation.ErrFetcherMethodNotImplemented):
+			// The destination cannot list its attestations (eg github)
+			logrus.Debugf("destination cannot be indexed, copying all the envelopes: %v", err)
+		case err != nil:
+			return nil, fmt.Errorf("reading attestations from destination: %w", err)
+		}
+		for _, env := range existing {
+			if key := envelopeKey(env); key != "" {
+				seen[key] = struct{}{}
+			}
+		}
+	}
+
+	pending := []attestation.Envelope{}
+	for _, env := range envs {
+		key := envelopeKey(env)
+		if key != "" {
+			if _, ok := seen[key]; ok {
+				summary.Skipped = append(summary.Skipped, env)
+				continue
+			}
+			seen[key] = struct{}{}
+		}
+		pending = append(pending, env)
+	}
+
+	if opts.DryRun || len(pending) == 0 {
+		summary.Copied = pending
+		return summary, nil
+	}
+
+	if err := to.Store(ctx, opts.Store, pending); err != nil {
+		summary.Failed = pending
+		return summary, fmt.Errorf("storing attestations: %w", err)
+	}
+	summary.Copied = pending
+	return summary, nil
+}
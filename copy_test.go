@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepo is a fetcher and storer keeping the envelopes in memory
+type memoryRepo struct {
+	mtx  sync.Mutex
+	envs []attestation.Envelope
+	fail bool
+	// unlisted makes Fetch unsupported, as in the github driver
+	unlisted bool
+}
+
+func (mr *memoryRepo) Fetch(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+	if mr.unlisted {
+		return nil, attestation.ErrFetcherMethodNotImplemented
+	}
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	return append([]attestation.Envelope{}, mr.envs...), nil
+}
+
+func (mr *memoryRepo) Store(_ context.Context, _ attestation.StoreOptions, envs []attestation.Envelope) error {
+	if mr.fail {
+		return errors.New("synth error")
+	}
+	mr.mtx.Lock()
+	defer mr.mtx.Unlock()
+	mr.envs = append(mr.envs, envs...)
+	return nil
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	other := `{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"test","digest":{"sha256":"8b1a9953c4611296a827abf8c47804d7e6c49c6b0b1e1d5e8b0e0ea0eb7bd9a6"}}],"predicateType":"https://example.com/other/v1","predicate":{}}`
+
+	for _, tc := range []struct {
+		name    string
+		opts    []CopyOptionsFunc
+		dest    *memoryRepo
+		copied  int
+		skipped int
+		failed  int
+		// total is the number of envelopes in the destination afterwards
+		total   int
+		mustErr bool
+	}{
+		{"empty-destination", nil, &memoryRepo{}, 2, 1, 0, 2, false},
+		{"skip-existing", nil, &memoryRepo{envs: []attestation.Envelope{testDSSE(testStatement)}}, 1, 2, 0, 2, false},
+		{"dry-run", []CopyOptionsFunc{WithDryRun(true)}, &memoryRepo{}, 2, 1, 0, 0, false},
+		{"predicate-type", []CopyOptionsFunc{WithCopyPredicateTypes("https://example.com/other/v1")}, &memoryRepo{}, 1, 0, 0, 1, false},
+		{"store-fails", nil, &memoryRepo{fail: true}, 0, 1, 2, 0, true},
+		{"unlisted-destination", nil, &memoryRepo{unlisted: true}, 2, 1, 0, 2, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			// The source has a duplicate copy of testStatement
+			src := &memoryRepo{envs: []attestation.Envelope{
+				testDSSE(testStatement), testDSSE(other), testDSSE(testStatement),
+			}}
+			summary, err := Copy(t.Context(), src, tc.dest, tc.opts...)
+			if tc.mustErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Len(t, summary.Copied, tc.copied)
+			require.Len(t, summary.Skipped, tc.skipped)
+			require.Len(t, summary.Failed, tc.failed)
+			require.Len(t, tc.dest.envs, tc.total)
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+	src := &memoryRepo{envs: []attestation.Envelope{testDSSE(testStatement)}}
+	dest := &memoryRepo{}
+	agent, err := New(WithRepository(src))
+	require.NoError(t, err)
+
+	summary, err := agent.Sync(t.Context(), dest)
+	require.NoError(t, err)
+	require.Len(t, summary.Copied, 1)
+	require.Len(t, dest.envs, 1)
+
+	// A second sync finds the envelope in the destination
+	summary, err = agent.Sync(t.Context(), dest)
+	require.NoError(t, err)
+	require.Empty(t, summary.Copied)
+	require.Len(t, summary.Skipped, 1)
+}
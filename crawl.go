@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/carabiner-dev/attestation"
+
+	"github.com/carabiner-dev/collector/predicate/slsa"
+)
+
+// DefaultMaxCrawlNodes is the default maximum number of subjects visited
+// when crawling the supply chain graph.
+const DefaultMaxCrawlNodes = 1000
+
+// CrawlGraph is the supply chain graph built by Agent.Crawl. Its nodes are
+// the subjects visited and its edges link the subjects of a SLSA provenance
+// attestation to the materials and resolved dependencies it lists.
+type CrawlGraph struct {
+	// Nodes are the subjects visited, in discovery order. The subjects
+	// passed to Crawl come first.
+	Nodes []*CrawlNode
+
+	// Edges link the nodes built from the provenance attestations.
+	Edges []CrawlEdge
+
+	// Truncated is true when the depth or node limit kept the crawler from
+	// following some dependencies.
+	Truncated bool
+}
+
+// CrawlNode is a subject in the crawl graph.
+type CrawlNode struct {
+	// Subject is the subject as first seen by the crawler.
+	Subject attestation.Subject
+
+	// Depth is the distance from the subjects passed to Crawl.
+	Depth int
+
+	// Envelopes are the attestations found about the subject.
+	Envelopes []attestation.Envelope
+}
+
+// CrawlEdge records that the subject in the From node was built from the
+// subject in the To node. The indexes point to CrawlGraph.Nodes.
+type CrawlEdge struct {
+	From int
+	To   int
+
+	// Envelope is the provenance attestation declaring the dependency.
+	Envelope attestation.Envelope
+}
+
+// Envelopes returns all the envelopes in the graph without duplicates, in
+// node order.
+func (g *CrawlGraph) Envelopes() []attestation.Envelope {
+	ret := []attestation.Envelope{}
+	for _, n := range g.Nodes {
+		for _, env := range n.Envelopes {
+			if !slices.Contains(ret, env) {
+				ret = append(ret, env)
+			}
+		}
+	}
+	return ret
+}
+
+// Crawl fetches the attestations of the subjects and follows the materials
+// and resolved dependencies listed in their SLSA provenance attestations
+// (v0.2, v1.0 and v1.1), fetching the attestations of the dependencies in
+// turn. Dependencies are followed up to depth levels from the subjects and
+// until the graph has Options.MaxCrawlNodes nodes. Subjects and
+// dependencies without digests cannot be looked up and are skipped. Subjects
+// sharing any digest are considered the same node, so cycles are only
+// visited once.
+//
+// The fetch options are applied to each lookup.
+func (agent *Agent) Crawl(ctx context.Context, subjects []attestation.Subject, depth int, optFn ...FetchOptionsFunc) (*CrawlGraph, error) {
+	if depth < 0 {
+		return nil, errors.New("crawl depth cannot be negative")
+	}
+
+	c := &crawler{
+		agent:    agent,
+		graph:    &CrawlGraph{Nodes: []*CrawlNode{}, Edges: []CrawlEdge{}},
+		digests:  map[string]int{},
+		maxNodes: agent.Options.MaxCrawlNodes,
+	}
+
+	level := []int{}
+	for _, s := range subjects {
+		if len(s.GetDigest()) == 0 {
+			continue
+		}
+		if i, ok := c.addNode(s, 0); ok && !slices.Contains(level, i) {
+			level = append(level, i)
+		}
+	}
+
+	for len(level) > 0 {
+		next, err := c.visit(ctx, level, depth, optFn)
+		if err != nil {
+			return nil, err
+		}
+		level = next
+	}
+	return c.graph, nil
+}
+
+// crawler holds the state of a crawl
+type crawler struct {
+	agent *Agent
+	graph *CrawlGraph
+
+	// digests indexes the nodes by "algorithm:value" strings
+	digests  map[string]int
+	maxNodes int
+}
+
+// visit fetches the attestations of a level of nodes and returns the new
+// nodes found in their provenance attestations.
+func (c *crawler) visit(ctx context.Context, level []int, maxDepth int, optFn []FetchOptionsFunc) ([]int, error) {
+	subjects := make([]attestation.Subject, 0, len(level))
+	for _, i := range level {
+		subjects = append(subjects, c.graph.Nodes[i].Subject)
+	}
+
+	atts, err := c.agent.FetchAttestationsBySubject(ctx, subjects, optFn...)
+	if err != nil {
+		return nil, fmt.Errorf("fetching attestations for crawl level %d: %w", c.graph.Nodes[level[0]].Depth, err)
+	}
+
+	next := []int{}
+	for _, env := range atts {
+		if env.GetStatement() == nil {
+			continue
+		}
+
+		// Attach the envelope to the nodes of this level it describes
+		from := []int{}
+		for _, s := range env.GetStatement().GetSubjects() {
+			i, ok := c.lookup(s)
+			if !ok || !slices.Contains(level, i) || slices.Contains(from, i) {
+				continue
+			}
+			from = append(from, i)
+			c.graph.Nodes[i].Envelopes = append(c.graph.Nodes[i].Envelopes, env)
+		}
+
+		for _, dep := range slsa.Dependencies(env.GetPredicate()) {
+			if len(dep.GetDigest()) == 0 {
+				continue
+			}
+			for _, f := range from {
+				to, isNew, ok := c.dependencyNode(dep, c.graph.Nodes[f].Depth+1, maxDepth)
+				if !ok {
+					continue
+				}
+				c.graph.Edges = append(c.graph.Edges, CrawlEdge{From: f, To: to, Envelope: env})
+				if isNew {
+					next = append(next, to)
+				}
+			}
+		}
+	}
+	return next, nil
+}
+
+// dependencyNode returns the node of a dependency, creating it if needed.
+// The first boolean is true when the node is new, the second is false if
+// the dependency was not added because of the crawl limits.
+func (c *crawler) dependencyNode(dep attestation.Subject, depth, maxDepth int) (int, bool, bool) {
+	if i, ok := c.lookup(dep); ok {
+		return i, false, true
+	}
+	if depth > maxDepth {
+		c.graph.Truncated = true
+		return 0, false, false
+	}
+	i, ok := c.addNode(dep, depth)
+	return i, ok, ok
+}
+
+// addNode adds a subject to the graph. If a node with any of its digests
+// exists, its index is returned instead.
+func (c *crawler) addNode(s attestation.Subject, depth int) (int, bool) {
+	if i, ok := c.lookup(s); ok {
+		return i, true
+	}
+	if c.maxNodes > 0 && len(c.graph.Nodes) >= c.maxNodes {
+		c.graph.Truncated = true
+		return 0, false
+	}
+
+	c.graph.Nodes = append(c.graph.Nodes, &CrawlNode{
+		Subject: s, Depth: depth, Envelopes: []attestation.Envelope{},
+	})
+	i := len(c.graph.Nodes) - 1
+	for algo, val := range s.GetDigest() {
+		c.digests[algo+":"+val] = i
+	}
+	return i, true
+}
+
+// lookup returns the node that shares a digest with the subject.
+func (c *crawler) lookup(s attestation.Subject) (int, bool) {
+	for algo, val := range s.GetDigest() {
+		if i, ok := c.digests[algo+":"+val]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
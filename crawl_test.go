@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	intoto "github.com/in-toto/attestation/go/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// testProvenance returns an envelope with SLSA v1 provenance stating that
+// the subject digest was built from the dependency digests.
+func testProvenance(subject string, deps ...string) attestation.Envelope {
+	rds := ""
+	for i, d := range deps {
+		if i > 0 {
+			rds += ","
+		}
+		rds += fmt.Sprintf(`{"name":%q,"digest":{"sha256":%q}}`, d, d)
+	}
+	return testDSSE(fmt.Sprintf(
+		`{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":%q,"digest":{"sha256":%q}}],`+
+			`"predicateType":"https://slsa.dev/provenance/v1","predicate":{"buildDefinition":`+
+			`{"buildType":"https://example.com/build/v1","resolvedDependencies":[%s]}}}`,
+		subject, subject, rds,
+	))
+}
+
+// crawlFetcher returns the envelopes whose subjects match the requested
+// digests.
+func crawlFetcher(envs ...attestation.Envelope) *fakeFetcher {
+	return &fakeFetcher{
+		fetchBySubjectFunc: func(_ context.Context, _ attestation.FetchOptions, subjects []attestation.Subject) ([]attestation.Envelope, error) {
+			return subjectQuery(subjects).Run(envs), nil
+		},
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	t.Parallel()
+	envs := []attestation.Envelope{
+		testProvenance("app", "lib", "src"),
+		testProvenance("lib", "base", "app"), // cycle back to app
+		testProvenance("base", "toolchain"),
+	}
+	app := &intoto.ResourceDescriptor{Digest: map[string]string{"sha256": "app"}}
+
+	for _, tc := range []struct {
+		name      string
+		depth     int
+		maxNodes  int
+		nodes     []string
+		edges     int
+		truncated bool
+	}{
+		{"depth-0", 0, 0, []string{"app"}, 0, true},
+		{"depth-1", 1, 0, []string{"app", "lib", "src"}, 3, true},
+		{"full", 5, 0, []string{"app", "lib", "src", "base", "toolchain"}, 5, false},
+		{"node-limit", 5, 3, []string{"app", "lib", "src"}, 3, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			agent, err := New(WithMaxCrawlNodes(tc.maxNodes))
+			require.NoError(t, err)
+			require.NoError(t, agent.AddRepository(crawlFetcher(envs...)))
+
+			graph, err := agent.Crawl(t.Context(), []attestation.Subject{app}, tc.depth)
+			require.NoError(t, err)
+
+			names := []string{}
+			for _, n := range graph.Nodes {
+				names = append(names, n.Subject.GetDigest()["sha256"])
+			}
+			require.Equal(t, tc.nodes, names)
+			require.Len(t, graph.Edges, tc.edges)
+			require.Equal(t, tc.truncated, graph.Truncated)
+			require.Len(t, graph.Nodes[0].Envelopes, 1)
+		})
+	}
+
+	agent, err := New()
+	require.NoError(t, err)
+	_, err = agent.Crawl(t.Context(), []attestation.Subject{app}, -1)
+	require.Error(t, err)
+}
+
+func TestCrawlSkipsSubjectsWithoutDigests(t *testing.T) {
+	t.Parallel()
+	agent, err := New()
+	require.NoError(t, err)
+	queried := []attestation.Subject{}
+	require.NoError(t, agent.AddRepository(&fakeFetcher{
+		fetchBySubjectFunc: func(_ context.Context, _ attestation.FetchOptions, subjects []attestation.Subject) ([]attestation.Envelope, error) {
+			queried = append(queried, subjects...)
+			return []attestation.Envelope{}, nil
+		},
+	}))
+
+	graph, err := agent.Crawl(t.Context(), []attestation.Subject{
+		&intoto.ResourceDescriptor{Name: "nodigest"},
+		&intoto.ResourceDescriptor{Digest: map[string]string{"sha256": "app"}},
+	}, 1)
+	require.NoError(t, err)
+	require.Len(t, graph.Nodes, 1)
+	require.Equal(t, "app", graph.Nodes[0].Subject.GetDigest()["sha256"])
+	require.Len(t, queried, 1)
+}
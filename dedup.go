@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/sirupsen/logrus"
+
+	"github.com/carabiner-dev/collector/envelope/bundle"
+	"github.com/carabiner-dev/collector/envelope/dsse"
+)
+
+// deduplicate collapses envelopes carrying the same signed payload or
+// statement. Of each set of duplicates, the best verified copy is kept (see
+// envelopeRank) in the position of the first one and the sources of the
+// rest are merged into it.
+//
+// The envelopes may be shared with the cache or other callers, so the
+// sources are merged into a copy of the one kept. The envelopes passed are
+// never modified.
+func deduplicate(envs []attestation.Envelope) []attestation.Envelope {
+	ret := []attestation.Envelope{}
+	index := map[string]int{}
+	// copied records the positions holding copies made by the function
+	copied := map[int]bool{}
+	for _, env := range envs {
+		key := envelopeKey(env)
+		if key == "" {
+			ret = append(ret, env)
+			continue
+		}
+
+		i, seen := index[key]
+		if !seen {
+			index[key] = len(ret)
+			ret = append(ret, env)
+			continue
+		}
+
+		if ret[i] == env {
+			continue
+		}
+
+		keep, drop := ret[i], env
+		if envelopeRank(env) > envelopeRank(ret[i]) {
+			keep, drop = env, ret[i]
+			copied[i] = false
+		}
+		if !copied[i] {
+			c, err := cloneEnvelope(keep)
+			if err != nil {
+				logrus.Debugf("not merging the sources of duplicate envelope: %v", err)
+				ret[i] = keep
+				continue
+			}
+			keep = c
+			copied[i] = true
+		}
+		addSources(keep, Sources(drop)...)
+		ret[i] = keep
+	}
+	return ret
+}
+
+// envelopeKey returns a string identifying the content of an envelope. For
+// signed envelopes it is the digest of the signed payload, otherwise the
+// digest of the statement. JSON data is normalized before hashing so that
+// the same statement produces the same key regardless of the formatting.
+// An empty string is returned when the envelope has no usable content.
+func envelopeKey(env attestation.Envelope) string {
+	var data []byte
+	switch e := env.(type) {
+	case *bundle.Envelope:
+		data = e.GetDsseEnvelope().GetPayload()
+	case *dsse.Envelope:
+		if e.Envelope != nil {
+			data = e.GetPayload()
+		}
+	}
+
+	if len(data) == 0 {
+		if env == nil || env.GetStatement() == nil {
+			return ""
+		}
+		var err error
+		data, err = json.Marshal(env.GetStatement())
+		if err != nil {
+			return ""
+		}
+	}
+
+	// Normalize the JSON to make keys independent of the formatting. Go
+	// marshals maps with their keys sorted.
+	var v any
+	if err := json.Unmarshal(data, &v); err == nil {
+		if normalized, err := json.Marshal(v); err == nil {
+			data = normalized
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// envelopeRank scores the trust of an envelope copy. Verified envelopes rank
+// above unverified ones. Then, sigstore bundles with a transparency log entry
+// rank highest followed by other bundles, signed DSSE envelopes and, last,
+// unsigned envelopes.
+func envelopeRank(env attestation.Envelope) int {
+	rank := 0
+	if v := env.GetVerification(); v != nil && v.GetVerified() {
+		rank += 10
+	}
+
+	switch e := env.(type) {
+	case *bundle.Envelope:
+		rank += 3
+		if len(e.GetVerificationMaterial().GetTlogEntries()) > 0 {
+			rank += 2
+		}
+	default:
+		if len(env.GetSignatures()) > 0 {
+			rank++
+		}
+	}
+	return rank
+}
+
+// streamDeduplicator drops the envelopes of a stream that duplicate an
+// envelope already yielded. A yielded envelope cannot be replaced, it only
+// lists the sources of the copies found in its own batch.
+type streamDeduplicator struct {
+	seen map[string]struct{}
+}
+
+func newStreamDeduplicator() *streamDeduplicator {
+	return &streamDeduplicator{
+		seen: map[string]struct{}{},
+	}
+}
+
+// filter returns the envelopes of a batch that were not yielded before
+// and records them as yielded. Duplicates within the batch are expected to
+// be collapsed already, see deduplicate. Copies of envelopes yielded before
+// are dropped without merging their sources, as the consumer owns the
+// yielded envelopes.
+func (d *streamDeduplicator) filter(envs []attestation.Envelope) []attestation.Envelope {
+	ret := make([]attestation.Envelope, 0, len(envs))
+	for _, env := range envs {
+		key := envelopeKey(env)
+		if key == "" {
+			ret = append(ret, env)
+			continue
+		}
+		if _, ok := d.seen[key]; ok {
+			continue
+		}
+		d.seen[key] = struct{}{}
+		ret = append(ret, env)
+	}
+	return ret
+}
@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	sigstore "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	sigstoreProtoDSSE "github.com/sigstore/protobuf-specs/gen/pb-go/dsse"
+	protorekor "github.com/sigstore/protobuf-specs/gen/pb-go/rekor/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/bundle"
+	"github.com/carabiner-dev/collector/envelope/dsse"
+)
+
+func testDSSE(payload string) *dsse.Envelope {
+	return &dsse.Envelope{
+		Envelope: &sigstoreProtoDSSE.Envelope{
+			Payload:     []byte(payload),
+			PayloadType: "application/vnd.in-toto+json",
+			Signatures:  []*sigstoreProtoDSSE.Signature{{Sig: []byte("sig")}},
+		},
+		Signatures: []attestation.Signature{&dsse.Signature{Signature: []byte("sig")}},
+	}
+}
+
+func testBundle(payload string, tlog bool) *bundle.Envelope {
+	b := &bundle.Envelope{
+		Bundle: sigstore.Bundle{
+			VerificationMaterial: &sigstore.VerificationMaterial{},
+			Content: &sigstore.Bundle_DsseEnvelope{
+				DsseEnvelope: &sigstoreProtoDSSE.Envelope{
+					Payload:     []byte(payload),
+					PayloadType: "application/vnd.in-toto+json",
+				},
+			},
+		},
+	}
+	if tlog {
+		b.VerificationMaterial.TlogEntries = []*protorekor.TransparencyLogEntry{{LogIndex: 1}}
+	}
+	return b
+}
+
+func TestDeduplicate(t *testing.T) {
+	t.Parallel()
+	// Same statement, different formatting
+	other := `{"predicateType":"https://example.com/test/v1", "_type":"https://in-toto.io/Statement/v1","subject":[{"name":"test","digest":{"sha256":"8b1a9953c4611296a827abf8c47804d7e6c49c6b0b1e1d5e8b0e0ea0eb7bd9a6"}}],"predicate":{"test":true}}`
+	different := `{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"test","digest":{"sha256":"8b1a9953c4611296a827abf8c47804d7e6c49c6b0b1e1d5e8b0e0ea0eb7bd9a6"}}],"predicateType":"https://example.com/test/v1","predicate":{"test":false}}`
+
+	for _, tc := range []struct {
+		name   string
+		envs   func() []attestation.Envelope
+		expect int
+		// keep is the index of the envelope expected first in the results
+		keep int
+	}{
+		{
+			name: "no-dupes",
+			envs: func() []attestation.Envelope {
+				return []attestation.Envelope{testDSSE(testStatement), testDSSE(different)}
+			},
+			expect: 2,
+		},
+		{
+			name: "dsse-dupes-keep-first",
+			envs: func() []attestation.Envelope {
+				return []attestation.Envelope{testDSSE(testStatement), testDSSE(other)}
+			},
+			expect: 1,
+			keep:   0,
+		},
+		{
+			name: "bundle-over-dsse",
+			envs: func() []attestation.Envelope {
+				return []attestation.Envelope{testDSSE(testStatement), testBundle(testStatement, false)}
+			},
+			expect: 1,
+			keep:   1,
+		},
+		{
+			name: "tlog-bundle-over-bundle",
+			envs: func() []attestation.Envelope {
+				return []attestation.Envelope{testBundle(testStatement, false), testBundle(testStatement, true), testDSSE(different)}
+			},
+			expect: 2,
+			keep:   1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			envs := tc.envs()
+			for i, env := range envs {
+				addSources(env, Source{Moniker: "test", Init: "test:" + string(rune('a'+i))})
+			}
+			res := deduplicate(envs)
+			require.Len(t, res, tc.expect)
+			require.IsType(t, envs[tc.keep], res[0])
+			require.Equal(t, envelopeRank(envs[tc.keep]), envelopeRank(res[0]))
+			if tc.expect < len(envs) {
+				// The sources of the dropped copies are merged into a copy
+				require.NotSame(t, envs[tc.keep], res[0])
+				require.Len(t, Sources(res[0]), 1+len(envs)-tc.expect)
+			} else {
+				require.Same(t, envs[tc.keep], res[0])
+			}
+			for _, env := range envs {
+				require.Len(t, Sources(env), 1)
+			}
+		})
+	}
+}
+
+func TestFetchWithDeduplication(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name   string
+		dedup  bool
+		expect int
+	}{
+		{"dedup", true, 1},
+		{"no-dedup", false, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			agent, err := New(WithDeduplication(tc.dedup))
+			require.NoError(t, err)
+			a := &fakeFetcher{fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+				return []attestation.Envelope{testDSSE(testStatement)}, nil
+			}}
+			b := &fakeFetcher{fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+				return []attestation.Envelope{testBundle(testStatement, true)}, nil
+			}}
+			require.NoError(t, agent.AddRepository(a, b))
+			agent.setRepoInit(a, "fake:a")
+			agent.setRepoInit(b, "fake:b")
+
+			res, err := agent.FetchAttestationsBySubject(t.Context(), []attestation.Subject{})
+			require.NoError(t, err)
+			require.Len(t, res, tc.expect)
+
+			got := 0
+			for _, err := range agent.FetchAttestationsBySubjectStream(t.Context(), []attestation.Subject{}) {
+				require.NoError(t, err)
+				got++
+			}
+			require.Equal(t, tc.expect, got)
+
+			if tc.dedup {
+				require.IsType(t, &bundle.Envelope{}, res[0])
+				require.Len(t, Sources(res[0]), 2)
+			}
+		})
+	}
+}
+
+func TestDeduplicateSharedResults(t *testing.T) {
+	t.Parallel()
+	agent, err := New(WithDeduplication(true), WithCache(NewMemoryCache()))
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b"} {
+		f := &fakeFetcher{fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+			return []attestation.Envelope{testDSSE(testStatement)}, nil
+		}}
+		require.NoError(t, agent.AddRepository(f))
+		agent.setRepoInit(f, "fake:"+name)
+	}
+
+	// Concurrent callers share the cached envelopes
+	var wg sync.WaitGroup
+	results := make(chan []attestation.Envelope, 32)
+	errs := make(chan error, 32)
+	for range 32 {
+		wg.Go(func() {
+			res, err := agent.FetchAttestationsBySubject(t.Context(), []attestation.Subject{})
+			errs <- err
+			results <- res
+		})
+	}
+	wg.Wait()
+	close(errs)
+	close(results)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	for res := range results {
+		require.Len(t, res, 1)
+		require.Len(t, Sources(res[0]), 2)
+	}
+
+	cached, err := agent.Cache.GetAttestationsBySubject(withCacheScope(t.Context(), agent.cacheScope(agent.fetchOptions())), []attestation.Subject{})
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	require.Len(t, *cached, 2)
+	for _, env := range *cached {
+		require.Len(t, Sources(env), 1)
+	}
+}
+
+func TestFetchStreamDeduplication(t *testing.T) {
+	t.Parallel()
+	agent, err := New(WithDeduplication(true), WithCache(NewMemoryCache()))
+	require.NoError(t, err)
+
+	// The second repository responds after the first envelope is yielded
+	release := make(chan struct{})
+	a := &fakeFetcher{fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+		return []attestation.Envelope{testDSSE(testStatement), testBundle(testStatement, true)}, nil
+	}}
+	b := &fakeFetcher{fetchBySubjectFunc: func(ctx context.Context, _ attestation.FetchOptions, _ []attestation.Subject) ([]attestation.Envelope, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return []attestation.Envelope{testDSSE(testStatement)}, nil
+	}}
+	require.NoError(t, agent.AddRepository(a, b))
+	agent.setRepoInit(a, "fake:a")
+	agent.setRepoInit(b, "fake:b")
+
+	got := []attestation.Envelope{}
+	for env, err := range agent.FetchAttestationsBySubjectStream(t.Context(), []attestation.Subject{}) {
+		require.NoError(t, err)
+		if len(got) == 0 {
+			close(release)
+		}
+		got = append(got, env)
+	}
+
+	// The best copy of the batch is yielded, the later copy is dropped
+	// without changing it
+	require.Len(t, got, 1)
+	require.IsType(t, &bundle.Envelope{}, got[0])
+	require.Equal(t, []Source{{Moniker: "fake", Init: "fake:a"}}, Sources(got[0]))
+
+	// The cache has the raw results
+	ctx := withCacheScope(t.Context(), agent.cacheScope(agent.fetchOptions()))
+	cached, err := agent.Cache.GetAttestationsBySubject(ctx, []attestation.Subject{})
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	require.Len(t, *cached, 3)
+	for _, env := range *cached {
+		require.Len(t, Sources(env), 1)
+	}
+
+	// Streams served from the cache keep the best copy too
+	got = got[:0]
+	for env, err := range agent.FetchAttestationsBySubjectStream(t.Context(), []attestation.Subject{}) {
+		require.NoError(t, err)
+		got = append(got, env)
+	}
+	require.Len(t, got, 1)
+	require.IsType(t, &bundle.Envelope{}, got[0])
+	require.Len(t, Sources(got[0]), 2)
+}
@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/nozzle/throttler"
+
+	"github.com/carabiner-dev/collector/repository"
+)
+
+// RedactedValue replaces the value of secret options in repository
+// descriptions.
+const RedactedValue = "[REDACTED]"
+
+// maxDescribeDepth limits how deep nested option values are described.
+const maxDescribeDepth = 4
+
+// RepositoryDescription describes a repository configured in the agent.
+type RepositoryDescription struct {
+	// Moniker is the repository type moniker.
+	Moniker string `json:"moniker"`
+
+	// Init is the init string used to build the repository, if any.
+	Init string `json:"init,omitempty"`
+
+	// Interfaces lists the attestation and repository interfaces
+	// implemented by the repository.
+	Interfaces []string `json:"interfaces"`
+
+	// Options has the effective options of the repository driver. Secrets
+	// (tokens, passwords, URL credentials) are redacted.
+	Options map[string]any `json:"options,omitempty"`
+}
+
+// repositoryInterfaces are the interfaces checked when describing a repository
+var repositoryInterfaces = []struct {
+	name string
+	is   func(any) bool
+}{
+	{"Fetcher", func(r any) bool { _, ok := r.(attestation.Fetcher); return ok }},
+	{"FetcherBySubject", func(r any) bool { _, ok := r.(attestation.FetcherBySubject); return ok }},
+	{"FetcherByPredicateType", func(r any) bool { _, ok := r.(attestation.FetcherByPredicateType); return ok }},
+	{"FetcherByPredicateTypeAndSubject", func(r any) bool { _, ok := r.(attestation.FetcherByPredicateTypeAndSubject); return ok }},
+	{"Storer", func(r any) bool { _, ok := r.(attestation.Storer); return ok }},
+	{"SignatureVerifier", func(r any) bool { _, ok := r.(repository.SignatureVerifier); return ok }},
+	{"Prober", func(r any) bool { _, ok := r.(repository.Prober); return ok }},
+	{"OfflineAware", func(r any) bool { _, ok := r.(repository.OfflineAware); return ok }},
+}
+
+// Describe returns a description of each repository configured in the
+// agent, in registration order.
+func (agent *Agent) Describe() []RepositoryDescription {
+	ret := make([]RepositoryDescription, 0, len(agent.Repositories))
+	for _, r := range agent.Repositories {
+		src := agent.repoSource(r)
+		desc := RepositoryDescription{
+			Moniker:    src.Moniker,
+			Init:       src.Init,
+			Interfaces: []string{},
+			Options:    describeOptions(r),
+		}
+		for _, iface := range repositoryInterfaces {
+			if iface.is(r) {
+				desc.Interfaces = append(desc.Interfaces, iface.name)
+			}
+		}
+		ret = append(ret, desc)
+	}
+	return ret
+}
+
+// describeOptions returns the exported options of a repository driver. When
+// the driver has an Options struct, it is used, otherwise the exported
+// fields of the driver itself are described.
+func describeOptions(r any) map[string]any {
+	v := reflect.ValueOf(r)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if f, ok := v.Type().FieldByName("Options"); ok && f.IsExported() && f.Type.Kind() == reflect.Struct {
+		v = v.FieldByIndex(f.Index)
+	}
+	return describeStruct(v, 0)
+}
+
+// describeStruct converts the exported fields of a struct to a map,
+// redacting secrets.
+func describeStruct(v reflect.Value, depth int) map[string]any {
+	ret := map[string]any{}
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		val, ok := describeValue(v.Field(i), depth+1)
+		if !ok {
+			continue
+		}
+		if isSecretName(field.Name) && !v.Field(i).IsZero() {
+			val = RedactedValue
+		}
+		ret[field.Name] = val
+	}
+	return ret
+}
+
+// describeValue returns a printable representation of an option value. The
+// boolean is false when the value cannot be described (funcs, channels).
+func describeValue(v reflect.Value, depth int) (any, bool) {
+	if depth > maxDescribeDepth {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return redactURL(v.String()), true
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Interface(), true
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, true
+		}
+		return describeValue(v.Elem(), depth)
+	case reflect.Interface:
+		// Interfaces can hold anything (clients, credentials), only
+		// their type is reported.
+		if v.IsNil() {
+			return nil, true
+		}
+		return fmt.Sprintf("%T", v.Interface()), true
+	case reflect.Slice, reflect.Array:
+		ret := []any{}
+		for i := range v.Len() {
+			if val, ok := describeValue(v.Index(i), depth+1); ok {
+				ret = append(ret, val)
+			}
+		}
+		return ret, true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		ret := map[string]any{}
+		iter := v.MapRange()
+		for iter.Next() {
+			if val, ok := describeValue(iter.Value(), depth+1); ok {
+				if isSecretName(iter.Key().String()) {
+					val = RedactedValue
+				}
+				ret[iter.Key().String()] = val
+			}
+		}
+		return ret, true
+	case reflect.Struct:
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return redactURL(s.String()), true
+		}
+		return describeStruct(v, depth), true
+	default:
+		return nil, false
+	}
+}
+
+// isSecretName returns true if an option name looks like it holds a secret.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"token", "password", "secret", "credential", "apikey"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactInit removes the password from the location of an init string.
+// The type moniker is split off first, otherwise the location would be
+// parsed as the opaque part of a URL and its credentials kept.
+func redactInit(init string) string {
+	moniker, location, ok := strings.Cut(init, ":")
+	if !ok {
+		return redactURL(init)
+	}
+	return moniker + ":" + redactURL(location)
+}
+
+// redactURL removes the password from strings holding URLs with
+// credentials.
+func redactURL(s string) string {
+	if !strings.Contains(s, "@") || !strings.Contains(s, "//") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	return u.Redacted()
+}
+
+// ProbeResult is the outcome of probing a repository.
+type ProbeResult struct {
+	// Repository identifies the repository by its init string or type moniker.
+	Repository string
+
+	// Probed is false when the repository does not implement the
+	// repository.Prober interface and was not checked.
+	Probed bool
+
+	// Latency is the time the probe took.
+	Latency time.Duration
+
+	// Error is the error returned by the probe, if any.
+	Error error
+}
+
+// Probe checks the health of the agent repositories implementing the
+// repository.Prober interface. It returns the result of each repository, in
+// registration order, and an error listing the failed probes.
+func (agent *Agent) Probe(ctx context.Context) ([]ProbeResult, error) {
+	results := make([]ProbeResult, len(agent.Repositories))
+	t := throttler.New(workers(agent.Options.ParallelFetches), len(agent.Repositories))
+	for i, r := range agent.Repositories {
+		go func(i int, r attestation.Repository) {
+			results[i] = ProbeResult{Repository: agent.repoName(r)}
+			if p, ok := r.(repository.Prober); ok {
+				start := time.Now()
+				results[i].Probed = true
+				results[i].Error = agent.checkOffline(r)
+				if results[i].Error == nil {
+					results[i].Error = p.Probe(ctx)
+				}
+				results[i].Latency = time.Since(start)
+			}
+			t.Done(nil)
+		}(i, r)
+		t.Throttle()
+	}
+
+	errs := []error{}
+	for _, res := range results {
+		if res.Error != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.Repository, res.Error))
+		}
+	}
+	return results, errors.Join(errs...)
+}
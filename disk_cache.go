@@ -0,0 +1,668 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	intoto "github.com/in-toto/attestation/go/v1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/carabiner-dev/collector/envelope"
+	"github.com/carabiner-dev/collector/envelope/bundle"
+	"github.com/carabiner-dev/collector/envelope/dsse"
+)
+
+// Ensure the disk cache implements the cache interfaces
+var (
+	_ Cache            = (*DiskCache)(nil)
+	_ CacheInvalidator = (*DiskCache)(nil)
+)
+
+// Directories of the disk cache
+const (
+	diskCacheEnvelopes             = "envelopes"
+	diskCacheSubjects              = "subjects"
+	diskCachePredicateTypes        = "predicate-types"
+	diskCacheSubjectPredicateTypes = "subject-predicate-types"
+)
+
+// DiskCache stores the results of the agent queries on disk so they can be
+// reused across processes. Envelopes are stored once, addressed by the
+// digest of their content, and the results of each query are recorded in
+// an index by subject, by predicate type or by both.
+//
+// Files are written to a temporary file and renamed into place, so
+// concurrent processes sharing the directory never read partial data. The
+// TTL in the options is recorded in each index entry when it is written,
+// MaxEntries and MaxBytes are not enforced on disk.
+//
+// Expired index entries and the envelopes no longer referenced by any entry
+// are removed when entries are invalidated and, at most once per TTL period
+// (or hour, without a TTL), when results are stored.
+//
+// Envelopes read from disk are checked against the digest recorded in the
+// index and parsed again. They lose the results of their signature
+// verification, the agent verifies them again when they are served from
+// the cache, whether or not it applies a verification policy.
+type DiskCache struct {
+	Options CacheOptions
+
+	dir string
+	now func() time.Time
+
+	hits, misses atomic.Uint64
+
+	mtx       sync.Mutex
+	lastSweep time.Time
+	// sweepGrace protects recently written envelopes from the sweeps, they
+	// may belong to an entry being written by another process.
+	sweepGrace time.Duration
+}
+
+// Defaults of the disk cache sweeps
+const (
+	diskCacheSweepPeriod = time.Hour
+	diskCacheSweepGrace  = time.Minute
+)
+
+// DefaultDiskCacheDir returns the default directory of the disk cache under
+// the user cache directory ($XDG_CACHE_HOME on Linux).
+func DefaultDiskCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("getting user cache directory: %w", err)
+	}
+	return filepath.Join(base, "carabiner", "collector"), nil
+}
+
+// NewDiskCache returns a cache storing its data in dir. If dir is empty, the
+// default directory is used (see DefaultDiskCacheDir) or, if the user has
+// no cache directory, a directory in the system temporary directory. The
+// directories are created when data is first stored.
+func NewDiskCache(dir string, funcs ...CacheOption) *DiskCache {
+	if dir == "" {
+		var err error
+		dir, err = DefaultDiskCacheDir()
+		if err != nil {
+			logrus.Debugf("using temporary cache directory: %v", err)
+			dir = filepath.Join(os.TempDir(), "carabiner-collector")
+		}
+	}
+	diskcache := &DiskCache{dir: dir, now: time.Now, sweepGrace: diskCacheSweepGrace}
+	for _, f := range funcs {
+		f(&diskcache.Options)
+	}
+	return diskcache
+}
+
+// Dir returns the directory where the cache stores its data.
+func (diskcache *DiskCache) Dir() string {
+	return diskcache.dir
+}
+
+// diskIndexEntry is the record of the results of a query in the index.
+type diskIndexEntry struct {
+	// Query is the kind of query and Scope the scope of the request, see
+	// CacheScope. They are kept to export the entry.
+	Query string `json:"query,omitempty"`
+	Scope string `json:"scope,omitempty"`
+
+	Stored  time.Time `json:"stored"`
+	Expires time.Time `json:"expires,omitzero"`
+
+	// The query subjects and predicate types, kept to support invalidation
+	Subjects       []json.RawMessage           `json:"subjects,omitempty"`
+	PredicateTypes []attestation.PredicateType `json:"predicateTypes,omitempty"`
+
+	Envelopes []diskIndexEnvelope `json:"envelopes"`
+}
+
+// diskIndexEnvelope points to an envelope in the cache. The predicate origin
+// is kept in the index as it carries the sources recorded by the agent,
+// which may differ between queries returning the same envelope.
+type diskIndexEnvelope struct {
+	Digest string          `json:"digest"`
+	Origin json.RawMessage `json:"origin,omitempty"`
+}
+
+func (diskcache *DiskCache) StoreAttestationsByPredicateType(ctx context.Context, pt []attestation.PredicateType, atts *[]attestation.Envelope) error {
+	return diskcache.store(ctx, cacheQueryPredicateType, nil, pt, atts)
+}
+
+func (diskcache *DiskCache) GetAttestationsByPredicateType(ctx context.Context, pt []attestation.PredicateType) (*[]attestation.Envelope, error) {
+	return diskcache.get(ctx, diskcache.queryPath(ctx, cacheQueryPredicateType, nil, pt))
+}
+
+func (diskcache *DiskCache) StoreAttestationsBySubject(ctx context.Context, subjects []attestation.Subject, atts *[]attestation.Envelope) error {
+	return diskcache.store(ctx, cacheQuerySubject, subjects, nil, atts)
+}
+
+func (diskcache *DiskCache) GetAttestationsBySubject(ctx context.Context, subjects []attestation.Subject) (*[]attestation.Envelope, error) {
+	return diskcache.get(ctx, diskcache.queryPath(ctx, cacheQuerySubject, subjects, nil))
+}
+
+func (diskcache *DiskCache) StoreAttestationsBySubjectAndPredicateType(ctx context.Context, subjects []attestation.Subject, pt []attestation.PredicateType, atts *[]attestation.Envelope) error {
+	return diskcache.store(ctx, cacheQuerySubjectPredicateType, subjects, pt, atts)
+}
+
+func (diskcache *DiskCache) GetAttestationsBySubjectAndPredicateType(ctx context.Context, subjects []attestation.Subject, pt []attestation.PredicateType) (*[]attestation.Envelope, error) {
+	return diskcache.get(ctx, diskcache.queryPath(ctx, cacheQuerySubjectPredicateType, subjects, pt))
+}
+
+// diskCacheIndexes maps the query kinds to their index directories
+var diskCacheIndexes = map[string]string{
+	cacheQuerySubject:              diskCacheSubjects,
+	cacheQueryPredicateType:        diskCachePredicateTypes,
+	cacheQuerySubjectPredicateType: diskCacheSubjectPredicateTypes,
+}
+
+// queryPath returns the path of the index entry of a query.
+func (diskcache *DiskCache) queryPath(ctx context.Context, query string, subjects []attestation.Subject, pt []attestation.PredicateType) string {
+	return diskcache.indexPath(diskCacheIndexes[query], scopedKey(ctx, cacheQueryKey(query, subjects, pt)))
+}
+
+// indexPath returns the path of the index entry of a query.
+func (diskcache *DiskCache) indexPath(index, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(diskcache.dir, index, hex.EncodeToString(sum[:])+".json")
+}
+
+// envelopePath returns the path of an envelope from its digest. Envelopes
+// are sharded in directories by the first byte of the digest.
+func (diskcache *DiskCache) envelopePath(digest string) string {
+	return filepath.Join(diskcache.dir, diskCacheEnvelopes, digest[:2], digest+".json")
+}
+
+// store writes the envelopes and the index entry of a query. Empty results
+// are only recorded when negative caching is enabled.
+func (diskcache *DiskCache) store(ctx context.Context, query string, subjects []attestation.Subject, pt []attestation.PredicateType, atts *[]attestation.Envelope) error {
+	stored := diskcache.now().UTC()
+	ttl := diskcache.Options.TTL
+	if atts == nil || len(*atts) == 0 {
+		ttl = diskcache.Options.NegativeTTL
+	}
+	var expires time.Time
+	if ttl > 0 {
+		expires = stored.Add(ttl)
+	}
+	if err := diskcache.write(ctx, query, subjects, pt, atts, stored, expires); err != nil {
+		return err
+	}
+	diskcache.sweep()
+	return nil
+}
+
+// write records the results of a query with their storage and expiry
+// times.
+func (diskcache *DiskCache) write(ctx context.Context, query string, subjects []attestation.Subject, pt []attestation.PredicateType, atts *[]attestation.Envelope, stored, expires time.Time) error {
+	path := diskcache.queryPath(ctx, query, subjects, pt)
+	if (atts == nil || len(*atts) == 0) && diskcache.Options.NegativeTTL <= 0 {
+		return diskcache.remove(path)
+	}
+
+	entry := diskIndexEntry{
+		Query:          query,
+		Scope:          CacheScope(ctx),
+		Stored:         stored,
+		Expires:        expires,
+		PredicateTypes: pt,
+		Envelopes:      []diskIndexEnvelope{},
+	}
+	for _, s := range subjects {
+		data, err := protojson.Marshal(toResourceDescriptor(s))
+		if err != nil {
+			return fmt.Errorf("marshaling subject: %w", err)
+		}
+		entry.Subjects = append(entry.Subjects, data)
+	}
+
+	if atts != nil {
+		for _, env := range *atts {
+			ie, err := diskcache.storeEnvelope(env)
+			if err != nil {
+				return err
+			}
+			entry.Envelopes = append(entry.Envelopes, ie)
+		}
+	}
+
+	data, err := json.Marshal(&entry)
+	if err != nil {
+		return fmt.Errorf("marshaling index entry: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("writing index entry: %w", err)
+	}
+	return nil
+}
+
+// storeEnvelope writes an envelope to the cache unless it is already there
+// and returns its index record.
+func (diskcache *DiskCache) storeEnvelope(env attestation.Envelope) (diskIndexEnvelope, error) {
+	ie := diskIndexEnvelope{}
+	data, err := marshalCachedEnvelope(env)
+	if err != nil {
+		return ie, err
+	}
+	sum := sha256.Sum256(data)
+	ie.Digest = hex.EncodeToString(sum[:])
+
+	if rd := originDescriptor(env); rd != nil {
+		ie.Origin, err = protojson.Marshal(rd)
+		if err != nil {
+			return ie, fmt.Errorf("marshaling predicate origin: %w", err)
+		}
+	}
+
+	// Envelopes already stored are touched to protect them from sweeps
+	// until the index entry is written.
+	path := diskcache.envelopePath(ie.Digest)
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err == nil {
+			return ie, nil
+		}
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return ie, fmt.Errorf("writing envelope: %w", err)
+	}
+	return ie, nil
+}
+
+// get reads the results of a query from the index. Expired, damaged or
+// incomplete entries are removed and reported as a miss.
+func (diskcache *DiskCache) get(ctx context.Context, path string) (*[]attestation.Envelope, error) {
+	ret, err := diskcache.read(path)
+	if ret == nil {
+		diskcache.misses.Add(1)
+	} else {
+		diskcache.hits.Add(1)
+		markPersistentRead(ctx)
+	}
+	return ret, err
+}
+
+func (diskcache *DiskCache) read(path string) (*[]attestation.Envelope, error) {
+	entry, err := readIndexEntry(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		logrus.Debugf("discarding cache entry %s: %v", path, err)
+		return nil, diskcache.remove(path)
+	}
+
+	if diskcache.expired(entry) {
+		return nil, diskcache.remove(path)
+	}
+
+	ret := make([]attestation.Envelope, 0, len(entry.Envelopes))
+	for _, ie := range entry.Envelopes {
+		env, err := diskcache.loadEnvelope(ie)
+		if err != nil {
+			logrus.Debugf("discarding cache entry %s: %v", path, err)
+			return nil, diskcache.remove(path)
+		}
+		ret = append(ret, env)
+	}
+	return &ret, nil
+}
+
+func (diskcache *DiskCache) expired(entry *diskIndexEntry) bool {
+	return !entry.Expires.IsZero() && diskcache.now().After(entry.Expires)
+}
+
+// loadEnvelope reads and parses an envelope from the cache and restores its
+// predicate origin. Envelopes that do not match the digest in the index are
+// rejected.
+func (diskcache *DiskCache) loadEnvelope(ie diskIndexEnvelope) (attestation.Envelope, error) {
+	if len(ie.Digest) < 2 {
+		return nil, fmt.Errorf("invalid envelope digest %q", ie.Digest)
+	}
+	path := diskcache.envelopePath(ie.Digest)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading envelope: %w", err)
+	}
+
+	// Envelopes modified on disk are removed so they are written again
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != ie.Digest {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logrus.Debugf("removing modified envelope %s: %v", path, err)
+		}
+		return nil, fmt.Errorf("envelope %s does not match its digest", ie.Digest)
+	}
+	env, err := parseCachedEnvelope(data, ie.Origin)
+	if err != nil {
+		return nil, fmt.Errorf("envelope %s: %w", ie.Digest, err)
+	}
+	return env, nil
+}
+
+// parseCachedEnvelope parses an envelope serialized by
+// marshalCachedEnvelope and restores its predicate origin.
+func parseCachedEnvelope(data []byte, origin json.RawMessage) (attestation.Envelope, error) {
+	envs, err := envelope.Parsers.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing envelope: %w", err)
+	}
+	if len(envs) != 1 {
+		return nil, fmt.Errorf("data has %d envelopes", len(envs))
+	}
+
+	if len(origin) > 0 && envs[0].GetPredicate() != nil {
+		rd := &intoto.ResourceDescriptor{}
+		if err := protojson.Unmarshal(origin, rd); err != nil {
+			return nil, fmt.Errorf("parsing predicate origin: %w", err)
+		}
+		envs[0].GetPredicate().SetOrigin(rd)
+	}
+	return envs[0], nil
+}
+
+// Invalidate removes the index entries of all queries involving any of the
+// subjects or predicate types and the envelopes no other query references.
+func (diskcache *DiskCache) Invalidate(subjects []attestation.Subject, pt []attestation.PredicateType) error {
+	for _, index := range []string{diskCacheSubjects, diskCachePredicateTypes, diskCacheSubjectPredicateTypes} {
+		paths, err := filepath.Glob(filepath.Join(diskcache.dir, index, "*.json"))
+		if err != nil {
+			return fmt.Errorf("listing index entries: %w", err)
+		}
+		for _, path := range paths {
+			entry, err := readIndexEntry(path)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				if err := diskcache.remove(path); err != nil {
+					return err
+				}
+				continue
+			}
+			if !entry.matches(subjects, pt) {
+				continue
+			}
+			if err := diskcache.remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return diskcache.collect()
+}
+
+// sweep collects the cache data no longer used. To keep stores cheap, it
+// runs at most once per TTL period or, without a TTL, once an hour.
+func (diskcache *DiskCache) sweep() {
+	period := diskcache.Options.TTL
+	if n := diskcache.Options.NegativeTTL; n > 0 && (period <= 0 || n < period) {
+		period = n
+	}
+	if period <= 0 {
+		period = diskCacheSweepPeriod
+	}
+
+	diskcache.mtx.Lock()
+	if !diskcache.lastSweep.IsZero() && diskcache.now().Sub(diskcache.lastSweep) < period {
+		diskcache.mtx.Unlock()
+		return
+	}
+	diskcache.lastSweep = diskcache.now()
+	diskcache.mtx.Unlock()
+
+	if err := diskcache.collect(); err != nil {
+		logrus.Debugf("sweeping disk cache: %v", err)
+	}
+}
+
+// collect removes the expired and damaged index entries and the envelopes
+// not referenced by any entry. Envelopes written in the last sweepGrace
+// are kept.
+func (diskcache *DiskCache) collect() error {
+	referenced := map[string]bool{}
+	for _, index := range []string{diskCacheSubjects, diskCachePredicateTypes, diskCacheSubjectPredicateTypes} {
+		paths, err := filepath.Glob(filepath.Join(diskcache.dir, index, "*.json"))
+		if err != nil {
+			return fmt.Errorf("listing index entries: %w", err)
+		}
+		for _, path := range paths {
+			entry, err := readIndexEntry(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil || diskcache.expired(entry) {
+				if err := diskcache.remove(path); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, ie := range entry.Envelopes {
+				referenced[ie.Digest] = true
+			}
+		}
+	}
+
+	err := filepath.WalkDir(filepath.Join(diskcache.dir, diskCacheEnvelopes), func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if de.IsDir() || filepath.Ext(path) != ".json" || referenced[strings.TrimSuffix(de.Name(), ".json")] {
+			return nil
+		}
+		info, err := de.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if time.Since(info.ModTime()) < diskcache.sweepGrace {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("removing cached envelope: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("collecting cached envelopes: %w", err)
+	}
+	return nil
+}
+
+// Purge removes all the data in the cache.
+func (diskcache *DiskCache) Purge() error {
+	for _, d := range []string{diskCacheEnvelopes, diskCacheSubjects, diskCachePredicateTypes, diskCacheSubjectPredicateTypes} {
+		if err := os.RemoveAll(filepath.Join(diskcache.dir, d)); err != nil {
+			return fmt.Errorf("removing cache data: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stats returns the usage statistics of the cache. The hits and misses are
+// counted by this instance, the entries and size are read from the
+// directory, which may be shared with other processes.
+func (diskcache *DiskCache) Stats() (CacheStats, error) {
+	stats := CacheStats{Hits: diskcache.hits.Load(), Misses: diskcache.misses.Load()}
+	now := diskcache.now()
+	for _, d := range []string{diskCacheEnvelopes, diskCacheSubjects, diskCachePredicateTypes, diskCacheSubjectPredicateTypes} {
+		err := filepath.WalkDir(filepath.Join(diskcache.dir, d), func(path string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if de.IsDir() {
+				return nil
+			}
+			info, err := de.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			stats.Bytes += info.Size()
+			if d == diskCacheEnvelopes || filepath.Ext(path) != ".json" {
+				return nil
+			}
+			entry, err := readIndexEntry(path)
+			if err != nil {
+				// Entries may be removed or damaged while reading them
+				logrus.Debugf("skipping cache entry %s: %v", path, err)
+				return nil
+			}
+			stats.addAge(now, entry.Stored)
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return stats, fmt.Errorf("reading cache directory: %w", err)
+		}
+	}
+	return stats, nil
+}
+
+// remove deletes an index entry. Entries already removed by another
+// process are ignored.
+func (diskcache *DiskCache) remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing cache entry: %w", err)
+	}
+	return nil
+}
+
+// matches returns true if the query of the entry involves any of the
+// subjects or predicate types.
+func (entry *diskIndexEntry) matches(subjects []attestation.Subject, pt []attestation.PredicateType) bool {
+	if slices.ContainsFunc(entry.PredicateTypes, func(t attestation.PredicateType) bool {
+		return slices.Contains(pt, t)
+	}) {
+		return true
+	}
+	for _, data := range entry.Subjects {
+		rd := &intoto.ResourceDescriptor{}
+		if err := protojson.Unmarshal(data, rd); err != nil {
+			continue
+		}
+		if slices.ContainsFunc(subjects, func(s attestation.Subject) bool {
+			return subjectsMatch(rd, s)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+func readIndexEntry(path string) (*diskIndexEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &diskIndexEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("parsing index entry: %w", err)
+	}
+	return entry, nil
+}
+
+// marshalCachedEnvelope serializes an envelope in a format the envelope
+// parsers read back. The JSON is compacted so the same envelope always
+// produces the same data. Envelopes other than DSSE and sigstore bundles
+// are stored as their bare statement.
+func marshalCachedEnvelope(env attestation.Envelope) ([]byte, error) {
+	var data []byte
+	var err error
+	switch e := env.(type) {
+	case *dsse.Envelope:
+		data, err = protojson.Marshal(e.Envelope)
+	case *bundle.Envelope:
+		data, err = json.Marshal(e)
+	default:
+		if env == nil || env.GetStatement() == nil {
+			return nil, errors.New("envelope has no statement")
+		}
+		data, err = marshalStatement(env.GetStatement())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("marshaling envelope: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, fmt.Errorf("compacting envelope JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// marshalStatement serializes a statement as an in-toto statement.
+func marshalStatement(s attestation.Statement) ([]byte, error) {
+	st := struct {
+		Type          string                    `json:"_type"`
+		Subject       []json.RawMessage         `json:"subject"`
+		PredicateType attestation.PredicateType `json:"predicateType"`
+		Predicate     json.RawMessage           `json:"predicate,omitempty"`
+	}{
+		Type:          s.GetType(),
+		Subject:       []json.RawMessage{},
+		PredicateType: s.GetPredicateType(),
+	}
+	if st.Type == "" {
+		st.Type = intoto.StatementTypeUri
+	}
+	for _, sub := range s.GetSubjects() {
+		data, err := protojson.Marshal(toResourceDescriptor(sub))
+		if err != nil {
+			return nil, fmt.Errorf("marshaling subject: %w", err)
+		}
+		st.Subject = append(st.Subject, data)
+	}
+	if p := s.GetPredicate(); p != nil && json.Valid(p.GetData()) {
+		st.Predicate = p.GetData()
+	}
+	return json.Marshal(&st)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(f.Name()) //nolint:errcheck
+
+	if _, err := f.Write(data); err != nil {
+		f.Close() //nolint:errcheck,gosec
+		return fmt.Errorf("writing temporary file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("renaming temporary file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/bare"
+	"github.com/carabiner-dev/collector/envelope/bundle"
+	"github.com/carabiner-dev/collector/envelope/dsse"
+)
+
+func TestDiskCacheRoundTrip(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	src := Source{Moniker: "jsonl", Init: "jsonl:test.jsonl"}
+
+	env := testDSSE(testStatement)
+	addSources(env, src)
+	atts := []attestation.Envelope{
+		env, testBundle(testStatement, true),
+		&bare.Envelope{Statement: testDSSE(testStatement).GetStatement()},
+	}
+
+	require.NoError(t, NewDiskCache(dir).StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &atts))
+	require.NoError(t, NewDiskCache(dir).StoreAttestationsBySubject(t.Context(), cacheSubject("b"), &atts))
+
+	// A new cache on the same directory reads the data
+	res, err := NewDiskCache(dir).GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.Len(t, *res, 3)
+	require.IsType(t, &dsse.Envelope{}, (*res)[0])
+	require.IsType(t, &bundle.Envelope{}, (*res)[1])
+	require.IsType(t, &bare.Envelope{}, (*res)[2])
+	require.Equal(t, []Source{src}, Sources((*res)[0]))
+	require.Equal(t, envelopeKey(env), envelopeKey((*res)[0]))
+
+	// Envelopes are stored once
+	files, err := filepath.Glob(filepath.Join(dir, diskCacheEnvelopes, "*", "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+
+	res, err = NewDiskCache(dir).GetAttestationsByPredicateType(t.Context(), []attestation.PredicateType{"https://example.com/test/v1"})
+	require.NoError(t, err)
+	require.Nil(t, res)
+}
+
+func TestDiskCacheTTL(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Now()}
+	diskcache := NewDiskCache(t.TempDir(), WithCacheTTL(time.Minute))
+	diskcache.now = clock.now
+	pt := []attestation.PredicateType{"https://example.com/test/v1"}
+
+	atts := []attestation.Envelope{testDSSE(testStatement)}
+	require.NoError(t, diskcache.StoreAttestationsByPredicateType(t.Context(), pt, &atts))
+
+	clock.t = clock.t.Add(30 * time.Second)
+	res, err := diskcache.GetAttestationsByPredicateType(t.Context(), pt)
+	require.NoError(t, err)
+	require.Len(t, *res, 1)
+
+	clock.t = clock.t.Add(time.Minute)
+	res, err = diskcache.GetAttestationsByPredicateType(t.Context(), pt)
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.NoFileExists(t, diskcache.queryPath(t.Context(), cacheQueryPredicateType, nil, pt))
+}
+
+func TestDiskCacheInvalidate(t *testing.T) {
+	t.Parallel()
+	diskcache := NewDiskCache(t.TempDir())
+	pt := []attestation.PredicateType{"https://example.com/test/v1"}
+	atts := []attestation.Envelope{testDSSE(testStatement)}
+	require.NoError(t, diskcache.StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &atts))
+	require.NoError(t, diskcache.StoreAttestationsBySubject(t.Context(), cacheSubject("b"), &atts))
+	require.NoError(t, diskcache.StoreAttestationsBySubjectAndPredicateType(t.Context(), cacheSubject("b"), pt, &atts))
+
+	require.NoError(t, diskcache.Invalidate(cacheSubject("b"), nil))
+	res, err := diskcache.GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	for _, get := range []func() (*[]attestation.Envelope, error){
+		func() (*[]attestation.Envelope, error) {
+			return diskcache.GetAttestationsBySubject(t.Context(), cacheSubject("b"))
+		},
+		func() (*[]attestation.Envelope, error) {
+			return diskcache.GetAttestationsBySubjectAndPredicateType(t.Context(), cacheSubject("b"), pt)
+		},
+	} {
+		res, err := get()
+		require.NoError(t, err)
+		require.Nil(t, res)
+	}
+
+	// Damaged entries are a miss
+	path := diskcache.queryPath(t.Context(), cacheQuerySubject, cacheSubject("a"), nil)
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	res, err = diskcache.GetAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	require.NoError(t, diskcache.Purge())
+	require.NoDirExists(t, filepath.Join(diskcache.Dir(), diskCacheEnvelopes))
+}
+
+func TestDiskCacheCollectsEnvelopes(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{t: time.Now()}
+	diskcache := NewDiskCache(t.TempDir(), WithCacheTTL(time.Hour))
+	diskcache.now = clock.now
+	diskcache.sweepGrace = 0
+
+	envelopes := func() []string {
+		t.Helper()
+		files, err := filepath.Glob(filepath.Join(diskcache.Dir(), diskCacheEnvelopes, "*", "*.json"))
+		require.NoError(t, err)
+		return files
+	}
+	pt := "https://slsa.dev/provenance/v1"
+	e1, e2, e3 := testDSSEWithSubject(pt, "a", "sha256"), testDSSEWithSubject(pt, "b", "sha256"), testDSSEWithSubject(pt, "c", "sha256")
+
+	require.NoError(t, diskcache.StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &[]attestation.Envelope{e1, e2}))
+	require.NoError(t, diskcache.StoreAttestationsBySubject(t.Context(), cacheSubject("b"), &[]attestation.Envelope{e2}))
+	require.Len(t, envelopes(), 2)
+
+	// Envelopes referenced by other queries are kept
+	require.NoError(t, diskcache.Invalidate(cacheSubject("a"), nil))
+	require.Len(t, envelopes(), 1)
+
+	// Replaced and expired results are collected by the periodic sweep
+	require.NoError(t, diskcache.StoreAttestationsBySubject(t.Context(), cacheSubject("b"), &[]attestation.Envelope{e3}))
+	require.Len(t, envelopes(), 2)
+	clock.t = clock.t.Add(2 * time.Hour)
+	require.NoError(t, diskcache.StoreAttestationsBySubject(t.Context(), cacheSubject("c"), &[]attestation.Envelope{e1}))
+	require.Len(t, envelopes(), 1)
+	require.NoFileExists(t, diskcache.queryPath(t.Context(), cacheQuerySubject, cacheSubject("b"), nil))
+
+	require.NoError(t, diskcache.Purge())
+	require.Empty(t, envelopes())
+
+	// Recently written envelopes are not collected
+	diskcache.sweepGrace = time.Hour
+	require.NoError(t, diskcache.StoreAttestationsBySubject(t.Context(), cacheSubject("a"), &[]attestation.Envelope{e1}))
+	require.NoError(t, diskcache.Invalidate(cacheSubject("a"), nil))
+	require.Len(t, envelopes(), 1)
+}
+
+func TestDiskCacheAcrossAgents(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	calls := 0
+	fetcher := &fakeFetcher{
+		fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+			calls++
+			return []attestation.Envelope{testDSSE(testStatement)}, nil
+		},
+	}
+
+	for i := range 2 {
+		agent, err := New(WithCache(NewDiskCache(dir)), WithRepository(fetcher))
+		require.NoError(t, err)
+		atts, report, err := agent.FetchAttestationsBySubjectWithReport(t.Context(), cacheSubject("a"))
+		require.NoError(t, err)
+		require.Len(t, atts, 1)
+		require.Equal(t, i == 1, report.FromCache)
+	}
+	require.Equal(t, 1, calls)
+}
+
+func TestDiskCacheRejectsModifiedEnvelopes(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	calls := 0
+	fetcher := &fakeFetcher{
+		fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+			calls++
+			return []attestation.Envelope{verifiedDSSE(testStatement)}, nil
+		},
+	}
+
+	newAgent := func() *Agent {
+		agent, err := New(WithCache(NewDiskCache(dir)), WithRepository(fetcher))
+		require.NoError(t, err)
+		return agent
+	}
+
+	_, err := newAgent().FetchAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+
+	// Change the predicate of the envelope stored on disk
+	files, err := filepath.Glob(filepath.Join(dir, diskCacheEnvelopes, "*", "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	data = bytes.Replace(data, []byte(base64.StdEncoding.EncodeToString([]byte(testStatement))),
+		[]byte(base64.StdEncoding.EncodeToString([]byte(strings.Replace(testStatement, "true", "false", 1)))), 1)
+	require.NoError(t, os.WriteFile(files[0], data, 0o600))
+
+	// The modified envelope is not served, the data is fetched again
+	atts, report, err := newAgent().FetchAttestationsBySubjectWithReport(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.False(t, report.FromCache)
+	require.Equal(t, 2, calls)
+	require.Len(t, atts, 1)
+	require.Contains(t, string(atts[0].GetStatement().GetPredicate().GetData()), "true")
+
+	// And the cache holds the original envelope again
+	_, report, err = newAgent().FetchAttestationsBySubjectWithReport(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.True(t, report.FromCache)
+	require.Equal(t, 2, calls)
+}
@@ -0,0 +1,510 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/google/go-containerregistry/pkg/crane"
+
+	"github.com/carabiner-dev/collector/internal/creds"
+	"github.com/carabiner-dev/collector/repository/coci"
+	"github.com/carabiner-dev/collector/repository/filesystem"
+	"github.com/carabiner-dev/collector/repository/git"
+	"github.com/carabiner-dev/collector/repository/github"
+	"github.com/carabiner-dev/collector/repository/gitsign"
+	"github.com/carabiner-dev/collector/repository/http"
+	"github.com/carabiner-dev/collector/repository/jsonl"
+	"github.com/carabiner-dev/collector/repository/maven"
+	"github.com/carabiner-dev/collector/repository/note"
+	"github.com/carabiner-dev/collector/repository/oci"
+	"github.com/carabiner-dev/collector/repository/ossrebuild"
+	"github.com/carabiner-dev/collector/repository/release"
+	"github.com/carabiner-dev/collector/repository/sbomfs"
+	"github.com/carabiner-dev/collector/repository/stash"
+)
+
+// OptionKind is the type of value a driver option takes.
+type OptionKind string
+
+const (
+	OptionString OptionKind = "string"
+	OptionBool   OptionKind = "bool"
+	OptionInt    OptionKind = "int"
+	OptionUint   OptionKind = "uint"
+
+	// OptionSecret options take the name of an environment variable
+	// holding the secret. Secrets are never written inline.
+	OptionSecret OptionKind = "secret"
+)
+
+// DriverOption describes an option accepted by a repository driver.
+type DriverOption struct {
+	Name        string
+	Kind        OptionKind
+	Description string
+}
+
+// driverSpec defines the options a built-in driver accepts and how to
+// build a repository with them.
+type driverSpec struct {
+	options []DriverOption
+	// build creates the repository from the init string (without the
+	// type moniker) and the parsed options.
+	build func(string, optionValues) (attestation.Repository, error)
+}
+
+// optionValues holds the parsed values of the options passed to a driver.
+type optionValues map[string]any
+
+func (v optionValues) str(name string) (string, bool) {
+	s, ok := v[name].(string)
+	return s, ok
+}
+
+func (v optionValues) boolean(name string) (bool, bool) {
+	b, ok := v[name].(bool)
+	return b, ok
+}
+
+func (v optionValues) integer(name string) (int, bool) {
+	i, ok := v[name].(int)
+	return i, ok
+}
+
+func (v optionValues) uinteger(name string) (uint, bool) {
+	i, ok := v[name].(uint)
+	return i, ok
+}
+
+// driverSpecs has the option definitions of the built-in drivers, keyed by
+// type moniker. They are registered with the drivers in
+// LoadDefaultRepositoryTypes, drivers registered under the same moniker
+// with RegisterCollectorType take no options.
+var driverSpecs = map[string]driverSpec{
+	coci.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "insecure", Kind: OptionBool, Description: "allow connecting to the registry over plain HTTP"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*coci.Options) error{coci.WithReference(init)}
+			if b, ok := v.boolean("insecure"); ok && b {
+				fns = append(fns, coci.WithCraneOpts(crane.Insecure))
+			}
+			return coci.New(fns...)
+		},
+	},
+	filesystem.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "rekor-url", Kind: OptionString, Description: "transparency log used to verify signatures"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			if s, ok := v.str("rekor-url"); ok {
+				return filesystem.New(filesystem.WithInitString(init), filesystem.WithRekorURL(s))
+			}
+			return filesystem.New(filesystem.WithInitString(init))
+		},
+	},
+	git.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "ref", Kind: OptionString, Description: "git ref to clone"},
+			{Name: "path", Kind: OptionString, Description: "directory of the attestations in the repository"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*git.Options) error{git.WithLocator(init)}
+			if s, ok := v.str("ref"); ok {
+				fns = append(fns, git.WithRef(s))
+			}
+			if s, ok := v.str("path"); ok {
+				fns = append(fns, git.WithPath(s))
+			}
+			return git.New(fns...)
+		},
+	},
+	github.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "token-env", Kind: OptionSecret, Description: "environment variable holding the GitHub token"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*github.Options){github.WithRepo(init)}
+			if s, ok := v.str("token-env"); ok {
+				fns = append(fns, github.WithToken(s))
+			}
+			return github.New(fns...)
+		},
+	},
+	gitsign.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "token-env", Kind: OptionSecret, Description: "environment variable holding the token used to fetch the remote"},
+			{Name: "remote", Kind: OptionString, Description: "git remote to fetch"},
+			{Name: "ref", Kind: OptionString, Description: "git ref to fetch"},
+			{Name: "depth", Kind: OptionInt, Description: "depth of the fetched history"},
+			{Name: "rekor-url", Kind: OptionString, Description: "transparency log used to look up signatures"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*gitsign.Collector) error{gitsign.WithInitString(init)}
+			if s, ok := v.str("token-env"); ok {
+				fns = append(fns, gitsign.WithToken(s))
+			}
+			if s, ok := v.str("remote"); ok {
+				fns = append(fns, gitsign.WithRemote(s))
+			}
+			if s, ok := v.str("ref"); ok {
+				fns = append(fns, gitsign.WithRef(s))
+			}
+			if i, ok := v.integer("depth"); ok {
+				fns = append(fns, gitsign.WithDepth(i))
+			}
+			if s, ok := v.str("rekor-url"); ok {
+				fns = append(fns, gitsign.WithRekorURL(s))
+			}
+			return gitsign.New(func(c *gitsign.Collector) error {
+				for _, fn := range fns {
+					if err := fn(c); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		},
+	},
+	http.TypeMoniker:      httpSpec("http"),
+	http.TypeMonikerHTTPS: httpSpec("https"),
+	jsonl.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "max-parallel", Kind: OptionInt, Description: "number of files read in parallel"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*jsonl.Options){jsonl.WithPath(init)}
+			if i, ok := v.integer("max-parallel"); ok {
+				fns = append(fns, jsonl.WithMaxParallel(i))
+			}
+			return jsonl.New(fns...)
+		},
+	},
+	maven.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "base-url", Kind: OptionString, Description: "base URL of the Maven repository"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*maven.Collector) error{maven.WithPackageURL(init)}
+			if s, ok := v.str("base-url"); ok {
+				fns = append(fns, maven.WithBaseURL(s))
+			}
+			return maven.New(fns...)
+		},
+	},
+	note.TypeMoniker: {
+		options: noteOptions,
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			return note.New(append([]func(*note.Options){note.WithLocator(init)}, noteFuncs(v)...)...)
+		},
+	},
+	note.TypeMonikerDynamic: {
+		options: noteOptions,
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			return note.NewDynamic(append([]func(*note.Options){note.DynamicRepoURL(init)}, noteFuncs(v)...)...)
+		},
+	},
+	oci.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "insecure", Kind: OptionBool, Description: "connect to the registry over plain HTTP"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*oci.Options) error{oci.WithReference(init)}
+			if b, ok := v.boolean("insecure"); ok {
+				fns = append(fns, oci.WithInsecure(b))
+			}
+			return oci.New(fns...)
+		},
+	},
+	// The drivers without options reject the options in their init strings
+	ossrebuild.TypeMoniker: {},
+	release.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "token-env", Kind: OptionSecret, Description: "environment variable holding the GitHub token"},
+			{Name: "tag", Kind: OptionString, Description: "release tag"},
+			{Name: "retries", Kind: OptionUint, Description: "number of retries of the release API requests"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*release.Collector) error{release.WithReleaseURL(init)}
+			if s, ok := v.str("token-env"); ok {
+				fns = append(fns, release.WithToken(s))
+			}
+			if s, ok := v.str("tag"); ok {
+				fns = append(fns, release.WithTag(s))
+			}
+			if i, ok := v.uinteger("retries"); ok {
+				fns = append(fns, release.WithRetries(i))
+			}
+			return release.New(fns...)
+		},
+	},
+	sbomfs.TypeMoniker: {},
+	stash.TypeMoniker: {
+		options: []DriverOption{
+			{Name: "token-env", Kind: OptionSecret, Description: "environment variable holding the stash token"},
+			{Name: "url", Kind: OptionString, Description: "address of the stash service"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			fns := []func(*stash.Options){stash.WithInit(init)}
+			if s, ok := v.str("token-env"); ok {
+				fns = append(fns, stash.WithToken(s))
+			}
+			if s, ok := v.str("url"); ok {
+				fns = append(fns, stash.WithURL(s))
+			}
+			return stash.New(fns...)
+		},
+	},
+}
+
+// httpSpec returns the driver spec of the http and https drivers.
+func httpSpec(scheme string) driverSpec {
+	return driverSpec{
+		options: []DriverOption{
+			{Name: "retries", Kind: OptionUint, Description: "number of retries of each request"},
+			{Name: "jsonl", Kind: OptionBool, Description: "read the responses as JSON lines"},
+			{Name: "template-subject", Kind: OptionString, Description: "URL template to fetch by subject"},
+			{Name: "template-subject-digest", Kind: OptionString, Description: "URL template to fetch by subject digest"},
+			{Name: "template-subject-name", Kind: OptionString, Description: "URL template to fetch by subject name"},
+			{Name: "template-subject-uri", Kind: OptionString, Description: "URL template to fetch by subject URI"},
+			{Name: "template-predicate-type", Kind: OptionString, Description: "URL template to fetch by predicate type"},
+		},
+		build: func(init string, v optionValues) (attestation.Repository, error) {
+			if strings.HasPrefix(init, "//") {
+				init = scheme + ":" + init
+			}
+			fns := []func(*http.Options) error{http.WithURL(init)}
+			if i, ok := v.uinteger("retries"); ok {
+				fns = append(fns, http.WithRetries(i))
+			}
+			if b, ok := v.boolean("jsonl"); ok {
+				fns = append(fns, http.WithReadJSONL(b))
+			}
+			for name, fn := range map[string]func(string) func(*http.Options) error{
+				"template-subject":        http.WithTemplateSubject,
+				"template-subject-digest": http.WithTemplateSubjectDigest,
+				"template-subject-name":   http.WithTemplateSubjectName,
+				"template-subject-uri":    http.WithTemplateSubjectUri,
+				"template-predicate-type": http.WithTemplatePredicateType,
+			} {
+				if s, ok := v.str(name); ok {
+					fns = append(fns, fn(s))
+				}
+			}
+			return http.New(fns...)
+		},
+	}
+}
+
+// noteOptions are the options of the git notes drivers.
+var noteOptions = []DriverOption{
+	{Name: "push", Kind: OptionBool, Description: "push the notes to the remote after storing"},
+	{Name: "username", Kind: OptionString, Description: "user name to authenticate to the git remote"},
+	{Name: "password-env", Kind: OptionSecret, Description: "environment variable holding the git remote password"},
+}
+
+func noteFuncs(v optionValues) []func(*note.Options) {
+	fns := []func(*note.Options){}
+	if b, ok := v.boolean("push"); ok {
+		fns = append(fns, note.WithPush(b))
+	}
+	user, uok := v.str("username")
+	pass, pok := v.str("password-env")
+	if uok || pok {
+		fns = append(fns, note.WithHttpAuth(user, pass))
+	}
+	return fns
+}
+
+// parseDriverOptions validates the options passed to a driver and converts
+// them to their typed values. When resolve is true, secret options are read
+// from the environment, otherwise only their names are checked.
+func parseDriverOptions(moniker string, opts map[string]string, resolve bool) (optionValues, error) {
+	mtx.Lock()
+	spec, ok := repositoryOptions[moniker]
+	mtx.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("repository type %q does not support options", moniker)
+	}
+
+	errs := []error{}
+	ret := optionValues{}
+	for name, value := range opts {
+		i := slices.IndexFunc(spec.options, func(o DriverOption) bool { return o.Name == name })
+		if i == -1 {
+			errs = append(errs, unknownOptionError(moniker, name, spec.options))
+			continue
+		}
+
+		var err error
+		switch spec.options[i].Kind {
+		case OptionString:
+			ret[name] = value
+		case OptionBool:
+			ret[name], err = strconv.ParseBool(value)
+		case OptionInt:
+			ret[name], err = strconv.Atoi(value)
+		case OptionUint:
+			var u uint64
+			u, err = strconv.ParseUint(value, 10, 0)
+			ret[name] = uint(u)
+		case OptionSecret:
+			if value == "" {
+				err = errors.New("environment variable name is empty")
+				break
+			}
+			if !resolve {
+				ret[name] = ""
+				break
+			}
+			secret := creds.Token("", value)
+			if secret == "" {
+				err = fmt.Errorf("environment variable %s is not set", value)
+			}
+			ret[name] = secret
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for %s option %q: %w", moniker, name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// unknownOptionError builds the error returned when a driver does not
+// support an option, listing the valid ones.
+func unknownOptionError(moniker, name string, valid []DriverOption) error {
+	names := make([]string, 0, len(valid))
+	for _, o := range valid {
+		names = append(names, o.Name)
+	}
+	hint := ""
+	if slices.Contains(names, name+"-env") {
+		hint = fmt.Sprintf(" (secrets are read from the environment, use %s-env)", name)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("unknown option %q: %s repositories take no options", name, moniker)
+	}
+	return fmt.Errorf(
+		"unknown option %q for %s repositories%s, valid options: %s",
+		name, moniker, hint, strings.Join(names, ", "),
+	)
+}
+
+// splitInitString splits an init string in its type moniker, location and
+// options, merging the options in the init string with opts. The options of
+// the built-in drivers are written after the location, separated by
+// semicolons:
+//
+//	moniker:location;name=value;name=value
+//
+// The first semicolon starts the options when all the text after it is
+// made of name=value pairs, otherwise the semicolon is part of the
+// location. Names and values are unescaped as URL query parameters. Query
+// parameters in the location are never taken as options, as they may be
+// part of the URL of the repository.
+//
+// The init strings of the drivers registered with RegisterCollectorType
+// are returned unchanged.
+func splitInitString(init string, opts map[string]string) (moniker, location string, merged map[string]string, err error) {
+	moniker, location, _ = strings.Cut(init, ":")
+	mtx.Lock()
+	_, ok := repositoryOptions[moniker]
+	mtx.Unlock()
+	if !ok {
+		return moniker, location, opts, nil
+	}
+
+	var pairs []string
+	if loc, raw, ok := strings.Cut(location, ";"); ok && isOptionList(raw) {
+		location = loc
+		pairs = strings.Split(raw, ";")
+	}
+
+	merged = map[string]string{}
+	for name, value := range opts {
+		merged[name] = value
+	}
+	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		if name, err = url.QueryUnescape(name); err != nil {
+			return "", "", nil, fmt.Errorf("invalid option name in init string: %w", err)
+		}
+		if value, err = url.QueryUnescape(value); err != nil {
+			return "", "", nil, fmt.Errorf("invalid value for option %q in init string: %w", name, err)
+		}
+		if name == "" {
+			return "", "", nil, fmt.Errorf("option without a name in init string: %q", pair)
+		}
+		if _, ok := merged[name]; ok {
+			return "", "", nil, fmt.Errorf("option %q is set more than once", name)
+		}
+		merged[name] = value
+	}
+	return moniker, location, merged, nil
+}
+
+// isOptionList reports whether the text after a semicolon in an init string
+// is a list of name=value pairs.
+func isOptionList(raw string) bool {
+	for _, pair := range strings.Split(raw, ";") {
+		if pair != "" && !strings.Contains(pair, "=") {
+			return false
+		}
+	}
+	return true
+}
+
+// RepositoryFromStringWithOptions builds a repository from an init string
+// and a set of driver options. Options can also be set in the init string
+// of the built-in drivers, see below. Options are validated against the
+// options supported by the driver. Options of the secret kind take the name
+// of the environment variable holding the secret.
+//
+// Options are written after the location, separated by semicolons:
+//
+//	note:git+https://github.com/example/app@abc123;push=false
+//	https:example.com/atts.jsonl;retries=3;jsonl=true
+//
+// Only the built-in drivers take options. When a built-in moniker is
+// registered with another factory, its init strings reach the factory
+// unchanged and options are rejected.
+func RepositoryFromStringWithOptions(init string, opts map[string]string) (attestation.Repository, error) {
+	t, location, opts, err := splitInitString(init, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	mtx.Lock()
+	factory, ok := repositoryTypes[t]
+	spec := repositoryOptions[t]
+	mtx.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("repository type unknown: %q", t)
+	}
+	if len(opts) == 0 {
+		return factory(location)
+	}
+
+	values, err := parseDriverOptions(t, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	// Only the drivers with options have a spec, parseDriverOptions
+	// rejects the options of the rest.
+	return spec.build(location, values)
+}
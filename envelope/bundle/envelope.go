@@ -15,6 +15,8 @@ import (
 	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
 	sgbundle "github.com/sigstore/sigstore-go/pkg/bundle"
 	"github.com/sigstore/sigstore-go/pkg/fulcio/certificate"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/sigstore/sigstore-go/pkg/verify"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -103,25 +105,46 @@ func (env *Envelope) GetVerification() attestation.Verification {
 // Verify checks the bundle signatures and generatesit Verification data.
 // If the envelope is already verified, the signatures are not verified
 // again.
-func (e *Envelope) Verify(_ ...any) error {
+//
+// By default, bundles are verified against the sigstore public good
+// instance. To use a different trust root, pass a root.TrustedMaterial and,
+// optionally, VerifyOptions.
+func (e *Envelope) Verify(args ...any) error {
 	// If the bundle is already verified, don't retry
 	if e.GetVerification() != nil {
 		return nil
 	}
 
-	// Verify the sigstore signatures
-	verifier := signer.NewVerifier()
-
-	// We skip the identity verification as the policy chekcs it at runtime:
-	verifier.Options.SkipIdentityCheck = true
+	var trustedMaterial root.TrustedMaterial
+	var funcs []VerifyOption
+	for _, a := range args {
+		switch v := a.(type) {
+		case root.TrustedMaterial:
+			trustedMaterial = v
+		case VerifyOption:
+			funcs = append(funcs, v)
+		}
+	}
 
-	// Verify the bundle. We discard the result for now as it does not include
-	// the signature. We may capture it at some point.
-	if _, err := verifier.VerifyParsedBundle(
-		&sgbundle.Bundle{Bundle: &e.Bundle},
-		options.WithSkipIdentityCheck(true),
-	); err != nil {
-		return fmt.Errorf("verifying sigstore signatures: %w", err)
+	if trustedMaterial != nil {
+		if err := VerifyWithTrustedMaterial(&sgbundle.Bundle{Bundle: &e.Bundle}, trustedMaterial, funcs...); err != nil {
+			return fmt.Errorf("verifying sigstore signatures: %w", err)
+		}
+	} else {
+		// Verify the sigstore signatures
+		verifier := signer.NewVerifier()
+
+		// We skip the identity verification as the policy chekcs it at runtime:
+		verifier.Options.SkipIdentityCheck = true
+
+		// Verify the bundle. We discard the result for now as it does not include
+		// the signature. We may capture it at some point.
+		if _, err := verifier.VerifyParsedBundle(
+			&sgbundle.Bundle{Bundle: &e.Bundle},
+			options.WithSkipIdentityCheck(true),
+		); err != nil {
+			return fmt.Errorf("verifying sigstore signatures: %w", err)
+		}
 	}
 
 	if e.GetVerificationMaterial() == nil {
@@ -223,3 +246,48 @@ func (e *Envelope) UnmarshalJSON(data []byte) error {
 
 	return nil
 }
+
+// VerifyOption customizes the verification of bundles against a trust
+// root, see VerifyWithTrustedMaterial.
+type VerifyOption func(*verifyOptions)
+
+type verifyOptions struct {
+	optionalTlog bool
+}
+
+// WithoutTransparencyLog accepts bundles without transparency log entries
+// when the trust root has transparency logs. Those bundles are only checked
+// against the timestamp authorities of the trust root.
+func WithoutTransparencyLog() VerifyOption {
+	return func(o *verifyOptions) {
+		o.optionalTlog = true
+	}
+}
+
+// VerifyWithTrustedMaterial verifies a bundle against a custom trust root.
+// As with the default verifier, identities are not checked here. When the
+// trust root has transparency logs, bundles must have an entry in one of
+// them, unless WithoutTransparencyLog is passed.
+func VerifyWithTrustedMaterial(b *sgbundle.Bundle, tm root.TrustedMaterial, funcs ...VerifyOption) error {
+	vo := verifyOptions{}
+	for _, fn := range funcs {
+		fn(&vo)
+	}
+
+	opts := []verify.VerifierOption{verify.WithObserverTimestamps(1)}
+	// Requiring the log only when the bundle has entries would let a bundle
+	// skip the check by dropping them.
+	if (len(tm.RekorLogs()) > 0 && !vo.optionalTlog) || len(b.GetVerificationMaterial().GetTlogEntries()) > 0 {
+		opts = append(opts, verify.WithTransparencyLog(1))
+	}
+	v, err := verify.NewVerifier(tm, opts...)
+	if err != nil {
+		return fmt.Errorf("creating verifier: %w", err)
+	}
+	if _, err := v.Verify(b, verify.NewPolicy(
+		verify.WithoutArtifactUnsafe(), verify.WithoutIdentitiesUnsafe(),
+	)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"testing"
+
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/stretchr/testify/require"
+)
+
+// logTrustedMaterial is a trust root with a transparency log
+type logTrustedMaterial struct {
+	root.BaseTrustedMaterial
+}
+
+func (logTrustedMaterial) RekorLogs() map[string]*root.TransparencyLog {
+	return map[string]*root.TransparencyLog{"log": {}}
+}
+
+func TestVerifyRequiresTransparencyLog(t *testing.T) {
+	t.Parallel()
+	atts, err := (&Parser{}).ParseFile("testdata/bundle-provenance.json")
+	require.NoError(t, err)
+	env, ok := atts[0].(*Envelope)
+	require.True(t, ok)
+	require.NotEmpty(t, env.GetVerificationMaterial().GetTlogEntries())
+
+	// Dropping the log entries does not skip the transparency log check
+	env.VerificationMaterial.TlogEntries = nil
+	err = env.Verify(&logTrustedMaterial{})
+	require.ErrorContains(t, err, "transparency log")
+
+	// Unless the caller opts out
+	err = env.Verify(&logTrustedMaterial{}, WithoutTransparencyLog())
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "transparency log")
+}
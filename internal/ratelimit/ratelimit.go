@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ratelimit provides a minimal limiter to space out the requests
+// the agent sends to a repository.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces calls so that no more than a number of them start per
+// second. It does not allow bursts. A nil Limiter never waits.
+type Limiter struct {
+	mtx      sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// New returns a limiter allowing perSecond calls per second. It returns nil
+// (no limit) if perSecond is not positive.
+func New(perSecond float64) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next call is allowed or the context is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	// Reserve the next slot
+	l.mtx.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mtx.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+	t.Run("no-limit", func(t *testing.T) {
+		t.Parallel()
+		l := New(0)
+		require.Nil(t, l)
+		require.NoError(t, l.Wait(t.Context()))
+	})
+
+	t.Run("spaced", func(t *testing.T) {
+		t.Parallel()
+		l := New(20) // one call every 50ms
+		start := time.Now()
+		for range 3 {
+			require.NoError(t, l.Wait(t.Context()))
+		}
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+		l := New(0.1) // one call every 10s
+		require.NoError(t, l.Wait(t.Context()))
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		require.ErrorIs(t, l.Wait(ctx), context.Canceled)
+	})
+}
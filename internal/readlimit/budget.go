@@ -0,0 +1,303 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package readlimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Budget caps the total bytes read and envelopes parsed by all the
+// collectors queried in a single fetch. The agent passes it to the drivers
+// in the context. A nil budget has no limits.
+type Budget struct {
+	maxBytes     int64
+	maxEnvelopes int64
+	bytes        atomic.Int64
+	envelopes    atomic.Int64
+}
+
+// NewBudget returns a budget of maxBytes bytes and maxEnvelopes envelopes.
+// A value of 0 or less means no limit. When neither is limited, NewBudget
+// returns nil.
+func NewBudget(maxBytes int64, maxEnvelopes int) *Budget {
+	if maxBytes <= 0 && maxEnvelopes <= 0 {
+		return nil
+	}
+	return &Budget{maxBytes: maxBytes, maxEnvelopes: int64(maxEnvelopes)}
+}
+
+// take reserves up to n units from a counter capped at limit. It returns the
+// units granted.
+func take(counter *atomic.Int64, limit, n int64) int64 {
+	if limit <= 0 {
+		return n
+	}
+	for {
+		used := counter.Load()
+		granted := min(n, limit-used)
+		if granted <= 0 {
+			return 0
+		}
+		if counter.CompareAndSwap(used, used+granted) {
+			return granted
+		}
+	}
+}
+
+// Exhausted returns true when the budget has no bytes or envelopes left.
+func (b *Budget) Exhausted() bool {
+	if b == nil {
+		return false
+	}
+	return (b.maxBytes > 0 && b.bytes.Load() >= b.maxBytes) ||
+		(b.maxEnvelopes > 0 && b.envelopes.Load() >= b.maxEnvelopes)
+}
+
+// Tracker records the sources of a repository truncated by the budget.
+type Tracker struct {
+	mtx       sync.Mutex
+	sources   []string
+	envelopes atomic.Int64
+}
+
+// Truncated returns the sources that could not be read completely.
+func (t *Tracker) Truncated() []string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return slices.Clone(t.sources)
+}
+
+// Envelopes returns the number of envelopes charged to the budget.
+func (t *Tracker) Envelopes() int {
+	return int(t.envelopes.Load())
+}
+
+func (t *Tracker) truncate(source string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if !slices.Contains(t.sources, source) {
+		t.sources = append(t.sources, source)
+	}
+}
+
+type budgetKey struct{}
+
+type budgetState struct {
+	budget      *Budget
+	tracker     *Tracker
+	reservation *Reservation
+}
+
+func fromContext(ctx context.Context) budgetState {
+	if s, ok := ctx.Value(budgetKey{}).(budgetState); ok {
+		return s
+	}
+	return budgetState{}
+}
+
+// WithBudget returns a context carrying the budget.
+func WithBudget(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, budgetState{budget: b})
+}
+
+// Track returns a context that records the sources truncated by the budget
+// in the context in a new tracker. The agent tracks each repository.
+func Track(ctx context.Context) (context.Context, *Tracker) {
+	t := &Tracker{}
+	return context.WithValue(ctx, budgetKey{}, budgetState{budget: fromContext(ctx).budget, tracker: t}), t
+}
+
+// Reservation holds the charges made to the budget through a context until
+// they are committed or refunded. The agent makes a reservation for each
+// attempt to fetch from a repository, so failed attempts don't use the
+// budget left for the rest.
+type Reservation struct {
+	mtx       sync.Mutex
+	parent    budgetState
+	bytes     int64
+	envelopes int64
+	sources   []string
+	closed    bool
+}
+
+// Reserve returns a context whose charges to the budget are held in a new
+// reservation.
+func Reserve(ctx context.Context) (context.Context, *Reservation) {
+	s := fromContext(ctx)
+	r := &Reservation{parent: s}
+	return context.WithValue(ctx, budgetKey{}, budgetState{budget: s.budget, tracker: s.tracker, reservation: r}), r
+}
+
+// Commit keeps the charges of the reservation and records its truncated
+// sources in the tracker.
+func (r *Reservation) Commit() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if r.parent.tracker != nil {
+		r.parent.tracker.envelopes.Add(r.envelopes)
+	}
+	for _, source := range r.sources {
+		r.parent.truncate(source)
+	}
+}
+
+// Refund returns the charges of the reservation to the budget. From then
+// on, the budget is exhausted for the reads made with the context of the
+// reservation so abandoned drivers stop reading.
+func (r *Reservation) Refund() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	if b := r.parent.budget; b != nil {
+		b.bytes.Add(-r.bytes)
+		b.envelopes.Add(-r.envelopes)
+	}
+}
+
+// hold locks the reservation in the context, if any, while charging the
+// budget. It returns false when the reservation is closed.
+func (s budgetState) hold() (ok bool, release func()) {
+	if s.reservation == nil {
+		return true, func() {}
+	}
+	s.reservation.mtx.Lock()
+	return !s.reservation.closed, s.reservation.mtx.Unlock
+}
+
+// charge records the units charged to the budget.
+func (s budgetState) charge(bytes, envelopes int64) {
+	if s.reservation != nil {
+		s.reservation.bytes += bytes
+		s.reservation.envelopes += envelopes
+		return
+	}
+	if s.tracker != nil {
+		s.tracker.envelopes.Add(envelopes)
+	}
+}
+
+// Stop returns true when the budget in the context is exhausted, recording
+// source as truncated. Collectors reading many sources call it before each
+// one to stop early.
+func Stop(ctx context.Context, source string) bool {
+	s := fromContext(ctx)
+	ok, release := s.hold()
+	defer release()
+	if !ok {
+		return true
+	}
+	if !s.budget.Exhausted() {
+		return false
+	}
+	s.truncate(source)
+	return true
+}
+
+// Consume charges size bytes read from source to the budget in the
+// context. It returns false, recording the source as truncated, when the
+// budget does not have room for them. A source that does not fit uses up
+// the budget so later sources are not read either.
+func Consume(ctx context.Context, source string, size int64) bool {
+	s := fromContext(ctx)
+	ok, release := s.hold()
+	defer release()
+	if !ok {
+		return false
+	}
+	if s.budget == nil || s.budget.maxBytes <= 0 {
+		return true
+	}
+	for {
+		used := s.budget.bytes.Load()
+		if used+size <= s.budget.maxBytes {
+			if s.budget.bytes.CompareAndSwap(used, used+size) {
+				s.charge(size, 0)
+				return true
+			}
+			continue
+		}
+		if full := max(used, s.budget.maxBytes); s.budget.bytes.CompareAndSwap(used, full) {
+			s.charge(full-used, 0)
+			s.truncate(source)
+			return false
+		}
+	}
+}
+
+// ErrExhausted is returned by the readers of ConsumeReader when the budget
+// runs out.
+var ErrExhausted = errors.New("read budget exhausted")
+
+// ConsumeReader returns a reader that charges the bytes read from r to the
+// budget in the context as they are read, for sources whose size is not
+// known before reading them. When the budget does not have room for the
+// data read, the source is recorded as truncated and reads fail with
+// ErrExhausted.
+func ConsumeReader(ctx context.Context, r io.Reader, source string) io.Reader {
+	return &budgetReader{ctx: ctx, r: r, source: source}
+}
+
+type budgetReader struct {
+	ctx    context.Context
+	r      io.Reader
+	source string
+	err    error
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.r.Read(p)
+	if n > 0 && !Consume(b.ctx, b.source, int64(n)) {
+		b.err = ErrExhausted
+		return 0, b.err
+	}
+	return n, err
+}
+
+// ConsumeEnvelopes charges n envelopes parsed from source to the budget in
+// the context and returns how many of them can be kept. When it is less
+// than n, the source is recorded as truncated.
+func ConsumeEnvelopes(ctx context.Context, source string, n int) int {
+	s := fromContext(ctx)
+	ok, release := s.hold()
+	defer release()
+	if !ok {
+		return 0
+	}
+	if s.budget == nil {
+		return n
+	}
+	granted := int(take(&s.budget.envelopes, s.budget.maxEnvelopes, int64(n)))
+	s.charge(0, int64(granted))
+	if granted < n {
+		s.truncate(source)
+	}
+	return granted
+}
+
+func (s budgetState) truncate(source string) {
+	if s.reservation != nil {
+		if !slices.Contains(s.reservation.sources, source) {
+			s.reservation.sources = append(s.reservation.sources, source)
+		}
+		return
+	}
+	if s.tracker != nil {
+		s.tracker.truncate(source)
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package readlimit
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+	require.Nil(t, NewBudget(0, 0))
+
+	t.Run("bytes", func(t *testing.T) {
+		t.Parallel()
+		ctx, tracker := Track(WithBudget(t.Context(), NewBudget(10, 0)))
+		require.True(t, Consume(ctx, "a", 6))
+		require.False(t, Stop(ctx, "b"))
+		require.False(t, Consume(ctx, "b", 6))
+		// A source that does not fit uses up the budget
+		require.True(t, Stop(ctx, "c"))
+		require.False(t, Consume(ctx, "d", 1))
+		require.Equal(t, []string{"b", "c", "d"}, tracker.Truncated())
+		require.Equal(t, 7, ConsumeEnvelopes(ctx, "e", 7))
+	})
+
+	t.Run("envelopes", func(t *testing.T) {
+		t.Parallel()
+		ctx, tracker := Track(WithBudget(t.Context(), NewBudget(0, 5)))
+		require.Equal(t, 3, ConsumeEnvelopes(ctx, "a", 3))
+		require.Equal(t, 2, ConsumeEnvelopes(ctx, "b", 3))
+		require.Equal(t, 0, ConsumeEnvelopes(ctx, "c", 1))
+		require.True(t, Consume(ctx, "d", 1<<30))
+		require.Equal(t, []string{"b", "c"}, tracker.Truncated())
+		require.Equal(t, 5, tracker.Envelopes())
+	})
+
+	t.Run("reservations", func(t *testing.T) {
+		t.Parallel()
+		ctx, tracker := Track(WithBudget(t.Context(), NewBudget(10, 5)))
+
+		// Refunded reservations return their charges
+		rctx, r := Reserve(ctx)
+		require.True(t, Consume(rctx, "a", 8))
+		require.Equal(t, 4, ConsumeEnvelopes(rctx, "a", 4))
+		require.False(t, Consume(rctx, "b", 8))
+		r.Refund()
+		require.Empty(t, tracker.Truncated())
+		require.Zero(t, tracker.Envelopes())
+
+		// Closed reservations don't charge the budget
+		require.True(t, Stop(rctx, "c"))
+		require.False(t, Consume(rctx, "c", 1))
+		require.Zero(t, ConsumeEnvelopes(rctx, "c", 1))
+
+		// Committed reservations keep them
+		rctx, r = Reserve(ctx)
+		require.True(t, Consume(rctx, "d", 8))
+		require.Equal(t, 5, ConsumeEnvelopes(rctx, "d", 5))
+		require.False(t, Consume(rctx, "e", 8))
+		r.Commit()
+		r.Refund()
+		require.Equal(t, []string{"e"}, tracker.Truncated())
+		require.Equal(t, 5, tracker.Envelopes())
+		require.True(t, Stop(ctx, "f"))
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		t.Parallel()
+		ctx, tracker := Track(WithBudget(t.Context(), NewBudget(10, 0)))
+		data, err := io.ReadAll(ConsumeReader(ctx, strings.NewReader("0123456"), "a"))
+		require.NoError(t, err)
+		require.Equal(t, "0123456", string(data))
+
+		// Reading stops when the data does not fit
+		_, err = io.ReadAll(ConsumeReader(ctx, strings.NewReader("0123456"), "b"))
+		require.ErrorIs(t, err, ErrExhausted)
+		require.Equal(t, []string{"b"}, tracker.Truncated())
+		require.True(t, Stop(ctx, "c"))
+	})
+
+	t.Run("no-budget", func(t *testing.T) {
+		t.Parallel()
+		ctx, tracker := Track(t.Context())
+		require.True(t, Consume(ctx, "a", 1<<30))
+		require.Equal(t, 10, ConsumeEnvelopes(ctx, "a", 10))
+		require.False(t, Stop(ctx, "a"))
+		require.Empty(t, tracker.Truncated())
+	})
+}
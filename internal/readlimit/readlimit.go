@@ -5,7 +5,12 @@
 // repository collectors.
 package readlimit
 
-import "io"
+import (
+	"context"
+	"io"
+
+	"github.com/carabiner-dev/collector/observer"
+)
 
 // DefaultMaxReadSize is the fallback maximum read size (7 MiB) used when
 // the caller does not specify a limit (i.e. MaxReadSize == 0).
@@ -23,3 +28,55 @@ func Resolve(maxReadSize int64) int64 {
 func Reader(r io.Reader, maxReadSize int64) io.Reader {
 	return io.LimitReader(r, Resolve(maxReadSize))
 }
+
+// Exceeds returns true if size is over the resolved max read size. When it
+// is, the limit hit is sent to the observer in the context.
+func Exceeds(ctx context.Context, source string, size, maxReadSize int64) bool {
+	limit := Resolve(maxReadSize)
+	if size <= limit {
+		return false
+	}
+	observer.FromContext(ctx).LimitHit(ctx, observer.LimitHitEvent{
+		Source: source, Limit: limit, Size: size,
+	})
+	return true
+}
+
+// ReaderContext works like Reader but it sends a limit hit to the observer
+// in the context when the data is truncated.
+func ReaderContext(ctx context.Context, r io.Reader, maxReadSize int64, source string) io.Reader {
+	limit := Resolve(maxReadSize)
+	return &observedReader{ctx: ctx, r: r, source: source, limit: limit, remaining: limit}
+}
+
+type observedReader struct {
+	ctx       context.Context
+	r         io.Reader
+	source    string
+	limit     int64
+	remaining int64
+	checked   bool
+}
+
+func (o *observedReader) Read(p []byte) (int, error) {
+	if o.remaining <= 0 {
+		// Once the limit is reached, peek the source to find out if the
+		// data was truncated.
+		if !o.checked {
+			o.checked = true
+			var b [1]byte
+			if n, _ := o.r.Read(b[:]); n > 0 {
+				observer.FromContext(o.ctx).LimitHit(o.ctx, observer.LimitHitEvent{
+					Source: o.source, Limit: o.limit,
+				})
+			}
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > o.remaining {
+		p = p[:o.remaining]
+	}
+	n, err := o.r.Read(p)
+	o.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package readlimit
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/observer"
+)
+
+func TestExceeds(t *testing.T) {
+	t.Parallel()
+	rec := observer.NewRecorder()
+	ctx := observer.WithObserver(t.Context(), rec)
+	require.False(t, Exceeds(ctx, "a", 10, 10))
+	require.True(t, Exceeds(ctx, "b", 11, 10))
+	require.False(t, Exceeds(ctx, "c", 11, 0))
+	require.Len(t, rec.LimitHits, 1)
+	require.Equal(t, observer.LimitHitEvent{Source: "b", Limit: 10, Size: 11}, rec.LimitHits[0])
+}
+
+func TestReaderContext(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name   string
+		data   string
+		limit  int64
+		expect string
+		hit    bool
+	}{
+		{"under", "hello", 10, "hello", false},
+		{"exact", "hello", 5, "hello", false},
+		{"over", "hello world", 5, "hello", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			rec := observer.NewRecorder()
+			ctx := observer.WithObserver(t.Context(), rec)
+			data, err := io.ReadAll(ReaderContext(ctx, strings.NewReader(tc.data), tc.limit, "src"))
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, string(data))
+			require.Equal(t, tc.hit, len(rec.LimitHits) == 1)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+
+	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
+)
+
+// observedContext returns a context carrying the agent observer. If the
+// agent has no observer configured, the context is returned as is and any
+// observer already in it is used.
+func (agent *Agent) observedContext(ctx context.Context) context.Context {
+	if agent.Options.Observer == nil {
+		return ctx
+	}
+	return observer.WithObserver(ctx, agent.Options.Observer)
+}
+
+// fetchRepo runs fetch on a single repository applying its call policy and
+// sends the fetch start and end events to the observer. Drivers get the
+// observer and the read budget from the context. The returned report has
+// the repository latency, error and the sources truncated by the budget.
+func (agent *Agent) fetchRepo(ctx context.Context, r attestation.Fetcher, opts attestation.FetchOptions, fetch repoFetchFunc) ([]attestation.Envelope, RepositoryReport) {
+	name := agent.repoName(r)
+	ctx = observer.WithRepository(agent.observedContext(ctx), name)
+	ctx, tracker := readlimit.Track(ctx)
+	obs := observer.FromContext(ctx)
+
+	obs.FetchStart(ctx, observer.FetchStartEvent{Repository: name})
+	start := time.Now()
+	var atts []attestation.Envelope
+	err := agent.checkOffline(r)
+	// Repositories are not queried once the read budget is exhausted
+	if err == nil && !readlimit.Stop(ctx, name) {
+		atts, err = agent.callRepo(ctx, r, opts, fetch)
+	}
+	latency := time.Since(start)
+
+	// Charge the envelopes the driver did not account for to the budget
+	if extra := len(atts) - tracker.Envelopes(); err == nil && extra > 0 {
+		keep := readlimit.ConsumeEnvelopes(ctx, name, extra)
+		atts = atts[:len(atts)-extra+keep]
+	}
+
+	obs.FetchEnd(ctx, observer.FetchEndEvent{
+		Repository: name, Count: len(atts), Latency: latency, Error: err,
+	})
+	return atts, RepositoryReport{
+		Repository: name,
+		Latency:    latency,
+		Error:      err,
+		Truncated:  tracker.Truncated(),
+	}
+}
+
+// observeCache sends the outcome of a cache lookup to the observer. A
+// lookup is a hit only when the cached data is used to answer the query.
+func (agent *Agent) observeCache(ctx context.Context, method string, hit bool) {
+	ctx = agent.observedContext(ctx)
+	e := observer.CacheEvent{Method: method}
+	if hit {
+		observer.FromContext(ctx).CacheHit(ctx, e)
+		return
+	}
+	observer.FromContext(ctx).CacheMiss(ctx, e)
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	intoto "github.com/in-toto/attestation/go/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/bare"
+	"github.com/carabiner-dev/collector/observer"
+)
+
+func TestObserver(t *testing.T) {
+	t.Parallel()
+	rec := observer.NewRecorder()
+	agent, err := New(WithObserver(rec))
+	require.NoError(t, err)
+
+	ok := &fakeFetcher{
+		fetchBySubjectFunc: func(ctx context.Context, _ attestation.FetchOptions, _ []attestation.Subject) ([]attestation.Envelope, error) {
+			// Drivers report through the context observer
+			observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{Source: "att.json", Count: 1})
+			return []attestation.Envelope{&bare.Envelope{}}, nil
+		},
+	}
+	require.NoError(t, agent.AddRepository(ok))
+	agent.setRepoInit(ok, "fake:ok")
+
+	subjects := []attestation.Subject{
+		&intoto.ResourceDescriptor{Digest: map[string]string{"sha256": "8b1a9953c4611296a827abf8c47804d7e6c49c6b0b1e1d5e8b0e0ea0eb7bd9a6"}},
+	}
+	_, err = agent.FetchAttestationsBySubject(t.Context(), subjects)
+	require.NoError(t, err)
+
+	require.Len(t, rec.CacheMisses, 1)
+	require.Equal(t, "FetchAttestationsBySubject", rec.CacheMisses[0].Method)
+	require.Len(t, rec.FetchStarts, 1)
+	require.Equal(t, "fake:ok", rec.FetchStarts[0].Repository)
+	require.Len(t, rec.FetchEnds, 1)
+	require.Equal(t, 1, rec.FetchEnds[0].Count)
+	require.Len(t, rec.ParsedEnvelopes, 1)
+	require.Equal(t, "fake:ok", rec.ParsedEnvelopes[0].Repository)
+
+	// The second call is served from the cache
+	_, err = agent.FetchAttestationsBySubject(t.Context(), subjects)
+	require.NoError(t, err)
+	require.Len(t, rec.CacheHits, 1)
+	require.Len(t, rec.FetchStarts, 1)
+
+	// Store results
+	require.NoError(t, agent.AddRepository(storerThatErrs(false), storerThatErrs(true)))
+	_, err = agent.StoreWithReport(t.Context(), []attestation.Envelope{&bare.Envelope{}})
+	require.Error(t, err)
+	require.Len(t, rec.StoreResults, 2)
+	failed := 0
+	for _, r := range rec.StoreResults {
+		if r.Error != nil {
+			failed++
+		}
+	}
+	require.Equal(t, 1, failed)
+}
+
+func TestObserverFromContext(t *testing.T) {
+	t.Parallel()
+	agent, err := New()
+	require.NoError(t, err)
+	require.NoError(t, agent.AddRepository(&fakeFetcher{
+		fetchFunc: func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+			return nil, errors.New("synth error")
+		},
+	}))
+
+	// Without an agent observer, the one in the call context is used
+	rec := observer.NewRecorder()
+	_, report, err := agent.FetchWithReport(observer.WithObserver(t.Context(), rec))
+	require.NoError(t, err)
+	require.Len(t, report.Failed(), 1)
+	require.Len(t, rec.FetchEnds, 1)
+	require.Error(t, rec.FetchEnds[0].Error)
+}
+
+func TestObserverRejectedCacheEntry(t *testing.T) {
+	t.Parallel()
+	rec := observer.NewRecorder()
+	agent, err := New(
+		WithObserver(rec),
+		WithCache(NewDiskCache(t.TempDir())),
+		WithVerification(VerificationPolicyDrop),
+	)
+	require.NoError(t, err)
+	require.NoError(t, agent.AddRepository(&fakeFetcher{
+		fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+			return []attestation.Envelope{}, nil
+		},
+	}))
+
+	// The cached envelope fails verification, the query is fetched again
+	ctx := withCacheScope(t.Context(), agent.cacheScope(agent.fetchOptions()))
+	require.NoError(t, agent.Cache.StoreAttestationsBySubject(ctx, cacheSubject("a"), &[]attestation.Envelope{testDSSE(testStatement)}))
+
+	_, err = agent.FetchAttestationsBySubject(t.Context(), cacheSubject("a"))
+	require.NoError(t, err)
+	require.Empty(t, rec.CacheHits)
+	require.Len(t, rec.CacheMisses, 1)
+	require.Len(t, rec.FetchStarts, 1)
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+// Package observer defines the hooks the collector agent and the repository
+// drivers call on key fetch and store events. Implement the Observer
+// interface to bridge the events to a metrics or tracing system.
+//
+// The agent passes its observer to the drivers in the context, drivers
+// get it with FromContext which never returns nil.
+package observer
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives the events of the collector agent and its drivers.
+// Implementations must be safe for concurrent use as repositories are
+// queried in parallel.
+type Observer interface {
+	// FetchStart is called before querying a repository.
+	FetchStart(context.Context, FetchStartEvent)
+
+	// FetchEnd is called when a repository returns.
+	FetchEnd(context.Context, FetchEndEvent)
+
+	// EnvelopeParsed is called by drivers when data read from a source
+	// is parsed into envelopes.
+	EnvelopeParsed(context.Context, EnvelopeParsedEvent)
+
+	// ParseSkipped is called by drivers when data read from a source is
+	// ignored, for example when it is not an attestation.
+	ParseSkipped(context.Context, ParseSkippedEvent)
+
+	// LimitHit is called when data from a source exceeds the read limit.
+	LimitHit(context.Context, LimitHitEvent)
+
+	// CacheHit and CacheMiss are called after looking up a request in the
+	// agent cache. Cached data that the agent discards, for example when
+	// none of the envelopes pass verification, counts as a miss.
+	CacheHit(context.Context, CacheEvent)
+	CacheMiss(context.Context, CacheEvent)
+
+	// StoreResult is called when a storer returns.
+	StoreResult(context.Context, StoreResultEvent)
+}
+
+// FetchStartEvent is sent before querying a repository.
+type FetchStartEvent struct {
+	Repository string
+}
+
+// FetchEndEvent is sent when a repository returns.
+type FetchEndEvent struct {
+	Repository string
+	// Count is the number of envelopes returned by the repository.
+	Count   int
+	Latency time.Duration
+	Error   error
+}
+
+// EnvelopeParsedEvent is sent when data from a source is parsed.
+type EnvelopeParsedEvent struct {
+	Repository string
+	// Source identifies the data parsed (a path, a URL, an image reference).
+	Source string
+	// Count is the number of envelopes found in the data.
+	Count int
+	// Bytes is the size of the parsed data, 0 if unknown.
+	Bytes int64
+}
+
+// ParseSkippedEvent is sent when data from a source is ignored.
+type ParseSkippedEvent struct {
+	Repository string
+	Source     string
+	Reason     string
+	Error      error
+}
+
+// LimitHitEvent is sent when data from a source exceeds the read limit.
+type LimitHitEvent struct {
+	Repository string
+	Source     string
+	// Limit is the maximum read size in bytes.
+	Limit int64
+	// Size is the size of the data, 0 if unknown.
+	Size int64
+}
+
+// CacheEvent is sent after looking up a request in the agent cache.
+type CacheEvent struct {
+	// Method is the agent method that queried the cache, for example
+	// FetchAttestationsBySubject.
+	Method string
+}
+
+// StoreResultEvent is sent when a storer returns.
+type StoreResultEvent struct {
+	Repository string
+	// Count is the number of envelopes sent to the storer.
+	Count   int
+	Latency time.Duration
+	Error   error
+}
+
+// Nop is an observer that discards all events.
+type Nop struct{}
+
+var _ Observer = Nop{}
+
+func (Nop) FetchStart(context.Context, FetchStartEvent)         {}
+func (Nop) FetchEnd(context.Context, FetchEndEvent)             {}
+func (Nop) EnvelopeParsed(context.Context, EnvelopeParsedEvent) {}
+func (Nop) ParseSkipped(context.Context, ParseSkippedEvent)     {}
+func (Nop) LimitHit(context.Context, LimitHitEvent)             {}
+func (Nop) CacheHit(context.Context, CacheEvent)                {}
+func (Nop) CacheMiss(context.Context, CacheEvent)               {}
+func (Nop) StoreResult(context.Context, StoreResultEvent)       {}
+
+type contextKey struct{}
+
+// scoped is the observer stored in contexts. It completes the repository
+// of the driver events.
+type scoped struct {
+	Observer
+	repository string
+}
+
+func (s *scoped) EnvelopeParsed(ctx context.Context, e EnvelopeParsedEvent) {
+	if e.Repository == "" {
+		e.Repository = s.repository
+	}
+	s.Observer.EnvelopeParsed(ctx, e)
+}
+
+func (s *scoped) ParseSkipped(ctx context.Context, e ParseSkippedEvent) {
+	if e.Repository == "" {
+		e.Repository = s.repository
+	}
+	s.Observer.ParseSkipped(ctx, e)
+}
+
+func (s *scoped) LimitHit(ctx context.Context, e LimitHitEvent) {
+	if e.Repository == "" {
+		e.Repository = s.repository
+	}
+	s.Observer.LimitHit(ctx, e)
+}
+
+// WithObserver returns a context carrying the observer.
+func WithObserver(ctx context.Context, o Observer) context.Context {
+	if o == nil {
+		o = Nop{}
+	}
+	return context.WithValue(ctx, contextKey{}, &scoped{Observer: o})
+}
+
+// WithRepository returns a context that sets the repository name in the
+// events drivers send through the context observer.
+func WithRepository(ctx context.Context, repository string) context.Context {
+	s, ok := ctx.Value(contextKey{}).(*scoped)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, &scoped{Observer: s.Observer, repository: repository})
+}
+
+// FromContext returns the observer in the context or a Nop observer if the
+// context has none.
+func FromContext(ctx context.Context) Observer {
+	if s, ok := ctx.Value(contextKey{}).(*scoped); ok {
+		return s
+	}
+	return Nop{}
+}
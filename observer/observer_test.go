@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package observer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+	// Contexts without an observer get a no-op one
+	require.Equal(t, Nop{}, FromContext(t.Context()))
+	require.Equal(t, t.Context(), WithRepository(t.Context(), "fs:."))
+
+	rec := NewRecorder()
+	ctx := WithObserver(t.Context(), rec)
+	FromContext(ctx).EnvelopeParsed(ctx, EnvelopeParsedEvent{Source: "a.json"})
+
+	// Driver events get the repository from the context
+	rctx := WithRepository(ctx, "fs:.")
+	FromContext(rctx).EnvelopeParsed(rctx, EnvelopeParsedEvent{Source: "b.json"})
+	FromContext(rctx).ParseSkipped(rctx, ParseSkippedEvent{Source: "c.txt", Reason: "unparseable"})
+	FromContext(rctx).LimitHit(rctx, LimitHitEvent{Repository: "other", Limit: 10})
+	FromContext(rctx).CacheHit(rctx, CacheEvent{Method: "FetchAttestationsBySubject"})
+
+	require.Len(t, rec.ParsedEnvelopes, 2)
+	require.Empty(t, rec.ParsedEnvelopes[0].Repository)
+	require.Equal(t, "fs:.", rec.ParsedEnvelopes[1].Repository)
+	require.Equal(t, "fs:.", rec.SkippedParses[0].Repository)
+	require.Equal(t, "other", rec.LimitHits[0].Repository)
+	require.Len(t, rec.CacheHits, 1)
+
+	rec.Reset()
+	require.Empty(t, rec.ParsedEnvelopes)
+	require.Empty(t, rec.CacheHits)
+}
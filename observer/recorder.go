@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package observer
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Observer = (*Recorder)(nil)
+
+// Recorder is an observer that keeps all the events it receives in memory.
+// It is useful in tests and to inspect a single agent call.
+type Recorder struct {
+	mtx             sync.Mutex
+	FetchStarts     []FetchStartEvent
+	FetchEnds       []FetchEndEvent
+	ParsedEnvelopes []EnvelopeParsedEvent
+	SkippedParses   []ParseSkippedEvent
+	LimitHits       []LimitHitEvent
+	CacheHits       []CacheEvent
+	CacheMisses     []CacheEvent
+	StoreResults    []StoreResultEvent
+}
+
+// NewRecorder returns a new, empty recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) FetchStart(_ context.Context, e FetchStartEvent) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.FetchStarts = append(r.FetchStarts, e)
+}
+
+func (r *Recorder) FetchEnd(_ context.Context, e FetchEndEvent) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.FetchEnds = append(r.FetchEnds, e)
+}
+
+func (r *Recorder) EnvelopeParsed(_ context.Context, e EnvelopeParsedEvent) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.ParsedEnvelopes = append(r.ParsedEnvelopes, e)
+}
+
+func (r *Recorder) ParseSkipped(_ context.Context, e ParseSkippedEvent) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.SkippedParses = append(r.SkippedParses, e)
+}
+
+func (r *Recorder) LimitHit(_ context.Context, e LimitHitEvent) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.LimitHits = append(r.LimitHits, e)
+}
+
+func (r *Recorder) CacheHit(_ context.Context, e CacheEvent) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.CacheHits = append(r.CacheHits, e)
+}
+
+func (r *Recorder) CacheMiss(_ context.Context, e CacheEvent) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.CacheMisses = append(r.CacheMisses, e)
+}
+
+func (r *Recorder) StoreResult(_ context.Context, e StoreResultEvent) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.StoreResults = append(r.StoreResults, e)
+}
+
+// Reset discards all the recorded events.
+func (r *Recorder) Reset() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.FetchStarts = nil
+	r.FetchEnds = nil
+	r.ParsedEnvelopes = nil
+	r.SkippedParses = nil
+	r.LimitHits = nil
+	r.CacheHits = nil
+	r.CacheMisses = nil
+	r.StoreResults = nil
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	sigstoreProtoDSSE "github.com/sigstore/protobuf-specs/gen/pb-go/dsse"
+	"github.com/stretchr/testify/require"
+)
+
+// offlineFetcher is a fetcher supporting offline mode
+type offlineFetcher struct {
+	fakeFetcher
+	offline bool
+}
+
+func (of *offlineFetcher) SetOffline(offline bool) {
+	of.offline = offline
+}
+
+func TestOfflineFetch(t *testing.T) {
+	t.Parallel()
+	fetch := func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+		return []attestation.Envelope{testDSSE(testStatement)}, nil
+	}
+
+	for _, tc := range []struct {
+		name    string
+		offline bool
+		expect  int
+		failed  int
+	}{
+		{"online", false, 2, 0},
+		{"offline", true, 1, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			remote := &fakeFetcher{fetchFunc: fetch}
+			local := &offlineFetcher{fakeFetcher: fakeFetcher{fetchFunc: fetch}}
+			agent, err := New(WithOffline(tc.offline), WithRepository(remote), WithRepository(local))
+			require.NoError(t, err)
+			require.Equal(t, tc.offline, local.offline)
+
+			atts, report, err := agent.FetchWithReport(t.Context())
+			require.NoError(t, err)
+			require.Len(t, atts, tc.expect)
+			require.Len(t, report.Failed(), tc.failed)
+			if tc.failed > 0 {
+				require.ErrorIs(t, report.Err(), ErrOfflineMode)
+			}
+		})
+	}
+}
+
+func TestOfflineStore(t *testing.T) {
+	t.Parallel()
+	agent, err := New(WithOffline(true), WithRepository(storerThatErrs(false)))
+	require.NoError(t, err)
+
+	err = agent.Store(t.Context(), []attestation.Envelope{testDSSE(testStatement)})
+	require.ErrorIs(t, err, ErrOfflineMode)
+}
+
+func TestOfflineVerification(t *testing.T) {
+	t.Parallel()
+	agent, err := New(WithOffline(true), WithVerification(VerificationPolicyAnnotate))
+	require.NoError(t, err)
+
+	// Without a trusted root, bundles can't be verified offline
+	b := testBundle(testStatement, true)
+	b.GetDsseEnvelope().Signatures = []*sigstoreProtoDSSE.Signature{{Sig: []byte("signature")}}
+	require.Contains(t, agent.verifyEnvelope(b), ErrOfflineMode.Error())
+}
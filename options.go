@@ -9,6 +9,9 @@ import (
 
 	"github.com/carabiner-dev/attestation"
 	"github.com/carabiner-dev/signer/key"
+	"github.com/sigstore/sigstore-go/pkg/root"
+
+	"github.com/carabiner-dev/collector/observer"
 )
 
 // DefaultMaxReadSize is the default maximum number of bytes the collector will
@@ -16,14 +19,18 @@ import (
 const DefaultMaxReadSize int64 = 7 << 20
 
 var defaultOptions = Options{
-	UserAgentString:  "carabiner-collector/v1",
-	FailIfNoFetchers: false,
-	UseCache:         true,
-	ParallelFetches:  4,
-	ParallelStores:   4,
-	MaxReadSize:      DefaultMaxReadSize,
-	Fetch:            attestation.FetchOptions{},
-	Store:            attestation.StoreOptions{},
+	UserAgentString:    "carabiner-collector/v1",
+	FailIfNoFetchers:   false,
+	UseCache:           true,
+	ParallelFetches:    4,
+	ParallelStores:     4,
+	StorePolicy:        StorePolicyAllOrNothing,
+	VerificationPolicy: VerificationPolicyKeep,
+	MaxReadSize:        DefaultMaxReadSize,
+	MaxCrawlNodes:      DefaultMaxCrawlNodes,
+	SortOrder:          SortByRepository,
+	Fetch:              attestation.FetchOptions{},
+	Store:              attestation.StoreOptions{},
 }
 
 // Options groups the configuration knob for the collector agent
@@ -36,20 +43,86 @@ type Options struct {
 	// Use cache controls if the agent uses the attestation cache
 	UseCache bool
 
+	// ParallelFetches and ParallelStores are the number of repositories
+	// queried or written to at the same time. Values under 1 are treated
+	// as 1.
 	ParallelFetches int
 	ParallelStores  int
 
+	// StorePolicy defines when a store operation is considered successful
+	// when some of the storer repositories fail.
+	StorePolicy StorePolicy
+
+	// StoreQuorum is the number of storers that need to accept the
+	// attestations for a store to succeed when using StorePolicyQuorum.
+	// Zero means a majority of the storers.
+	StoreQuorum int
+
 	// MaxReadSize is the maximum number of bytes the collector will read from
 	// any single external source (HTTP response, file, OCI blob, etc.).
 	// A value of 0 means no limit. Defaults to DefaultMaxReadSize (7 MiB).
 	MaxReadSize int64
 
+	// MaxFetchBytes and MaxFetchEnvelopes are the read budget of a fetch:
+	// the total bytes read and envelopes parsed by all the repositories
+	// queried in a single call. Drivers stop reading when it runs out and
+	// the truncated sources are listed in the fetch report. A value of 0
+	// means no limit.
+	MaxFetchBytes     int64
+	MaxFetchEnvelopes int
+
+	// MaxCrawlNodes is the maximum number of subjects visited by Crawl.
+	// A value of 0 means no limit. Defaults to DefaultMaxCrawlNodes.
+	MaxCrawlNodes int
+
 	Fetch attestation.FetchOptions
 	Store attestation.StoreOptions
 
+	// StoreRoutes decide which envelopes are sent to each storer. Storers
+	// without routes receive all envelopes.
+	StoreRoutes []StoreRoute
+
+	// SortOrder defines the order of the envelopes returned by the fetch
+	// methods. The limit is applied after sorting. Defaults to
+	// SortByRepository.
+	SortOrder SortOrder
+
+	// Deduplicate controls if the agent collapses envelopes with the same
+	// signed payload or statement returned by different repositories.
+	Deduplicate bool
+
 	// Keys are verification keys that the agent distributes to repositories
 	// implementing the repository.SignatureVerifier interface.
 	Keys []key.PublicKeyProvider
+
+	// VerifySignatures makes the agent verify the signatures of the fetched
+	// envelopes and attach the results to them.
+	VerifySignatures bool
+
+	// VerificationPolicy defines what happens to envelopes that are unsigned
+	// or fail verification. Defaults to VerificationPolicyKeep.
+	VerificationPolicy VerificationPolicy
+
+	// TrustedRoot is the sigstore trust root used to verify bundles. When
+	// nil, bundles are verified against the sigstore public good instance.
+	// The agent also sends it to repositories implementing the
+	// repository.TrustedRootSetter interface.
+	TrustedRoot root.TrustedMaterial
+
+	// Offline forbids network access. Repositories implementing the
+	// repository.OfflineAware interface are switched to offline mode, the
+	// rest fail with ErrOfflineMode. Sigstore bundles can only be verified
+	// with a TrustedRoot.
+	Offline bool
+
+	// RepositoryPolicy is the default timeout, rate limit and retry policy
+	// applied when fetching from the repositories. Use SetRepositoryPolicy
+	// to set the policy of specific repositories.
+	RepositoryPolicy RepositoryPolicy
+
+	// Observer receives the fetch, cache and store events of the agent and
+	// its repositories. When nil, the observer in the call context is used.
+	Observer observer.Observer
 }
 
 type InitFunction func(*Agent) error
@@ -60,6 +133,19 @@ func WithRepository(repo attestation.Repository) InitFunction {
 	}
 }
 
+// WithCache sets the cache of the agent and enables caching. For example,
+// to keep the results across runs use WithCache(NewDiskCache("")).
+func WithCache(cache Cache) InitFunction {
+	return func(agent *Agent) error {
+		if cache == nil {
+			return fmt.Errorf("cache is nil")
+		}
+		agent.Cache = cache
+		agent.Options.UseCache = true
+		return nil
+	}
+}
+
 func WithParallelFetches(threads int) InitFunction {
 	return func(agent *Agent) error {
 		agent.Options.ParallelFetches = threads
@@ -74,6 +160,47 @@ func WithParallelStores(threads int) InitFunction {
 	}
 }
 
+// WithStorePolicy sets the policy that determines if a store operation
+// succeeded when some of the storer repositories fail.
+func WithStorePolicy(policy StorePolicy) InitFunction {
+	return func(agent *Agent) error {
+		switch policy {
+		case StorePolicyAllOrNothing, StorePolicyBestEffort, StorePolicyQuorum:
+			agent.Options.StorePolicy = policy
+			return nil
+		default:
+			return fmt.Errorf("unknown store policy %q", policy)
+		}
+	}
+}
+
+// WithStoreQuorum sets the store policy to StorePolicyQuorum, requiring at
+// least n storers to accept the attestations for a store to succeed.
+func WithStoreQuorum(n int) InitFunction {
+	return func(agent *Agent) error {
+		if n < 1 {
+			return fmt.Errorf("store quorum must be at least 1")
+		}
+		agent.Options.StorePolicy = StorePolicyQuorum
+		agent.Options.StoreQuorum = n
+		return nil
+	}
+}
+
+// WithStoreRoutes adds routing rules that decide which envelopes are sent to
+// each storer repository when storing attestations.
+func WithStoreRoutes(routes ...StoreRoute) InitFunction {
+	return func(agent *Agent) error {
+		for i := range routes {
+			if err := routes[i].Validate(); err != nil {
+				return fmt.Errorf("invalid store route #%d: %w", i, err)
+			}
+		}
+		agent.Options.StoreRoutes = append(agent.Options.StoreRoutes, routes...)
+		return nil
+	}
+}
+
 // WithMaxReadSize sets the maximum number of bytes the collector will read from
 // any single external source. A value of 0 means no limit.
 func WithMaxReadSize(n int64) InitFunction {
@@ -83,6 +210,110 @@ func WithMaxReadSize(n int64) InitFunction {
 	}
 }
 
+// WithMaxCrawlNodes sets the maximum number of subjects visited when
+// crawling the supply chain graph. A value of 0 means no limit.
+func WithMaxCrawlNodes(n int) InitFunction {
+	return func(agent *Agent) error {
+		if n < 0 {
+			return fmt.Errorf("maximum crawl nodes cannot be negative")
+		}
+		agent.Options.MaxCrawlNodes = n
+		return nil
+	}
+}
+
+// WithObserver sets the observer that receives the agent events.
+func WithObserver(o observer.Observer) InitFunction {
+	return func(agent *Agent) error {
+		agent.Options.Observer = o
+		return nil
+	}
+}
+
+// WithSortOrder sets the order of the envelopes returned by the agent.
+func WithSortOrder(order SortOrder) InitFunction {
+	return func(agent *Agent) error {
+		switch order {
+		case SortByRepository, SortByDigest, SortNewestFirst, SortByPredicateType:
+			agent.Options.SortOrder = order
+			return nil
+		default:
+			return fmt.Errorf("unknown sort order %q", order)
+		}
+	}
+}
+
+// WithDeduplication makes the agent collapse envelopes carrying the same
+// signed payload or statement. Of each set of duplicates, the best verified
+// copy is returned with the sources of all copies merged into it.
+func WithDeduplication(dedup bool) InitFunction {
+	return func(agent *Agent) error {
+		agent.Options.Deduplicate = dedup
+		return nil
+	}
+}
+
+// WithVerification makes the agent verify the signatures of the envelopes
+// it fetches. The policy defines what to do with envelopes that are
+// unsigned or fail verification.
+func WithVerification(policy VerificationPolicy) InitFunction {
+	return func(agent *Agent) error {
+		switch policy {
+		case VerificationPolicyKeep, VerificationPolicyAnnotate, VerificationPolicyDrop:
+			agent.Options.VerifySignatures = true
+			agent.Options.VerificationPolicy = policy
+			return nil
+		default:
+			return fmt.Errorf("unknown verification policy %q", policy)
+		}
+	}
+}
+
+// WithTrustedRoot sets the sigstore trust root used to verify bundles.
+func WithTrustedRoot(tm root.TrustedMaterial) InitFunction {
+	return func(agent *Agent) error {
+		agent.Options.TrustedRoot = tm
+		return nil
+	}
+}
+
+// WithTrustedRootFile reads the sigstore trust root used to verify bundles
+// from a trusted_root.json file.
+func WithTrustedRootFile(path string) InitFunction {
+	return func(agent *Agent) error {
+		tm, err := loadTrustedRoot(path)
+		if err != nil {
+			return err
+		}
+		agent.Options.TrustedRoot = tm
+		return nil
+	}
+}
+
+// WithReadBudget sets the read budget of each fetch call: the total bytes
+// read and envelopes parsed across all repositories. A value of 0 means no
+// limit.
+func WithReadBudget(maxBytes int64, maxEnvelopes int) InitFunction {
+	return func(agent *Agent) error {
+		if maxBytes < 0 || maxEnvelopes < 0 {
+			return fmt.Errorf("read budget cannot be negative")
+		}
+		agent.Options.MaxFetchBytes = maxBytes
+		agent.Options.MaxFetchEnvelopes = maxEnvelopes
+		return nil
+	}
+}
+
+// WithOffline runs the agent in offline mode, where no repository can access
+// the network. Combine it with WithTrustedRootFile to verify sigstore
+// signatures.
+func WithOffline(offline bool) InitFunction {
+	return func(agent *Agent) error {
+		agent.Options.Offline = offline
+		return nil
+	}
+}
+
 // FetchOptionsFunc are functions to define options when fetching
 type FetchOptionsFunc func(*attestation.FetchOptions)
 
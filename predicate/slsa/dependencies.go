@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package slsa
+
+import (
+	"github.com/carabiner-dev/attestation"
+
+	v02 "github.com/carabiner-dev/collector/predicate/slsa/provenance/v02"
+	v10 "github.com/carabiner-dev/collector/predicate/slsa/provenance/v10"
+	v11 "github.com/carabiner-dev/collector/predicate/slsa/provenance/v11"
+)
+
+// Dependencies returns the artifacts a build consumed as recorded in a SLSA
+// provenance predicate: the materials in v0.2 and the resolved dependencies
+// in v1.0 and v1.1. It returns nil if the predicate is not SLSA provenance.
+func Dependencies(pred attestation.Predicate) []attestation.Subject {
+	if pred == nil {
+		return nil
+	}
+
+	ret := []attestation.Subject{}
+	switch prov := pred.GetParsed().(type) {
+	case *v02.Provenance:
+		for _, m := range prov.GetMaterials() {
+			ret = append(ret, m)
+		}
+	case *v10.Provenance:
+		for _, rd := range prov.GetBuildDefinition().GetResolvedDependencies() {
+			ret = append(ret, rd)
+		}
+	case *v11.Provenance:
+		for _, rd := range prov.GetBuildDefinition().GetResolvedDependencies() {
+			ret = append(ret, rd)
+		}
+	default:
+		return nil
+	}
+	return ret
+}
@@ -7,7 +7,10 @@ import (
 	"os"
 	"testing"
 
+	"github.com/carabiner-dev/attestation"
 	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/predicate/generic"
 )
 
 func TestParseV02(t *testing.T) {
@@ -33,3 +36,58 @@ func TestParseV02(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestDependencies(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name   string
+		parse  func([]byte) (attestation.Predicate, error)
+		data   string
+		expect []string
+	}{
+		{
+			name:   "v0.2-materials",
+			parse:  parseProvenanceV02,
+			data:   `{"materials":[{"uri":"git+https://example.com/repo","digest":{"sha1":"abc"}},{"uri":"https://example.com/runner"}]}`,
+			expect: []string{"git+https://example.com/repo", "https://example.com/runner"},
+		},
+		{
+			name:   "v1.0-resolved-dependencies",
+			parse:  parseProvenanceV10,
+			data:   `{"buildDefinition":{"buildType":"test","resolvedDependencies":[{"uri":"git+https://example.com/repo","digest":{"gitCommit":"abc"}}]}}`,
+			expect: []string{"git+https://example.com/repo"},
+		},
+		{
+			name:   "v1.1-resolved-dependencies",
+			parse:  parseProvenanceV11,
+			data:   `{"buildDefinition":{"buildType":"test","resolvedDependencies":[{"name":"dep","digest":{"sha256":"abc"}}]}}`,
+			expect: []string{"dep"},
+		},
+		{
+			name:   "v1.0-no-dependencies",
+			parse:  parseProvenanceV10,
+			data:   `{"buildDefinition":{"buildType":"test"}}`,
+			expect: []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pred, err := tc.parse([]byte(tc.data))
+			require.NoError(t, err)
+			deps := Dependencies(pred)
+			require.NotNil(t, deps)
+			names := []string{}
+			for _, d := range deps {
+				if d.GetUri() != "" {
+					names = append(names, d.GetUri())
+				} else {
+					names = append(names, d.GetName())
+				}
+			}
+			require.Equal(t, tc.expect, names)
+		})
+	}
+
+	require.Nil(t, Dependencies(nil))
+	require.Nil(t, Dependencies(&generic.Predicate{Parsed: map[string]any{}}))
+}
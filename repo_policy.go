@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/cenkalti/backoff/v5"
+
+	"github.com/carabiner-dev/collector/internal/ratelimit"
+	"github.com/carabiner-dev/collector/internal/readlimit"
+)
+
+// RepositoryPolicy controls how the agent calls a repository when fetching.
+// The zero value applies no timeout, rate limit or retries.
+type RepositoryPolicy struct {
+	// Timeout limits the time of each fetch attempt. Zero means no timeout.
+	Timeout time.Duration
+
+	// RateLimit is the maximum number of fetch calls per second sent to the
+	// repository, including retries. Zero means no limit. Drivers may make
+	// several requests to their backend on each call.
+	RateLimit float64
+
+	// Retries is the number of times a failed fetch is retried.
+	Retries uint
+
+	// InitialBackoff and MaxBackoff bound the exponential backoff between
+	// retries. When zero, the backoff library defaults are used.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Validate checks the policy settings.
+func (p *RepositoryPolicy) Validate() error {
+	errs := []error{}
+	if p.Timeout < 0 {
+		errs = append(errs, errors.New("timeout cannot be negative"))
+	}
+	if p.RateLimit < 0 {
+		errs = append(errs, errors.New("rate limit cannot be negative"))
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		errs = append(errs, errors.New("backoff intervals cannot be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// repoPolicy is a policy with the state needed to enforce it.
+type repoPolicy struct {
+	RepositoryPolicy
+	limiter *ratelimit.Limiter
+}
+
+func newRepoPolicy(p RepositoryPolicy) *repoPolicy {
+	return &repoPolicy{RepositoryPolicy: p, limiter: ratelimit.New(p.RateLimit)}
+}
+
+// WithRepositoryPolicy sets the policy used to call the repositories. When
+// no repositories are passed, it sets the default policy of the agent used
+// for all the repositories without their own.
+func WithRepositoryPolicy(policy RepositoryPolicy, repos ...attestation.Repository) InitFunction {
+	return func(agent *Agent) error {
+		return agent.SetRepositoryPolicy(policy, repos...)
+	}
+}
+
+// SetRepositoryPolicy sets the policy used to call the repositories. When no
+// repositories are passed, it sets the default policy of the agent used for
+// all the repositories without their own.
+func (agent *Agent) SetRepositoryPolicy(policy RepositoryPolicy, repos ...attestation.Repository) error {
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid repository policy: %w", err)
+	}
+
+	agent.initsMutex.Lock()
+	defer agent.initsMutex.Unlock()
+	if len(repos) == 0 {
+		agent.Options.RepositoryPolicy = policy
+		agent.defaultPolicy = newRepoPolicy(policy)
+		return nil
+	}
+
+	if agent.repoPolicies == nil {
+		agent.repoPolicies = map[any]*repoPolicy{}
+	}
+	for _, r := range repos {
+		if !reflect.TypeOf(r).Comparable() {
+			continue
+		}
+		// Each repository gets its own limiter
+		agent.repoPolicies[r] = newRepoPolicy(policy)
+	}
+	return nil
+}
+
+// policyFor returns the policy of a repository or the agent default.
+func (agent *Agent) policyFor(r any) *repoPolicy {
+	agent.initsMutex.Lock()
+	defer agent.initsMutex.Unlock()
+	if reflect.TypeOf(r).Comparable() {
+		if p, ok := agent.repoPolicies[r]; ok {
+			return p
+		}
+	}
+
+	// The default policy may have been set directly in the options
+	if agent.defaultPolicy == nil || agent.defaultPolicy.RepositoryPolicy != agent.Options.RepositoryPolicy {
+		agent.defaultPolicy = newRepoPolicy(agent.Options.RepositoryPolicy)
+	}
+	return agent.defaultPolicy
+}
+
+// callRepo runs fetch on a repository enforcing its policy: each attempt is
+// rate limited and subject to the timeout, failed attempts are retried with
+// exponential backoff. Unimplemented methods and canceled contexts are not
+// retried.
+//
+// Each attempt reserves its reads from the read budget in the context. The
+// reads of failed attempts are refunded so they don't use the budget left
+// for the retries and the other repositories.
+func (agent *Agent) callRepo(ctx context.Context, r attestation.Fetcher, opts attestation.FetchOptions, fetch repoFetchFunc) ([]attestation.Envelope, error) {
+	p := agent.policyFor(r)
+
+	attempt := func() ([]attestation.Envelope, error) {
+		if err := p.limiter.Wait(ctx); err != nil {
+			return nil, backoff.Permanent(err)
+		}
+		actx, reservation := readlimit.Reserve(ctx)
+		var atts []attestation.Envelope
+		var err error
+		if p.Timeout > 0 {
+			var cancel context.CancelFunc
+			actx, cancel = context.WithTimeout(actx, p.Timeout)
+			defer cancel()
+			atts, err = fetchWithDeadline(actx, r, opts, fetch)
+		} else {
+			atts, err = fetch(actx, r, opts)
+		}
+		if err == nil {
+			reservation.Commit()
+			return atts, nil
+		}
+		reservation.Refund()
+		if errors.Is(err, attestation.ErrFetcherMethodNotImplemented) || ctx.Err() != nil {
+			return nil, backoff.Permanent(err)
+		}
+		if actx.Err() != nil {
+			return nil, fmt.Errorf("fetch timed out after %s: %w", p.Timeout, err)
+		}
+		return nil, err
+	}
+
+	b := backoff.NewExponentialBackOff()
+	if p.InitialBackoff > 0 {
+		b.InitialInterval = p.InitialBackoff
+	}
+	if p.MaxBackoff > 0 {
+		b.MaxInterval = p.MaxBackoff
+	}
+	atts, err := backoff.Retry(ctx, attempt,
+		backoff.WithBackOff(b),
+		backoff.WithMaxTries(p.Retries+1),
+		backoff.WithMaxElapsedTime(0),
+	)
+
+	// The last attempt may return a permanent error without unwrapping
+	var perr *backoff.PermanentError
+	if errors.As(err, &perr) {
+		err = perr.Unwrap()
+	}
+	return atts, err
+}
+
+// fetchWithDeadline runs fetch and returns when it finishes or when the
+// context expires, even if the driver does not honor the context. In that
+// case the driver call is abandoned and its context is canceled to make
+// the driver stop.
+func fetchWithDeadline(ctx context.Context, r attestation.Fetcher, opts attestation.FetchOptions, fetch repoFetchFunc) ([]attestation.Envelope, error) {
+	type result struct {
+		atts []attestation.Envelope
+		err  error
+	}
+	fctx, cancel := context.WithCancel(ctx)
+	ch := make(chan result, 1)
+	go func() {
+		atts, err := fetch(fctx, r, opts)
+		ch <- result{atts, err}
+	}()
+	select {
+	case res := <-ch:
+		cancel()
+		return res.atts, res.err
+	case <-ctx.Done():
+		cancel()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/internal/readlimit"
+)
+
+// flakyFetcher fails the first calls and then returns an envelope. When
+// hang is set, it blocks ignoring the context.
+type flakyFetcher struct {
+	failures int32
+	hang     chan struct{}
+	calls    atomic.Int32
+}
+
+func (ff *flakyFetcher) Fetch(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+	n := ff.calls.Add(1)
+	if ff.hang != nil {
+		<-ff.hang
+	}
+	if n <= ff.failures {
+		return nil, errors.New("transient error")
+	}
+	return []attestation.Envelope{testDSSE(testStatement)}, nil
+}
+
+func TestRepositoryPolicy(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		policy  RepositoryPolicy
+		repo    func() *flakyFetcher
+		calls   int32
+		mustErr string
+	}{
+		{"no-retries", RepositoryPolicy{}, func() *flakyFetcher { return &flakyFetcher{failures: 1} }, 1, "transient"},
+		{"retried", RepositoryPolicy{Retries: 2, InitialBackoff: time.Millisecond}, func() *flakyFetcher { return &flakyFetcher{failures: 2} }, 3, ""},
+		{"retries-exhausted", RepositoryPolicy{Retries: 1, InitialBackoff: time.Millisecond}, func() *flakyFetcher { return &flakyFetcher{failures: 5} }, 2, "transient"},
+		{"timeout", RepositoryPolicy{Timeout: 10 * time.Millisecond}, func() *flakyFetcher { return &flakyFetcher{hang: make(chan struct{})} }, 1, "deadline exceeded"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			repo := tc.repo()
+			if repo.hang != nil {
+				defer close(repo.hang)
+			}
+			agent, err := New(WithRepository(repo), WithRepositoryPolicy(tc.policy, repo))
+			require.NoError(t, err)
+
+			res, err := agent.Fetch(t.Context())
+			require.Equal(t, tc.calls, repo.calls.Load())
+			if tc.mustErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.mustErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+		})
+	}
+
+	_, err := New(WithRepositoryPolicy(RepositoryPolicy{Timeout: -1}))
+	require.Error(t, err)
+}
+
+func TestRepositoryPolicyDefault(t *testing.T) {
+	t.Parallel()
+	repo := &flakyFetcher{failures: 1}
+	agent, err := New(
+		WithRepository(repo),
+		WithRepositoryPolicy(RepositoryPolicy{Retries: 1, InitialBackoff: time.Millisecond}),
+	)
+	require.NoError(t, err)
+	_, err = agent.Fetch(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, int32(2), repo.calls.Load())
+}
+
+func TestRepositoryPolicyReadBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("retries", func(t *testing.T) {
+		t.Parallel()
+		// Each attempt reads 6 bytes of a budget of 10
+		var calls atomic.Int32
+		repo := &fakeFetcher{fetchFunc: func(ctx context.Context, _ attestation.FetchOptions) ([]attestation.Envelope, error) {
+			if !readlimit.Consume(ctx, "att.json", 6) {
+				return nil, errors.New("budget exhausted")
+			}
+			if calls.Add(1) == 1 {
+				return nil, errors.New("transient error")
+			}
+			return []attestation.Envelope{testDSSE(testStatement)}, nil
+		}}
+		agent, err := New(
+			WithRepository(repo), WithReadBudget(10, 0),
+			WithRepositoryPolicy(RepositoryPolicy{Retries: 1, InitialBackoff: time.Millisecond}, repo),
+		)
+		require.NoError(t, err)
+
+		res, report, err := agent.FetchWithReport(t.Context())
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		require.Equal(t, int32(2), calls.Load())
+		require.Empty(t, report.Truncated())
+	})
+
+	t.Run("abandoned", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		read := make(chan bool, 1)
+		canceled := make(chan bool, 1)
+		repo := &fakeFetcher{fetchFunc: func(ctx context.Context, _ attestation.FetchOptions) ([]attestation.Envelope, error) {
+			// The driver ignores the context until released
+			<-release
+			canceled <- ctx.Err() != nil
+			read <- readlimit.Consume(ctx, "att.json", 1)
+			return nil, nil
+		}}
+		agent, err := New(
+			WithRepository(repo), WithReadBudget(10, 0),
+			WithRepositoryPolicy(RepositoryPolicy{Timeout: 10 * time.Millisecond}, repo),
+		)
+		require.NoError(t, err)
+
+		_, err = agent.Fetch(t.Context())
+		require.Error(t, err)
+		close(release)
+		require.True(t, <-canceled)
+		require.False(t, <-read)
+	})
+}
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+)
+
+// FetchReport records the outcome of a fetch operation in each of the
+// queried repositories. It lets callers decide if a partial answer (some
+// repositories failed while others returned data) is acceptable.
+type FetchReport struct {
+	// Repositories has one entry per repository, in the order they are
+	// registered in the agent. Repositories in priority tiers that were
+	// not queried are marked as skipped.
+	Repositories []RepositoryReport
+
+	// FromCache is true when the results were served from the agent cache
+	// and no repository was queried.
+	FromCache bool
+}
+
+// RepositoryReport is the result of querying (or writing to) a single
+// repository.
+type RepositoryReport struct {
+	// Repository identifies the repository by its init string or, when the
+	// repository was not built from one, by its type moniker.
+	Repository string
+
+	// Count is the number of attestations returned by the repository,
+	// before applying any query or limit. In store reports, it is the
+	// number of attestations sent to the repository.
+	Count int
+
+	// Latency is the time the repository took to respond.
+	Latency time.Duration
+
+	// Error is the error returned by the repository, if any.
+	Error error
+
+	// Skipped is true when the repository was not queried because a
+	// higher priority tier answered the fetch.
+	Skipped bool
+
+	// Truncated lists the sources (files, URLs, image layers) that were not
+	// read completely, or where the repository stopped reading, because the
+	// fetch ran out of read budget. When the driver does not track its
+	// sources or the repository was not queried, the repository is listed.
+	Truncated []string
+}
+
+// Failed returns the reports of the repositories that returned an error.
+func (r *FetchReport) Failed() []RepositoryReport {
+	ret := []RepositoryReport{}
+	if r == nil {
+		return ret
+	}
+	for _, rr := range r.Repositories {
+		if rr.Error != nil {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// Truncated returns the reports of the repositories that could not read
+// all their sources because the read budget ran out.
+func (r *FetchReport) Truncated() []RepositoryReport {
+	ret := []RepositoryReport{}
+	if r == nil {
+		return ret
+	}
+	for _, rr := range r.Repositories {
+		if len(rr.Truncated) > 0 {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// complete returns true when all the repositories returned their full
+// results. Only complete results are cached.
+func (r *FetchReport) complete() bool {
+	return len(r.Failed()) == 0 && len(r.Truncated()) == 0
+}
+
+// Partial returns true when at least one repository failed while others
+// answered successfully.
+func (r *FetchReport) Partial() bool {
+	if r == nil {
+		return false
+	}
+	failed := len(r.Failed())
+	return failed > 0 && failed < len(r.Repositories)
+}
+
+// Err returns an error joining the errors of all failed repositories or nil
+// if all repositories answered successfully.
+func (r *FetchReport) Err() error {
+	errs := []error{}
+	for _, rr := range r.Failed() {
+		errs = append(errs, fmt.Errorf("%s: %w", rr.Repository, rr.Error))
+	}
+	return errors.Join(errs...)
+}
+
+// repoName returns the string used to identify a repository in reports. It
+// is the init string when the repository was added from one. Otherwise, the
+// name is derived from the driver type.
+func (agent *Agent) repoName(r any) string {
+	if init, ok := agent.repoInit(r); ok {
+		return init
+	}
+	return repoMoniker(r)
+}
+
+// repoInit returns the init string used to build a repository, if it was
+// added to the agent from one. Passwords in the location are redacted as
+// the init string ends up in reports, errors and envelope annotations.
+func (agent *Agent) repoInit(r any) (string, bool) {
+	if !reflect.TypeOf(r).Comparable() {
+		return "", false
+	}
+	agent.initsMutex.Lock()
+	defer agent.initsMutex.Unlock()
+	init, ok := agent.repoInits[r]
+	if !ok {
+		return "", false
+	}
+	return redactInit(init), true
+}
+
+// setRepoInit records the init string used to build a repository.
+func (agent *Agent) setRepoInit(r attestation.Repository, init string) {
+	if !reflect.TypeOf(r).Comparable() {
+		return
+	}
+	agent.initsMutex.Lock()
+	defer agent.initsMutex.Unlock()
+	if agent.repoInits == nil {
+		agent.repoInits = map[any]string{}
+	}
+	agent.repoInits[r] = init
+}
+
+// repoMoniker derives a name for a repository from its type. The
+// repositories of the built-in drivers are named by their type moniker,
+// other repositories by the package and name of their type (or just the
+// package, for types named Collector).
+func repoMoniker(r any) string {
+	if moniker, ok := builtinMoniker(r); ok {
+		return moniker
+	}
+	t := reflect.TypeOf(r)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	pkg := path.Base(t.PkgPath())
+	if t.Name() == "Collector" {
+		return pkg
+	}
+	return pkg + "." + t.Name()
+}
@@ -5,7 +5,8 @@ package collector
 
 import (
 	"errors"
-	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 
@@ -13,6 +14,7 @@ import (
 
 	"github.com/carabiner-dev/collector/repository/coci"
 	"github.com/carabiner-dev/collector/repository/filesystem"
+	"github.com/carabiner-dev/collector/repository/git"
 	"github.com/carabiner-dev/collector/repository/github"
 	"github.com/carabiner-dev/collector/repository/gitsign"
 	"github.com/carabiner-dev/collector/repository/http"
@@ -27,62 +29,247 @@ import (
 )
 
 var (
-	repositoryTypes          = map[string]RepositoryFactory{}
+	repositoryTypes       = map[string]RepositoryFactory{}
+	repositoryDescriptors = map[string]CollectorTypeDescriptor{}
+	// repositoryOptions has the option definitions of the registered types
+	// that parse options from their init strings (the built-in drivers).
+	repositoryOptions        = map[string]driverSpec{}
 	ErrTypeAlreadyRegistered = errors.New("collector type already registered")
 )
 
 type RepositoryFactory func(string) (attestation.Repository, error)
 
+// Capability is an operation supported by a type of collector.
+type Capability string
+
+const (
+	CapabilityFetch                Capability = "fetch"
+	CapabilityFetchBySubject       Capability = "fetch-by-subject"
+	CapabilityFetchByPredicateType Capability = "fetch-by-predicate-type"
+	CapabilityStore                Capability = "store"
+)
+
+// CollectorTypeDescriptor documents a type of collector so tools can list
+// the registered types and render their help without building a
+// repository.
+type CollectorTypeDescriptor struct {
+	// Description is a one line summary of the collector.
+	Description string
+
+	// Grammar is the syntax of the init string after the type moniker.
+	Grammar string
+
+	// Example is a complete init string, including the type moniker.
+	Example string
+
+	// Capabilities are the operations supported by the repositories of the
+	// type. Some drivers only support some of them depending on their
+	// configuration, eg the http driver fetches by subject when it has a
+	// subject URL template.
+	Capabilities []Capability
+}
+
+// CollectorType is a registered type of collector.
+type CollectorType struct {
+	Moniker string
+	CollectorTypeDescriptor
+
+	// Options are the driver options accepted in init strings and
+	// configuration files. Only the built-in drivers take options.
+	Options []DriverOption
+}
+
+// Supports returns true if the collector type supports an operation.
+func (ct *CollectorType) Supports(c Capability) bool {
+	return slices.Contains(ct.Capabilities, c)
+}
+
 var mtx sync.Mutex
 
+// RepositoryFromString builds a repository from an init string. The init
+// string of the built-in drivers can carry driver options after the
+// location, see RepositoryFromStringWithOptions.
 func RepositoryFromString(init string) (attestation.Repository, error) {
-	t, init, _ := strings.Cut(init, ":")
-	if b, ok := repositoryTypes[t]; ok {
-		return b(init)
+	return RepositoryFromStringWithOptions(init, nil)
+}
+
+// RegisterCollectorType registers a new type of collector. The optional
+// descriptor documents the type in ListCollectorTypes.
+func RegisterCollectorType(moniker string, factory RepositoryFactory, descriptor ...CollectorTypeDescriptor) error {
+	if len(descriptor) > 1 {
+		return errors.New("a collector type takes only one descriptor")
 	}
-	return nil, fmt.Errorf("repository type unknown: %q", t)
+	return registerCollectorType(moniker, factory, nil, descriptor)
 }
 
-// RegisterCollectorType registers a new type of collector
-func RegisterCollectorType(moniker string, factory RepositoryFactory) error {
+// registerCollectorType registers a type of collector and, for the built-in
+// drivers, the options they parse from their init strings.
+func registerCollectorType(moniker string, factory RepositoryFactory, spec *driverSpec, descriptor []CollectorTypeDescriptor) error {
+	mtx.Lock()
+	defer mtx.Unlock()
 	if _, ok := repositoryTypes[moniker]; ok {
 		return ErrTypeAlreadyRegistered
 	}
-	mtx.Lock()
 	repositoryTypes[moniker] = factory
-	mtx.Unlock()
+	if len(descriptor) == 1 {
+		repositoryDescriptors[moniker] = descriptor[0]
+	}
+	if spec != nil {
+		repositoryOptions[moniker] = *spec
+	}
 	return nil
 }
 
-// RegisterCollectorType registers a new type of collector
+// UnregisterCollectorType removes a type of collector from the registry
 func UnregisterCollectorType(moniker string) {
 	mtx.Lock()
 	delete(repositoryTypes, moniker)
+	delete(repositoryDescriptors, moniker)
+	delete(repositoryOptions, moniker)
 	mtx.Unlock()
 }
 
+// ListCollectorTypes returns the registered types of collectors sorted by
+// moniker. Types registered without a descriptor only have their moniker
+// set.
+func ListCollectorTypes() []CollectorType {
+	mtx.Lock()
+	defer mtx.Unlock()
+	ret := make([]CollectorType, 0, len(repositoryTypes))
+	for moniker := range repositoryTypes {
+		ct := CollectorType{
+			Moniker:                 moniker,
+			CollectorTypeDescriptor: repositoryDescriptors[moniker],
+		}
+		ct.Capabilities = slices.Clone(ct.Capabilities)
+		ct.Options = slices.Clone(repositoryOptions[moniker].options)
+		ret = append(ret, ct)
+	}
+	slices.SortFunc(ret, func(a, b CollectorType) int {
+		return strings.Compare(a.Moniker, b.Moniker)
+	})
+	return ret
+}
+
+// builtinType is a type of collector shipped with the collector.
+type builtinType struct {
+	factory    RepositoryFactory
+	descriptor CollectorTypeDescriptor
+}
+
+// Capabilities shared by several built-in drivers
+var (
+	capsFetchers      = []Capability{CapabilityFetch, CapabilityFetchBySubject, CapabilityFetchByPredicateType}
+	capsFetchersStore = []Capability{CapabilityFetch, CapabilityFetchBySubject, CapabilityFetchByPredicateType, CapabilityStore}
+)
+
+// defaultRepositoryTypes are the built-in drivers
+var defaultRepositoryTypes = map[string]builtinType{
+	coci.TypeMoniker: {coci.Build, CollectorTypeDescriptor{
+		Description:  "Attestations attached to container images with the cosign tag convention",
+		Grammar:      "<image-ref>",
+		Example:      "coci:docker.io/library/alpine:latest",
+		Capabilities: []Capability{CapabilityFetch, CapabilityStore},
+	}},
+	filesystem.TypeMoniker: {filesystem.Build, CollectorTypeDescriptor{
+		Description:  "Attestation files in a local directory",
+		Grammar:      "<path>",
+		Example:      "fs:/path/to/attestations",
+		Capabilities: capsFetchers,
+	}},
+	git.TypeMoniker: {git.Build, CollectorTypeDescriptor{
+		Description:  "Attestation files in a git repository",
+		Grammar:      "<repository-url>[@<ref>][#<path>]",
+		Example:      "git:https://github.com/owner/repo@main#attestations",
+		Capabilities: capsFetchers,
+	}},
+	gitsign.TypeMoniker: {gitsign.Build, CollectorTypeDescriptor{
+		Description:  "Virtual attestations of the gitsign signatures of git commits",
+		Grammar:      "<repository-locator>",
+		Example:      "gitsign:https://github.com/owner/repo",
+		Capabilities: capsFetchers,
+	}},
+	github.TypeMoniker: {github.Build, CollectorTypeDescriptor{
+		Description:  "GitHub attestations API",
+		Grammar:      "<owner>/<repo>",
+		Example:      "github:owner/repo",
+		Capabilities: []Capability{CapabilityFetch, CapabilityFetchBySubject, CapabilityStore},
+	}},
+	http.TypeMoniker: {http.BuildHTTP, CollectorTypeDescriptor{
+		Description:  "Attestations served over HTTP",
+		Grammar:      "//<host>/<path>",
+		Example:      "http://example.com/attestations.jsonl",
+		Capabilities: capsFetchers,
+	}},
+	http.TypeMonikerHTTPS: {http.BuildHTTPs, CollectorTypeDescriptor{
+		Description:  "Attestations served over HTTPS",
+		Grammar:      "//<host>/<path>",
+		Example:      "https://example.com/attestations.jsonl",
+		Capabilities: capsFetchers,
+	}},
+	jsonl.TypeMoniker: {jsonl.Build, CollectorTypeDescriptor{
+		Description:  "Attestations in JSON Lines files",
+		Grammar:      "<path>",
+		Example:      "jsonl:/path/to/file.jsonl",
+		Capabilities: capsFetchers,
+	}},
+	maven.TypeMoniker: {maven.Build, CollectorTypeDescriptor{
+		Description:  "Attestations published with Maven packages",
+		Grammar:      "<maven-purl>",
+		Example:      "maven:pkg:maven/com.example/app@1.0.0",
+		Capabilities: capsFetchers,
+	}},
+	note.TypeMoniker: {note.Build, CollectorTypeDescriptor{
+		Description:  "Attestations stored as git notes of a commit",
+		Grammar:      "<repository-url>@<commit>",
+		Example:      "note:git+https://github.com/owner/repo@abc123",
+		Capabilities: capsFetchersStore,
+	}},
+	note.TypeMonikerDynamic: {note.BuildDynamic, CollectorTypeDescriptor{
+		Description:  "Attestations stored as git notes of the commits in the subjects",
+		Grammar:      "<repository-url>",
+		Example:      "dnote:https://github.com/owner/repo",
+		Capabilities: []Capability{CapabilityFetch, CapabilityFetchBySubject, CapabilityStore},
+	}},
+	oci.TypeMoniker: {oci.Build, CollectorTypeDescriptor{
+		Description:  "Sigstore bundles attached to container images as OCI referrers",
+		Grammar:      "<image-ref>",
+		Example:      "oci:ghcr.io/owner/image:v1",
+		Capabilities: []Capability{CapabilityFetch, CapabilityStore},
+	}},
+	ossrebuild.TypeMoniker: {ossrebuild.Build, CollectorTypeDescriptor{
+		Description:  "Rebuild attestations of the OSS Rebuild project",
+		Grammar:      "",
+		Example:      "ossrebuild:",
+		Capabilities: []Capability{CapabilityFetch, CapabilityFetchBySubject},
+	}},
+	release.TypeMoniker: {release.Build, CollectorTypeDescriptor{
+		Description:  "Attestations stored as GitHub release assets",
+		Grammar:      "<owner>/<repo>[@<tag>]",
+		Example:      "release:owner/repo@v1.0.0",
+		Capabilities: capsFetchersStore,
+	}},
+	sbomfs.TypeMoniker: {sbomfs.Build, CollectorTypeDescriptor{
+		Description:  "Attestations stored in the nodes of an SBOM document",
+		Grammar:      "<path>",
+		Example:      "sbomfs:/path/to/sbom.spdx.json",
+		Capabilities: capsFetchersStore,
+	}},
+	stash.TypeMoniker: {stash.Build, CollectorTypeDescriptor{
+		Description:  "Carabiner stash service",
+		Grammar:      "<org>[/<namespace>]",
+		Example:      "stash:example-org/default",
+		Capabilities: capsFetchers,
+	}},
+}
+
 // LoadDefaultRepositoryTypes loads the default repository types into the
 // in-memory list to get them ready for instantiation.
 func LoadDefaultRepositoryTypes() error {
 	errs := []error{}
-	for t, factory := range map[string]RepositoryFactory{
-		coci.TypeMoniker:        coci.Build,
-		filesystem.TypeMoniker:  filesystem.Build,
-		oci.TypeMoniker:         oci.Build,
-		gitsign.TypeMoniker:     gitsign.Build,
-		github.TypeMoniker:      github.Build,
-		http.TypeMoniker:        http.BuildHTTP,
-		http.TypeMonikerHTTPS:   http.BuildHTTPs,
-		jsonl.TypeMoniker:       jsonl.Build,
-		maven.TypeMoniker:       maven.Build,
-		note.TypeMoniker:        note.Build,
-		note.TypeMonikerDynamic: note.BuildDynamic,
-		ossrebuild.TypeMoniker:  ossrebuild.Build,
-		release.TypeMoniker:     release.Build,
-		sbomfs.TypeMoniker:      sbomfs.Build,
-		stash.TypeMoniker:       stash.Build,
-	} {
-		if err := RegisterCollectorType(t, factory); err != nil {
+	for t, builtin := range defaultRepositoryTypes {
+		spec := driverSpecs[t]
+		if err := registerCollectorType(t, builtin.factory, &spec, []CollectorTypeDescriptor{builtin.descriptor}); err != nil {
 			if !errors.Is(err, ErrTypeAlreadyRegistered) {
 				errs = append(errs, err)
 			}
@@ -90,3 +277,55 @@ func LoadDefaultRepositoryTypes() error {
 	}
 	return errors.Join(errs...)
 }
+
+// builtinMonikers maps the repository types of the built-in drivers to
+// their type monikers, to name the repositories added as objects.
+var builtinMonikers = map[reflect.Type]string{
+	reflect.TypeFor[*coci.Collector]():                     coci.TypeMoniker,
+	reflect.TypeFor[*filesystem.Collector]():               filesystem.TypeMoniker,
+	reflect.TypeFor[*git.Collector]():                      git.TypeMoniker,
+	reflect.TypeFor[*gitsign.Collector]():                  gitsign.TypeMoniker,
+	reflect.TypeFor[*github.Collector]():                   github.TypeMoniker,
+	reflect.TypeFor[*http.Collector]():                     http.TypeMoniker,
+	reflect.TypeFor[*http.CollectorPredicateType]():        http.TypeMoniker,
+	reflect.TypeFor[*http.CollectorPredicateTypeSubject](): http.TypeMoniker,
+	reflect.TypeFor[*http.CollectorSubject]():              http.TypeMoniker,
+	reflect.TypeFor[*http.CollectorSubjectAndType]():       http.TypeMoniker,
+	reflect.TypeFor[*jsonl.Collector]():                    jsonl.TypeMoniker,
+	reflect.TypeFor[*maven.Collector]():                    maven.TypeMoniker,
+	reflect.TypeFor[*note.Collector]():                     note.TypeMoniker,
+	reflect.TypeFor[*note.Dynamic]():                       note.TypeMonikerDynamic,
+	reflect.TypeFor[*oci.Collector]():                      oci.TypeMoniker,
+	reflect.TypeFor[*ossrebuild.Collector]():               ossrebuild.TypeMoniker,
+	reflect.TypeFor[*release.Collector]():                  release.TypeMoniker,
+	reflect.TypeFor[*sbomfs.Collector]():                   sbomfs.TypeMoniker,
+	reflect.TypeFor[*stash.Collector]():                    stash.TypeMoniker,
+}
+
+// builtinMoniker returns the type moniker of a repository created by one of
+// the built-in drivers.
+func builtinMoniker(r any) (string, bool) {
+	moniker, ok := builtinMonikers[reflect.TypeOf(r)]
+	if !ok || moniker != http.TypeMoniker {
+		return moniker, ok
+	}
+
+	// The http driver serves both schemes
+	var opts http.Options
+	switch c := r.(type) {
+	case *http.Collector:
+		opts = c.Options
+	case *http.CollectorPredicateType:
+		opts = c.Options
+	case *http.CollectorPredicateTypeSubject:
+		opts = c.Options
+	case *http.CollectorSubject:
+		opts = c.Options
+	case *http.CollectorSubjectAndType:
+		opts = c.Options
+	}
+	if len(opts.URLs) > 0 && strings.HasPrefix(opts.URLs[0], http.TypeMonikerHTTPS+":") {
+		return http.TypeMonikerHTTPS, true
+	}
+	return moniker, true
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCollectorTypes(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, LoadDefaultRepositoryTypes())
+
+	types := ListCollectorTypes()
+	require.True(t, slices.IsSortedFunc(types, func(a, b CollectorType) int {
+		return strings.Compare(a.Moniker, b.Moniker)
+	}))
+
+	for moniker := range defaultRepositoryTypes {
+		i := slices.IndexFunc(types, func(ct CollectorType) bool { return ct.Moniker == moniker })
+		require.NotEqual(t, -1, i, moniker)
+		ct := types[i]
+		require.NotEmpty(t, ct.Description, moniker)
+		require.True(t, strings.HasPrefix(ct.Example, moniker+":"), moniker)
+		require.True(t, ct.Supports(CapabilityFetch), moniker)
+		require.Equal(t, driverSpecs[moniker].options, ct.Options, moniker)
+	}
+
+	// The capabilities cover the interfaces of the built repositories
+	for moniker, init := range map[string]string{
+		"fs":     "fs:.",
+		"git":    "git:https://github.com/owner/repo@main#attestations",
+		"jsonl":  "jsonl:repository/jsonl/testdata/single.jsonl",
+		"note":   "note:git+https://github.com/owner/repo@abc123",
+		"dnote":  "dnote:https://github.com/owner/repo",
+		"sbomfs": "sbomfs:repository/sbomfs/testdata/sbom.spdx.json",
+	} {
+		i := slices.IndexFunc(types, func(ct CollectorType) bool { return ct.Moniker == moniker })
+		repo, err := RepositoryFromString(init)
+		require.NoError(t, err, moniker)
+		for c, ok := range map[Capability]bool{
+			CapabilityFetch:                implements[attestation.Fetcher](repo),
+			CapabilityFetchBySubject:       implements[attestation.FetcherBySubject](repo),
+			CapabilityFetchByPredicateType: implements[attestation.FetcherByPredicateType](repo),
+			CapabilityStore:                implements[attestation.Storer](repo),
+		} {
+			require.Equal(t, ok, types[i].Supports(c), "%s %s", moniker, c)
+		}
+	}
+}
+
+func implements[T any](repo attestation.Repository) bool {
+	_, ok := repo.(T)
+	return ok
+}
+
+func TestRegisterCollectorTypeDescriptor(t *testing.T) {
+	t.Parallel()
+	factory := func(string) (attestation.Repository, error) { return &fakeFetcher{}, nil }
+	descriptor := CollectorTypeDescriptor{
+		Description:  "Test collector",
+		Grammar:      "<anything>",
+		Example:      "descriptortest:a",
+		Capabilities: []Capability{CapabilityFetch},
+	}
+
+	require.Error(t, RegisterCollectorType("descriptortest", factory, descriptor, descriptor))
+	require.NoError(t, RegisterCollectorType("descriptortest", factory, descriptor))
+	require.NoError(t, RegisterCollectorType("plaintest", factory))
+	t.Cleanup(func() {
+		UnregisterCollectorType("descriptortest")
+		UnregisterCollectorType("plaintest")
+	})
+
+	types := ListCollectorTypes()
+	i := slices.IndexFunc(types, func(ct CollectorType) bool { return ct.Moniker == "descriptortest" })
+	require.NotEqual(t, -1, i)
+	require.Equal(t, CollectorType{Moniker: "descriptortest", CollectorTypeDescriptor: descriptor}, types[i])
+	require.False(t, types[i].Supports(CapabilityStore))
+
+	i = slices.IndexFunc(types, func(ct CollectorType) bool { return ct.Moniker == "plaintest" })
+	require.NotEqual(t, -1, i)
+	require.Equal(t, CollectorType{Moniker: "plaintest"}, types[i])
+
+	UnregisterCollectorType("descriptortest")
+	require.False(t, slices.ContainsFunc(ListCollectorTypes(), func(ct CollectorType) bool {
+		return ct.Moniker == "descriptortest"
+	}))
+}
@@ -33,6 +33,8 @@ import (
 	"github.com/carabiner-dev/collector/envelope/bundle"
 	"github.com/carabiner-dev/collector/envelope/dsse"
 	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
+	"github.com/carabiner-dev/collector/repository"
 )
 
 var TypeMoniker = "coci"
@@ -86,7 +88,10 @@ var Build = func(istr string) (attestation.Repository, error) {
 	return New(WithReference(istr))
 }
 
-var _ attestation.Fetcher = (*Collector)(nil)
+var (
+	_ attestation.Fetcher = (*Collector)(nil)
+	_ repository.Prober   = (*Collector)(nil)
+)
 
 func New(funcs ...optFn) (*Collector, error) {
 	// Apply the functional options
@@ -163,6 +168,17 @@ func (c *Collector) SetKeys(keys []key.PublicKeyProvider) {
 	c.Keys = keys
 }
 
+// Probe checks that the registry is reachable and the image reference exists.
+func (c *Collector) Probe(ctx context.Context) error {
+	if c.Options.Reference == "" {
+		return errors.New("no image reference set")
+	}
+	if _, err := crane.Head(c.Options.Reference, append([]crane.Option{crane.WithContext(ctx)}, c.craneOpts()...)...); err != nil {
+		return fmt.Errorf("reading %s: %w", c.Options.Reference, err)
+	}
+	return nil
+}
+
 // Fetch queries the repository and retrieves any attestations matching the query
 func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
 	imageInfo, err := parseImageReference(ctx, c.Options.Reference, c.craneOpts()...)
@@ -255,6 +271,11 @@ func (c *Collector) fetchAttestationLayers(ctx context.Context, opts attestation
 			defer wg.Done()
 			sem <- struct{}{}
 			defer func() { <-sem }()
+			// Layers are not pulled once the fetch read budget runs out
+			ref := imageInfo.Registry + "/" + imageInfo.Repository + "@" + dl.layer.Digest.String()
+			if readlimit.Stop(ctx, ref) || !readlimit.Consume(ctx, ref, dl.layer.Size) {
+				return
+			}
 			envelope, err := getAttestationEnvelope(ctx, &opts, imageInfo, dl.layer, c.craneOpts()...)
 			results[j] = layerResult{envelope, err, dl.index}
 		}(j, dl)
@@ -263,14 +284,24 @@ func (c *Collector) fetchAttestationLayers(ctx context.Context, opts attestation
 
 	// Collect results preserving layer order.
 	var atts []attestation.Envelope
-	for _, r := range results {
+	for j, r := range results {
 		if r.err != nil {
 			return nil, fmt.Errorf("generating envelope from layer %d: %w", r.index, r.err)
 		}
+		if r.envelope == nil {
+			continue
+		}
+		ref := imageInfo.Registry + "/" + imageInfo.Repository + "@" + dsseLayers[j].layer.Digest.String()
 		if r.envelope.GetStatement() == nil {
 			logrus.Debugf("coci: skipping layer %d: payload could not be parsed into a statement", r.index)
+			observer.FromContext(ctx).ParseSkipped(ctx, observer.ParseSkippedEvent{
+				Source: ref, Reason: "unparseable payload",
+			})
 			continue
 		}
+		observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+			Source: ref, Count: 1, Bytes: dsseLayers[j].layer.Size,
+		})
 		atts = append(atts, r.envelope)
 		if opts.Limit > 0 && len(atts) >= opts.Limit {
 			break
@@ -300,7 +331,7 @@ func dsseEnvelopeFromOCILayer(ctx context.Context, opts *attestation.FetchOption
 
 	// Unmarshal the data
 	dsseEnv := &protodsse.Envelope{}
-	if err := unmarshaler.Unmarshal(readlimit.Reader(blob, opts.MaxReadSize), dsseEnv); err != nil {
+	if err := unmarshaler.Unmarshal(readlimit.ReaderContext(ctx, blob, opts.MaxReadSize, attRef), dsseEnv); err != nil {
 		return nil, fmt.Errorf("unmarshaling dsse envelope: %w", err)
 	}
 
@@ -86,6 +86,11 @@ func (c *Collector) fetchSignatures(ctx context.Context, opts attestation.FetchO
 			defer wg.Done()
 			sem <- struct{}{}
 			defer func() { <-sem }()
+			// Layers are not pulled once the fetch read budget runs out
+			ref := imageInfo.Registry + "/" + imageInfo.Repository + "@" + sl.layer.Digest.String()
+			if readlimit.Stop(ctx, ref) || !readlimit.Consume(ctx, ref, sl.layer.Size) {
+				return
+			}
 			env, err := c.getSignatureEnvelope(ctx, &opts, imageInfo, sl.layer)
 			results[j] = layerResult{env, err, sl.index}
 		}(j, sl)
@@ -99,6 +104,9 @@ func (c *Collector) fetchSignatures(ctx context.Context, opts attestation.FetchO
 			logrus.Debugf("coci: skipping .sig layer %d: %v", r.index, r.err)
 			continue
 		}
+		if r.envelope == nil {
+			continue
+		}
 		atts = append(atts, r.envelope)
 		if opts.Limit > 0 && len(atts) >= opts.Limit {
 			break
@@ -124,7 +132,7 @@ func (c *Collector) getSignatureEnvelope(ctx context.Context, opts *attestation.
 	}
 	defer blob.Close() //nolint:errcheck
 
-	payload, err := io.ReadAll(readlimit.Reader(blob, opts.MaxReadSize))
+	payload, err := io.ReadAll(readlimit.ReaderContext(ctx, blob, opts.MaxReadSize, layerRef))
 	if err != nil {
 		return nil, fmt.Errorf("reading signature payload: %w", err)
 	}
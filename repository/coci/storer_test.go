@@ -337,3 +337,21 @@ func TestDSSELayerForEnvelopePlainDSSE(t *testing.T) {
 	require.Nil(t, material)
 	require.Contains(t, string(data), `"payloadType"`)
 }
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	host := startTestRegistry(t)
+	ref := host + "/probe/app:latest"
+	pushEmptySubject(t, ctx, ref)
+
+	c, err := New(WithReference(ref), WithCraneOpts(crane.Insecure))
+	require.NoError(t, err)
+	require.NoError(t, c.Probe(ctx))
+
+	c, err = New(WithReference(host+"/probe/missing:latest"), WithCraneOpts(crane.Insecure))
+	require.NoError(t, err)
+	require.Error(t, c.Probe(ctx))
+
+	require.Error(t, (&Collector{}).Probe(ctx))
+}
@@ -17,11 +17,14 @@ import (
 
 	"github.com/carabiner-dev/attestation"
 	"github.com/carabiner-dev/signer/key"
+	"github.com/sigstore/sigstore-go/pkg/root"
 	"github.com/sirupsen/logrus"
 
 	"github.com/carabiner-dev/collector/envelope"
 	"github.com/carabiner-dev/collector/filters"
 	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
+	"github.com/carabiner-dev/collector/repository"
 )
 
 var TypeMoniker = "fs"
@@ -110,7 +113,25 @@ var WithKey = func(keys ...key.PublicKeyProvider) fnOpts {
 	}
 }
 
-var _ attestation.Fetcher = (*Collector)(nil)
+var WithOffline = func(offline bool) fnOpts {
+	return func(c *Collector) error {
+		c.Offline = offline
+		return nil
+	}
+}
+
+var WithTrustedRoot = func(tm root.TrustedMaterial) fnOpts {
+	return func(c *Collector) error {
+		c.TrustedRoot = tm
+		return nil
+	}
+}
+
+var (
+	_ attestation.Fetcher          = (*Collector)(nil)
+	_ repository.OfflineAware      = (*Collector)(nil)
+	_ repository.TrustedRootSetter = (*Collector)(nil)
+)
 
 // Collector is the filesystem collector
 type Collector struct {
@@ -123,6 +144,14 @@ type Collector struct {
 	Path                     string
 	FS                       fs.FS
 	Keys                     []key.PublicKeyProvider
+
+	// Offline disables the Rekor lookups of detached keyless signatures.
+	// Sigstore bundles are only verified when TrustedRoot is set.
+	Offline bool
+
+	// TrustedRoot is the sigstore trust root used to verify bundles. When
+	// nil, the signer library default is used.
+	TrustedRoot root.TrustedMaterial
 }
 
 // SetKeys sets the verification keys used by the collector.
@@ -130,6 +159,16 @@ func (c *Collector) SetKeys(keys []key.PublicKeyProvider) {
 	c.Keys = keys
 }
 
+// SetOffline implements repository.OfflineAware.
+func (c *Collector) SetOffline(offline bool) {
+	c.Offline = offline
+}
+
+// SetTrustedRoot implements repository.TrustedRootSetter.
+func (c *Collector) SetTrustedRoot(tm root.TrustedMaterial) {
+	c.TrustedRoot = tm
+}
+
 // Fetch queries the repository and retrieves any attestations matching the query
 func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
 	if c.FS == nil {
@@ -166,13 +205,20 @@ func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([
 		}
 
 		// Check file size before reading
-		maxSize := readlimit.Resolve(opts.MaxReadSize)
 		info, err := d.Info()
 		if err != nil {
 			return fmt.Errorf("getting file info for %s: %w", path, err)
 		}
-		if info.Size() > maxSize {
-			return fmt.Errorf("file %s (%d bytes) exceeds max read size (%d bytes)", path, info.Size(), maxSize)
+		if readlimit.Exceeds(ctx, path, info.Size(), opts.MaxReadSize) {
+			return fmt.Errorf(
+				"file %s (%d bytes) exceeds max read size (%d bytes)",
+				path, info.Size(), readlimit.Resolve(opts.MaxReadSize),
+			)
+		}
+
+		// Stop reading when the fetch read budget runs out
+		if readlimit.Stop(ctx, path) || !readlimit.Consume(ctx, path, info.Size()) {
+			return fs.SkipAll
 		}
 
 		// Read the file data from the filesystem
@@ -194,8 +240,15 @@ func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([
 			// An unparseable file shouldn't fail the whole collection —
 			// log and continue.
 			logrus.Debugf("skipping %s: parsing attestations: %v", path, err)
+			observer.FromContext(ctx).ParseSkipped(ctx, observer.ParseSkippedEvent{
+				Source: path, Reason: "unparseable file", Error: err,
+			})
 			return nil
 		}
+		observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+			Source: path, Count: len(attestations), Bytes: int64(len(bs)),
+		})
+		attestations = attestations[:readlimit.ConsumeEnvelopes(ctx, path, len(attestations))]
 
 		if opts.Query != nil {
 			attestations = opts.Query.Run(attestations)
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/carabiner-dev/collector/filters"
+	"github.com/carabiner-dev/collector/observer"
 )
 
 func TestFetch(t *testing.T) {
@@ -72,9 +73,16 @@ func TestFetchSkipsUnparseableFiles(t *testing.T) {
 	collector, err := New(WithFS(fsys))
 	require.NoError(t, err)
 
-	atts, err := collector.Fetch(t.Context(), attestation.FetchOptions{})
+	rec := observer.NewRecorder()
+	atts, err := collector.Fetch(observer.WithObserver(t.Context(), rec), attestation.FetchOptions{})
 	require.NoError(t, err)
 	require.Len(t, atts, 1)
+
+	// Skipped files are reported to the observer
+	require.Len(t, rec.ParsedEnvelopes, 1)
+	require.Equal(t, "results.intoto.json", rec.ParsedEnvelopes[0].Source)
+	require.Len(t, rec.SkippedParses, 1)
+	require.Equal(t, "broken.json", rec.SkippedParses[0].Source)
 }
 
 func TestFetchFetchByPredicateType(t *testing.T) {
@@ -29,7 +29,10 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/carabiner-dev/collector/envelope"
+	sbundle "github.com/carabiner-dev/collector/envelope/bundle"
+	"github.com/carabiner-dev/collector/internal/readlimit"
 	"github.com/carabiner-dev/collector/predicate/generic"
+	"github.com/carabiner-dev/collector/repository"
 	"github.com/carabiner-dev/collector/statement/intoto"
 )
 
@@ -102,19 +105,28 @@ func (c *Collector) processSignaturePairs(ctx context.Context, allFiles []string
 	for _, path := range allFiles {
 		var envs []attestation.Envelope
 
+		bundleExt := getSignatureExtension(path, c.SigstoreBundleExtensions)
+		sigExt := getSignatureExtension(path, c.SignatureExtensions)
+		if bundleExt == "" && sigExt == "" {
+			continue
+		}
+
+		// Stop reading when the fetch read budget runs out
+		if readlimit.Stop(ctx, path) {
+			break
+		}
+
 		// Check sigstore bundle extensions first. Sigstore bundles carry
 		// the artifact digest inside the messageSignature so there is no
 		// need to read the companion artifact.
-		if ext := getSignatureExtension(path, c.SigstoreBundleExtensions); ext != "" {
-			envs = c.processSigstoreBundle(path, ext, opts)
-		} else if ext := getSignatureExtension(path, c.SignatureExtensions); ext != "" {
-			// Raw signature extensions require a companion artifact.
-			envs = c.processRawSignature(ctx, path, ext, fileSet, opts)
+		if bundleExt != "" {
+			envs = c.processSigstoreBundle(path, bundleExt, opts)
 		} else {
-			continue
+			// Raw signature extensions require a companion artifact.
+			envs = c.processRawSignature(ctx, path, sigExt, fileSet, opts)
 		}
 
-		ret = append(ret, envs...)
+		ret = append(ret, envs[:readlimit.ConsumeEnvelopes(ctx, path, len(envs))]...)
 	}
 
 	return ret
@@ -223,6 +235,18 @@ func (c *Collector) processRawSignature(ctx context.Context, path, ext string, f
 // verifySigstoreBundle verifies a parsed sigstore bundle and extracts
 // the signing identity from its certificate.
 func (c *Collector) verifySigstoreBundle(bundle *sigstore.Bundle) (*sapi.Verification, error) {
+	if c.TrustedRoot != nil {
+		if err := sbundle.VerifyWithTrustedMaterial(&sgbundle.Bundle{Bundle: bundle}, c.TrustedRoot); err != nil {
+			return nil, fmt.Errorf("verifying sigstore bundle: %w", err)
+		}
+		return c.extractSigstoreIdentity(bundle)
+	}
+
+	// The default verifier may refresh the trust root from the network
+	if c.Offline {
+		return nil, fmt.Errorf("verifying sigstore bundle without a trusted root: %w", repository.ErrOfflineMode)
+	}
+
 	verifier := signer.NewVerifier()
 	verifier.Options.SkipIdentityCheck = true
 
@@ -23,6 +23,8 @@ import (
 	rekormodels "github.com/sigstore/rekor/pkg/generated/models"
 	"github.com/sigstore/rekor/pkg/tle"
 	"github.com/sirupsen/logrus"
+
+	"github.com/carabiner-dev/collector/repository"
 )
 
 const (
@@ -75,6 +77,13 @@ func (c *Collector) processCertSignaturePair(ctx context.Context, artifactPath,
 	}
 	digest := sha256.Sum256(artifactData)
 
+	// The signing time is only in Rekor, without it the certificate can't
+	// be verified.
+	if c.Offline {
+		logrus.Debugf("skipping detached signature of %s: rekor lookup not available offline", artifactPath)
+		return nil
+	}
+
 	// Look up the transparency-log entries recording this artifact digest so
 	// the reconstructed bundle carries an observer timestamp.
 	tlogs, err := c.fetchRekorTlogEntries(ctx, hex.EncodeToString(digest[:]))
@@ -111,6 +120,9 @@ func (c *Collector) processCertSignaturePair(ctx context.Context, artifactPath,
 // recording the given artifact digest and converts each match into a
 // transparency-log entry suitable for a sigstore bundle.
 func (c *Collector) fetchRekorTlogEntries(ctx context.Context, digestHex string) ([]*protorekor.TransparencyLogEntry, error) {
+	if c.Offline {
+		return nil, repository.ErrOfflineMode
+	}
 	rekorURL := c.RekorURL
 	if rekorURL == "" {
 		rekorURL = defaultRekorURL
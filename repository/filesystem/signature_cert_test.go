@@ -10,9 +10,12 @@ import (
 	"strings"
 	"testing"
 
+	sigstore "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
 	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
 	protorekor "github.com/sigstore/protobuf-specs/gen/pb-go/rekor/v1"
 	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/repository"
 )
 
 func TestCertificateCompanion(t *testing.T) {
@@ -120,3 +123,15 @@ func TestBuildDetachedBundle(t *testing.T) {
 	require.Equal(t, digest, ms.GetMessageDigest().GetDigest())
 	require.Equal(t, protocommon.HashAlgorithm_SHA2_256, ms.GetMessageDigest().GetAlgorithm())
 }
+
+func TestOfflineSkipsRekor(t *testing.T) {
+	t.Parallel()
+	// The URL is never contacted in offline mode
+	c, err := New(WithRekorURL("http://127.0.0.1:1"), WithOffline(true))
+	require.NoError(t, err)
+	_, err = c.fetchRekorTlogEntries(t.Context(), strings.Repeat("0", 64))
+	require.ErrorIs(t, err, repository.ErrOfflineMode)
+
+	_, err = c.verifySigstoreBundle(&sigstore.Bundle{})
+	require.ErrorIs(t, err, repository.ErrOfflineMode)
+}
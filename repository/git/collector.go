@@ -9,6 +9,7 @@ package git
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/carabiner-dev/attestation"
 	"github.com/go-git/go-billy/v5/helper/iofs"
@@ -16,7 +17,9 @@ import (
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/sigstore/sigstore-go/pkg/root"
 
+	"github.com/carabiner-dev/collector/repository"
 	"github.com/carabiner-dev/collector/repository/filesystem"
 )
 
@@ -24,10 +27,14 @@ var TypeMoniker = "git"
 
 // Implement the factory function
 var Build = func(istr string) (attestation.Repository, error) {
-	return New(WithPath(istr))
+	return New(WithLocator(istr))
 }
 
-var _ attestation.Fetcher = (*Collector)(nil)
+var (
+	_ attestation.Fetcher          = (*Collector)(nil)
+	_ repository.OfflineAware      = (*Collector)(nil)
+	_ repository.TrustedRootSetter = (*Collector)(nil)
+)
 
 func New(funcs ...optFn) (*Collector, error) {
 	// Apply the functional options
@@ -59,6 +66,11 @@ func (c *Collector) clone() error {
 	// be an option
 	fs := memfs.New()
 
+	// Only local repositories can be cloned without network access
+	if c.Options.Offline && !strings.HasPrefix(c.Options.URL, "file://") {
+		return fmt.Errorf("cloning %s: %w", c.Options.URL, repository.ErrOfflineMode)
+	}
+
 	// Make a shallow clone of the repo to memory
 	r, err := git.Clone(memory.NewStorage(), fs, &git.CloneOptions{
 		URL: c.Options.URL,
@@ -77,6 +89,8 @@ func (c *Collector) clone() error {
 	fscollector, err := filesystem.New(
 		filesystem.WithFS(iofs.New(fs)),
 		filesystem.WithPath(c.Options.Path),
+		filesystem.WithOffline(c.Options.Offline),
+		filesystem.WithTrustedRoot(c.Options.TrustedRoot),
 	)
 	if err != nil {
 		return fmt.Errorf("creating new fs collector: %w", err)
@@ -85,6 +99,23 @@ func (c *Collector) clone() error {
 	return nil
 }
 
+// SetOffline implements repository.OfflineAware. In offline mode only
+// file:// repositories can be read.
+func (c *Collector) SetOffline(offline bool) {
+	c.Options.Offline = offline
+	if c.FSCollector != nil {
+		c.FSCollector.SetOffline(offline)
+	}
+}
+
+// SetTrustedRoot implements repository.TrustedRootSetter.
+func (c *Collector) SetTrustedRoot(tm root.TrustedMaterial) {
+	c.Options.TrustedRoot = tm
+	if c.FSCollector != nil {
+		c.FSCollector.SetTrustedRoot(tm)
+	}
+}
+
 func (c *Collector) ensureClone() error {
 	if c.FSCollector == nil {
 		return c.clone()
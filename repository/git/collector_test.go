@@ -11,6 +11,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/release-utils/tar"
+
+	"github.com/carabiner-dev/collector/repository"
 )
 
 func TestClone(t *testing.T) {
@@ -48,3 +50,33 @@ func TestClone(t *testing.T) {
 		})
 	}
 }
+
+func TestCloneOffline(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, tar.Extract("testdata/repo.tar.gz", dir))
+
+	for _, tc := range []struct {
+		name    string
+		init    string
+		mustErr bool
+	}{
+		{"local", filepath.Join(dir, "repo"), false},
+		{"remote", "https://github.com/example/repo", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			c, err := New(WithLocator(tc.init))
+			require.NoError(t, err)
+			c.SetOffline(true)
+
+			err = c.clone()
+			if tc.mustErr {
+				require.ErrorIs(t, err, repository.ErrOfflineMode)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, c.FSCollector.Offline)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/carabiner-dev/vcslocator"
+	"github.com/sigstore/sigstore-go/pkg/root"
 )
 
 type optFn = func(*Options) error
@@ -21,6 +22,13 @@ type Options struct {
 	Path   string
 	Ref    string
 	Commit string
+
+	// Offline restricts the collector to local repositories.
+	Offline bool
+
+	// TrustedRoot is passed to the filesystem collector to verify sigstore
+	// bundles.
+	TrustedRoot root.TrustedMaterial
 }
 
 var defaultOptions = Options{}
@@ -78,3 +86,11 @@ func WithPath(path string) optFn {
 		return nil
 	}
 }
+
+// WithRef sets the git ref to clone, overriding the one in the locator.
+func WithRef(ref string) optFn {
+	return func(opts *Options) error {
+		opts.Ref = ref
+		return nil
+	}
+}
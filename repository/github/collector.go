@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"slices"
@@ -20,6 +21,8 @@ import (
 
 	"github.com/carabiner-dev/collector/envelope/bundle"
 	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
+	"github.com/carabiner-dev/collector/repository"
 )
 
 const (
@@ -33,6 +36,7 @@ var (
 	_ attestation.Fetcher          = (*Collector)(nil)
 	_ attestation.FetcherBySubject = (*Collector)(nil)
 	_ attestation.Storer           = (*Collector)(nil)
+	_ repository.Prober            = (*Collector)(nil)
 )
 
 // Implement the factory function
@@ -104,6 +108,18 @@ func New(funcs ...optFn) (*Collector, error) {
 	}, nil
 }
 
+// Probe checks that the repository is reachable with the configured token.
+func (c *Collector) Probe(ctx context.Context) error {
+	if c.Options.Owner == "" || c.Options.Repo == "" {
+		return fmt.Errorf("missing repository data")
+	}
+	resp, err := c.client.Call(ctx, http.MethodGet, fmt.Sprintf("repos/%s/%s", c.Options.Owner, c.Options.Repo), nil)
+	if err != nil {
+		return fmt.Errorf("reading repository %s/%s: %w", c.Options.Owner, c.Options.Repo, err)
+	}
+	return resp.Body.Close()
+}
+
 type attResponse struct {
 	Attestations []struct {
 		Bundle       *bundle.Envelope `json:"bundle"`
@@ -143,6 +159,10 @@ func (c *Collector) FetchBySubject(ctx context.Context, opts attestation.FetchOp
 			url = fmt.Sprintf("/repos/%s/%s/attestations/%s", c.Options.Owner, c.Options.Repo, digest)
 		}
 
+		// Stop querying the API when the fetch read budget is exhausted
+		if readlimit.Stop(ctx, url) {
+			break
+		}
 		envs, _, err := c.fetchFromUrl(ctx, url, opts.MaxReadSize)
 		if err != nil {
 			return nil, fmt.Errorf("fetching attestations: %w", err)
@@ -176,14 +196,22 @@ func (c *Collector) fetchFromUrl(ctx context.Context, url string, maxReadSize in
 	defer resp.Body.Close() //nolint:errcheck
 	res := &attResponse{}
 
-	dec := json.NewDecoder(readlimit.Reader(resp.Body, maxReadSize))
+	// The response is charged to the read budget as it is read, responses
+	// that don't fit are dropped.
+	dec := json.NewDecoder(readlimit.ReaderContext(ctx, readlimit.ConsumeReader(ctx, resp.Body, url), maxReadSize, url))
 	if err := dec.Decode(res); err != nil {
+		if errors.Is(err, readlimit.ErrExhausted) {
+			return ret, false, nil
+		}
 		return nil, false, fmt.Errorf("parsing response: %w", err)
 	}
 
 	for _, e := range res.Attestations {
 		ret = append(ret, e.Bundle)
 	}
+	observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+		Source: url, Count: len(ret),
+	})
 	return ret, false, nil
 }
 
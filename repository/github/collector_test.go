@@ -81,3 +81,39 @@ func TestWithRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		opts    Options
+		synterr error
+		mustErr bool
+	}{
+		{"reachable", Options{Owner: "carabiner-dev", Repo: "collector"}, nil, false},
+		{"api-error", Options{Owner: "carabiner-dev", Repo: "collector"}, errors.New("HTTP Error 401"), true},
+		{"no-repo", Options{}, nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			client, err := github.NewClient(
+				github.WithCaller(
+					&github.FileCaller{
+						SourcePath: "testdata/output.json",
+						Error:      tc.synterr,
+					},
+				),
+				github.WithEnsureToken(false),
+			)
+			require.NoError(t, err)
+
+			collector := &Collector{Options: tc.opts, client: client}
+			err = collector.Probe(t.Context())
+			if tc.mustErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
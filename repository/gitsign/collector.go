@@ -65,6 +65,7 @@ import (
 
 	"github.com/carabiner-dev/collector/filters"
 	"github.com/carabiner-dev/collector/predicate/generic"
+	"github.com/carabiner-dev/collector/repository"
 	intotostatement "github.com/carabiner-dev/collector/statement/intoto"
 )
 
@@ -79,6 +80,8 @@ var (
 	_ attestation.Fetcher                = (*Collector)(nil)
 	_ attestation.FetcherBySubject       = (*Collector)(nil)
 	_ attestation.FetcherByPredicateType = (*Collector)(nil)
+	_ repository.OfflineAware            = (*Collector)(nil)
+	_ repository.TrustedRootSetter       = (*Collector)(nil)
 )
 
 // oidRekorTransparencyLogEntry is the OID under which gitsign embeds a serialized
@@ -94,16 +97,25 @@ type Collector struct {
 	// trustedRoot caches the resolved sigstore trust root so it is loaded once and
 	// reused across every commit/tag verification.
 	trustedRootOnce sync.Once
-	trustedRoot     *root.TrustedRoot
+	trustedRoot     root.TrustedMaterial
 	trustedRootErr  error
 }
 
-// trusted resolves and caches the sigstore public-good trust root from the
-// signer library (an embedded snapshot with a TUF-refresh fallback), loaded
-// once and reused across every commit/tag verification.
-func (c *Collector) trusted() (*root.TrustedRoot, error) {
+// trusted resolves and caches the sigstore trust root, loaded once and reused
+// across every commit/tag verification. A root supplied in the options wins,
+// otherwise the public-good root is loaded from the signer library (an
+// embedded snapshot with a TUF-refresh fallback). In offline mode the library
+// is not used as the refresh may reach the network.
+func (c *Collector) trusted() (root.TrustedMaterial, error) {
 	c.trustedRootOnce.Do(func() {
-		c.trustedRoot, c.trustedRootErr = signersigstore.TrustedRoot()
+		switch {
+		case c.Options.TrustedRoot != nil:
+			c.trustedRoot = c.Options.TrustedRoot
+		case c.Options.Offline:
+			c.trustedRootErr = fmt.Errorf("no trusted root supplied: %w", repository.ErrOfflineMode)
+		default:
+			c.trustedRoot, c.trustedRootErr = signersigstore.TrustedRoot()
+		}
 	})
 	return c.trustedRoot, c.trustedRootErr
 }
@@ -139,6 +151,14 @@ type Options struct {
 	// Depth limits the fetched history. 0 fetches full history; 1 fetches only
 	// the ref tip, which is sufficient when the subject commit is that tip.
 	Depth int
+
+	// Offline forbids network access: remote repositories are not fetched
+	// and signatures without an embedded Rekor entry are not looked up.
+	Offline bool
+
+	// TrustedRoot is the sigstore trust root used to verify signatures. It
+	// is required in offline mode.
+	TrustedRoot root.TrustedMaterial
 }
 
 var defaultOptions = Options{
@@ -216,6 +236,22 @@ func WithDepth(depth int) optFn {
 	}
 }
 
+// WithOffline forbids the collector from accessing the network.
+func WithOffline(offline bool) optFn {
+	return func(c *Collector) error {
+		c.Options.Offline = offline
+		return nil
+	}
+}
+
+// WithTrustedRoot sets the sigstore trust root used to verify signatures.
+func WithTrustedRoot(tm root.TrustedMaterial) optFn {
+	return func(c *Collector) error {
+		c.Options.TrustedRoot = tm
+		return nil
+	}
+}
+
 func WithKeys(keys ...key.PublicKeyProvider) optFn {
 	return func(c *Collector) error {
 		c.Keys = append(c.Keys, keys...)
@@ -243,6 +279,18 @@ func (c *Collector) SetKeys(keys []key.PublicKeyProvider) {
 	c.Keys = keys
 }
 
+// SetOffline implements the repository.OfflineAware interface.
+func (c *Collector) SetOffline(offline bool) {
+	c.Options.Offline = offline
+}
+
+// SetTrustedRoot implements the repository.TrustedRootSetter interface. It
+// has to be called before the first verification, the trust root is loaded
+// only once.
+func (c *Collector) SetTrustedRoot(tm root.TrustedMaterial) {
+	c.Options.TrustedRoot = tm
+}
+
 // Fetch parses the locator and, if it contains a commit or tag reference, builds a
 // virtual attestation. Tag locators produce a tag predicate; commit locators
 // produce a commit predicate.
@@ -376,6 +424,10 @@ func (c *Collector) FetchBySubject(ctx context.Context, opts attestation.FetchOp
 func (c *Collector) openRepo() (*gogit.Repository, error) {
 	components, err := vcslocator.Locator(c.Options.Locator).Parse()
 	if err == nil && components.Transport != vcslocator.TransportFile {
+		if c.Options.Offline {
+			return nil, fmt.Errorf("fetching remote repository: %w", repository.ErrOfflineMode)
+		}
+
 		// Remote repository — fetch into memory. An explicit auth option wins,
 		// otherwise fall back to system git credentials.
 		auth := c.Options.Auth
@@ -720,6 +772,10 @@ func (c *Collector) verifySigstoreSignature(ctx context.Context, signedData, cms
 		return c.verifyEntry(ctx, leaf, si.Signature, digest[:], entryProto, trustedRoot)
 	}
 
+	if c.Options.Offline {
+		return nil, fmt.Errorf("signature has no embedded transparency log entry: %w", repository.ErrOfflineMode)
+	}
+
 	logrus.Debug("gitsign: signature has no embedded Rekor entry; looking it up online")
 	if entryProto, err = c.lookupTlogEntry(ctx, leaf, si.Signature, digest[:]); err == nil {
 		return c.verifyEntry(ctx, leaf, si.Signature, digest[:], entryProto, trustedRoot)
@@ -740,7 +796,7 @@ func (c *Collector) verifySigstoreSignature(ctx context.Context, signedData, cms
 // pieces, verifies transparency-log inclusion, then the leaf certificate validity and
 // SCT — all against the embedded trust root with no network access — and finally
 // summarizes the authenticated identity.
-func (c *Collector) verifyEntry(ctx context.Context, leaf *x509.Certificate, signature, digest []byte, entryProto *rekorpb.TransparencyLogEntry, trustedRoot *root.TrustedRoot) (*sapi.Verification, error) {
+func (c *Collector) verifyEntry(ctx context.Context, leaf *x509.Certificate, signature, digest []byte, entryProto *rekorpb.TransparencyLogEntry, trustedRoot root.TrustedMaterial) (*sapi.Verification, error) {
 	entity, err := buildTlogBundle(ctx, leaf, signature, digest, entryProto)
 	if err != nil {
 		return nil, fmt.Errorf("assembling verification bundle: %w", err)
@@ -1058,7 +1114,7 @@ func extractTlogEntry(si *protocol.SignerInfo) (*rekorpb.TransparencyLogEntry, b
 // gitsign's own pkg/git.CertVerifier, including the "cosign hack" of pinning the
 // verification time to the leaf's NotBefore (the transparency log establishes the
 // real signing time separately).
-func verifyCMSSignature(signedData, cmsRaw []byte, leaf *x509.Certificate, trustedRoot *root.TrustedRoot) error {
+func verifyCMSSignature(signedData, cmsRaw []byte, leaf *x509.Certificate, trustedRoot root.TrustedMaterial) error {
 	sd, err := cms.ParseSignedData(cmsRaw)
 	if err != nil {
 		return fmt.Errorf("parsing CMS: %w", err)
@@ -18,6 +18,8 @@ import (
 	intoto "github.com/in-toto/attestation/go/v1"
 	gspredicate "github.com/sigstore/gitsign/pkg/predicate"
 	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/repository"
 )
 
 const (
@@ -248,6 +250,25 @@ func TestOpenRepoFileLocator(t *testing.T) {
 	require.NotNil(t, repo)
 }
 
+func TestOpenRepoOffline(t *testing.T) {
+	repoPath, _ := initTestRepo(t)
+
+	c, err := New(WithRepoPath(repoPath), WithOffline(true))
+	require.NoError(t, err)
+	repo, err := c.openRepo()
+	require.NoError(t, err)
+	require.NotNil(t, repo)
+
+	c, err = New(WithInitString("git+https://github.com/example/repo@abc123"), WithOffline(true))
+	require.NoError(t, err)
+	_, err = c.openRepo()
+	require.ErrorIs(t, err, repository.ErrOfflineMode)
+
+	// Without a supplied trust root, none is loaded offline
+	_, err = c.trusted()
+	require.ErrorIs(t, err, repository.ErrOfflineMode)
+}
+
 func TestWithLimit(t *testing.T) {
 	repoPath := filepath.Join(t.TempDir(), "test-repo")
 	repo, err := gogit.PlainInit(repoPath, false)
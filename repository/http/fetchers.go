@@ -6,67 +6,36 @@ package http
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"io"
+	nethttp "net/http"
 	"text/template"
 
 	"github.com/carabiner-dev/attestation"
+	"github.com/nozzle/throttler"
 	"sigs.k8s.io/release-utils/http"
 
 	"github.com/carabiner-dev/collector/envelope"
 	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
 )
 
+// maxParallelRequests is the number of URLs requested at the same time
+const maxParallelRequests = 5
+
 // fetchGeneral is the URL to retrieve all available attestations
-func fetchGeneral(_ context.Context, opts *Options, fo attestation.FetchOptions) ([]attestation.Envelope, error) {
+func fetchGeneral(ctx context.Context, opts *Options, fo attestation.FetchOptions) ([]attestation.Envelope, error) {
 	if len(opts.URLs) == 0 {
 		return nil, fmt.Errorf("unable to do request, url empty")
 	}
-
-	maxSize := readlimit.Resolve(fo.MaxReadSize)
-
-	// Create the agent
-	a := http.NewAgent().WithRetries(opts.Retries).WithFailOnHTTPError(true)
-
-	var attestations []attestation.Envelope
-	var err error
-	datas, errs := a.GetGroup(opts.URLs)
-	for i := range datas {
-		if errs[i] != nil {
-			// Don't take 404 as an error
-			if strings.Contains(errs[i].Error(), "HTTP error 404") {
-				continue
-			}
-			return nil, fmt.Errorf("fetching http data: %w", errs[i])
-		}
-
-		if int64(len(datas[i])) > maxSize {
-			return nil, fmt.Errorf("response from %s exceeds max read size (%d bytes)", opts.URLs[i], maxSize)
-		}
-
-		// Parse the request output
-		var atts []attestation.Envelope
-		if opts.ReadJSONL {
-			atts, err = envelope.NewJSONL().Parse(datas[i])
-		} else {
-			atts, err = envelope.Parsers.Parse(bytes.NewReader(datas[i]))
-		}
-		if err != nil {
-			return nil, fmt.Errorf("parsing attestation data: %w", err)
-		}
-		attestations = append(attestations, atts...)
-
-		if fo.Limit > 0 && len(attestations) >= fo.Limit {
-			return attestations[:fo.Limit], nil
-		}
-	}
-	return attestations, err
+	return fetchURLs(ctx, opts, fo, opts.URLs)
 }
 
 // fetchBySubject fetches the subject from the subject URL. If the collector
 // has specialized URL templates defined for name, digest or uri, then
 // those will be used to fetch data.
-func fetchBySubject(_ context.Context, opts *Options, fo attestation.FetchOptions, subjects []attestation.Subject) ([]attestation.Envelope, error) {
+func fetchBySubject(ctx context.Context, opts *Options, fo attestation.FetchOptions, subjects []attestation.Subject) ([]attestation.Envelope, error) {
 	var subjectNameTemplate, subjectDigestTemplate, subjectUriTemplate *template.Template
 	var err error
 
@@ -138,40 +107,10 @@ func fetchBySubject(_ context.Context, opts *Options, fo attestation.FetchOption
 		}
 	}
 
-	maxSize := readlimit.Resolve(fo.MaxReadSize)
-	attestations := []attestation.Envelope{}
-	datas, errs := http.NewAgent().WithRetries(opts.Retries).WithFailOnHTTPError(true).GetGroup(urls)
-	for i, data := range datas {
-		if errs[i] != nil {
-			if strings.Contains(errs[i].Error(), "HTTP error 404") {
-				continue
-			}
-			return nil, fmt.Errorf("error requesting data: %w", errs[i])
-		}
-
-		if int64(len(data)) > maxSize {
-			return nil, fmt.Errorf("response exceeds max read size (%d bytes)", maxSize)
-		}
-
-		var atts []attestation.Envelope
-		if opts.ReadJSONL {
-			atts, err = envelope.NewJSONL().Parse(data)
-		} else {
-			atts, err = envelope.Parsers.Parse(bytes.NewReader(data))
-		}
-		if err != nil {
-			return nil, fmt.Errorf("parsing attestation data: %w", err)
-		}
-		attestations = append(attestations, atts...)
-
-		if fo.Limit > 0 && len(attestations) >= fo.Limit {
-			return attestations[:fo.Limit], nil
-		}
-	}
-	return attestations, nil
+	return fetchURLs(ctx, opts, fo, urls)
 }
 
-func fetchByPredicateType(_ context.Context, opts *Options, fo attestation.FetchOptions, types []attestation.PredicateType) ([]attestation.Envelope, error) {
+func fetchByPredicateType(ctx context.Context, opts *Options, fo attestation.FetchOptions, types []attestation.PredicateType) ([]attestation.Envelope, error) {
 	tmpl, err := template.New("urltemplate").Parse(opts.TemplatePredicateType)
 	if err != nil {
 		return nil, fmt.Errorf("parsing predicate URL template: %w", err)
@@ -184,21 +123,69 @@ func fetchByPredicateType(_ context.Context, opts *Options, fo attestation.Fetch
 		}
 		urls = append(urls, b.String())
 	}
+	return fetchURLs(ctx, opts, fo, urls)
+}
+
+// fetchURLs requests the URLs in parallel and parses the responses in
+// order. The bodies are charged to the read budget of the fetch as they are
+// read, reading stops at the first response that does not fit. Responses
+// not found are skipped.
+func fetchURLs(ctx context.Context, opts *Options, fo attestation.FetchOptions, urls []string) ([]attestation.Envelope, error) {
 	maxSize := readlimit.Resolve(fo.MaxReadSize)
+
+	// The requests share the client of the agent, it is read once as the
+	// agent sets its options on every read.
+	a := http.NewAgent().WithRetries(opts.Retries).WithFailOnHTTPError(true)
+	client := a.Client()
+	resps := make([]*nethttp.Response, len(urls))
+	errs := make([]error, len(urls))
+	t := throttler.New(maxParallelRequests, len(urls))
+	for i, u := range urls {
+		go func() {
+			resps[i], errs[i] = a.SendGetRequest(client, u) //nolint:bodyclose // Closed below
+			t.Done(nil)
+		}()
+		t.Throttle()
+	}
+	defer func() {
+		for _, resp := range resps {
+			if resp != nil {
+				resp.Body.Close() //nolint:errcheck,gosec
+			}
+		}
+	}()
+
 	attestations := []attestation.Envelope{}
-	datas, errs := http.NewAgent().WithRetries(opts.Retries).WithFailOnHTTPError(true).GetGroup(urls)
-	for i, data := range datas {
+	for i, resp := range resps {
 		if errs[i] != nil {
-			if strings.Contains(err.Error(), "HTTP error 404") {
-				continue
-			}
-			return nil, fmt.Errorf("error requesting data: %w", err)
+			return nil, fmt.Errorf("fetching http data: %w", errs[i])
 		}
 
-		if int64(len(data)) > maxSize {
-			return nil, fmt.Errorf("response exceeds max read size (%d bytes)", maxSize)
+		// Don't take 404 as an error
+		if resp.StatusCode == nethttp.StatusNotFound {
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("fetching http data: HTTP error %s for %s", resp.Status, urls[i])
 		}
 
+		// Responses over the fetch read budget are dropped
+		if readlimit.Stop(ctx, urls[i]) {
+			break
+		}
+		data, err := io.ReadAll(io.LimitReader(readlimit.ConsumeReader(ctx, resp.Body, urls[i]), maxSize+1))
+		if errors.Is(err, readlimit.ErrExhausted) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading response from %s: %w", urls[i], err)
+		}
+
+		if readlimit.Exceeds(ctx, urls[i], int64(len(data)), fo.MaxReadSize) {
+			return nil, fmt.Errorf("response from %s exceeds max read size (%d bytes)", urls[i], maxSize)
+		}
+
+		// Parse the request output
 		var atts []attestation.Envelope
 		if opts.ReadJSONL {
 			atts, err = envelope.NewJSONL().Parse(data)
@@ -208,7 +195,10 @@ func fetchByPredicateType(_ context.Context, opts *Options, fo attestation.Fetch
 		if err != nil {
 			return nil, fmt.Errorf("parsing attestation data: %w", err)
 		}
-		attestations = append(attestations, atts...)
+		observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+			Source: urls[i], Count: len(atts), Bytes: int64(len(data)),
+		})
+		attestations = append(attestations, atts[:readlimit.ConsumeEnvelopes(ctx, urls[i], len(atts))]...)
 
 		if fo.Limit > 0 && len(attestations) >= fo.Limit {
 			return attestations[:fo.Limit], nil
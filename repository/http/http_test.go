@@ -5,11 +5,16 @@ package http
 
 import (
 	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 
 	"github.com/carabiner-dev/attestation"
 	intoto "github.com/in-toto/attestation/go/v1"
 	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/internal/readlimit"
 )
 
 func TestNew(t *testing.T) {
@@ -101,3 +106,23 @@ func TestFetchData(t *testing.T) {
 	}
 	//
 }
+
+func TestFetchBudget(t *testing.T) {
+	t.Parallel()
+	data, err := os.ReadFile("../jsonl/testdata/single.jsonl")
+	require.NoError(t, err)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, _ *nethttp.Request) {
+		w.Write(data) //nolint:errcheck,gosec
+	}))
+	t.Cleanup(server.Close)
+
+	// The budget has room for the first response only
+	ctx, tracker := readlimit.Track(readlimit.WithBudget(t.Context(), readlimit.NewBudget(int64(len(data))+10, 0)))
+	collector, err := New(WithURL(server.URL+"/a", server.URL+"/b", server.URL+"/c"))
+	require.NoError(t, err)
+	atts, err := collector.Fetch(ctx, attestation.FetchOptions{})
+	require.NoError(t, err)
+	require.Len(t, atts, 1)
+	require.Equal(t, []string{server.URL + "/b"}, tracker.Truncated())
+	require.True(t, readlimit.Stop(ctx, "next"))
+}
@@ -19,6 +19,8 @@ import (
 	"github.com/carabiner-dev/collector/envelope"
 	"github.com/carabiner-dev/collector/filters"
 	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
+	"github.com/carabiner-dev/collector/repository"
 )
 
 var TypeMoniker = "jsonl"
@@ -28,7 +30,10 @@ var Build = func(istr string) (attestation.Repository, error) {
 	return New(WithPath(istr))
 }
 
-var _ attestation.Fetcher = (*Collector)(nil)
+var (
+	_ attestation.Fetcher     = (*Collector)(nil)
+	_ repository.OfflineAware = (*Collector)(nil)
+)
 
 func New(funcs ...optFn) (*Collector, error) {
 	// Apply the functional options
@@ -50,14 +55,18 @@ type Collector struct {
 	Options Options
 }
 
+// SetOffline implements repository.OfflineAware. The collector only reads
+// local files so it works the same in offline mode.
+func (c *Collector) SetOffline(bool) {}
+
 // readAttestations
-func (c *Collector) readAttestations(opts *attestation.FetchOptions, paths []string, filterset *attestation.FilterSet) ([]attestation.Envelope, error) {
-	t := throttler.New(c.Options.MaxParallel, len(paths))
+func (c *Collector) readAttestations(ctx context.Context, opts *attestation.FetchOptions, paths []string, filterset *attestation.FilterSet) ([]attestation.Envelope, error) {
+	t := throttler.New(max(c.Options.MaxParallel, 1), len(paths))
 	ret := []attestation.Envelope{}
 	mtx := sync.Mutex{}
 	for _, path := range paths {
 		go func() {
-			moreAtts, err := parseJsonlFile(opts, path, filterset)
+			moreAtts, err := parseJsonlFile(ctx, opts, path, filterset)
 			if err != nil {
 				t.Done(err)
 				return
@@ -77,7 +86,7 @@ func (c *Collector) readAttestations(opts *attestation.FetchOptions, paths []str
 
 // parseJsonlFile uses the carabiner jsonl module to parse a jsonl bundle and
 // get all the attestations in it.
-func parseJsonlFile(opts *attestation.FetchOptions, path string, filterset *attestation.FilterSet) ([]attestation.Envelope, error) {
+func parseJsonlFile(ctx context.Context, opts *attestation.FetchOptions, path string, filterset *attestation.FilterSet) ([]attestation.Envelope, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("opening %q: %w", path, err)
@@ -87,6 +96,14 @@ func parseJsonlFile(opts *attestation.FetchOptions, path string, filterset *atte
 	}
 	ret := []attestation.Envelope{}
 
+	// Files are not read once the fetch read budget runs out
+	if readlimit.Stop(ctx, path) {
+		return ret, nil
+	}
+	if info, err := f.Stat(); err == nil && !readlimit.Consume(ctx, path, min(info.Size(), readlimit.Resolve(opts.MaxReadSize))) {
+		return ret, nil
+	}
+
 	for i, r := range cjsonl.IterateBundle(readlimit.Reader(f, opts.MaxReadSize)) {
 		if r == nil {
 			continue
@@ -97,12 +114,19 @@ func parseJsonlFile(opts *attestation.FetchOptions, path string, filterset *atte
 		if err != nil {
 			return nil, fmt.Errorf("parsing attestation %d in %q: %w", i, path, err)
 		}
+		observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+			Source: fmt.Sprintf("%s#%d", path, i), Count: len(envelopes),
+		})
 
 		// If the json did not return anything (not likely)
 		if len(envelopes) == 0 {
 			continue
 		}
 
+		if readlimit.ConsumeEnvelopes(ctx, path, len(envelopes)) < len(envelopes) {
+			break
+		}
+
 		// Complete the attestation source, we know that the envelope returns max 1
 		// attestation per line
 		if envelopes[0].GetStatement() != nil &&
@@ -127,7 +151,7 @@ func parseJsonlFile(opts *attestation.FetchOptions, path string, filterset *atte
 
 // Fetch queries the repository and retrieves any attestations matching the query
 func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
-	return c.readAttestations(&opts, c.Options.Paths, &attestation.FilterSet{})
+	return c.readAttestations(ctx, &opts, c.Options.Paths, &attestation.FilterSet{})
 }
 
 // FetchBySubject calls the attestation reader with a filter preconfigured
@@ -141,7 +165,7 @@ func (c *Collector) FetchBySubject(ctx context.Context, opts attestation.FetchOp
 		matcher.HashSets = append(matcher.HashSets, s.GetDigest())
 	}
 
-	atts, err := c.readAttestations(&opts, c.Options.Paths, &attestation.FilterSet{matcher})
+	atts, err := c.readAttestations(ctx, &opts, c.Options.Paths, &attestation.FilterSet{matcher})
 	if err != nil {
 		return nil, fmt.Errorf("reading attestation: %w", err)
 	}
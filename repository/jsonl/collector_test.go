@@ -8,6 +8,8 @@ import (
 
 	"github.com/carabiner-dev/attestation"
 	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/observer"
 )
 
 func TestParseJsonlFile(t *testing.T) {
@@ -24,7 +26,7 @@ func TestParseJsonlFile(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			atts, err := parseJsonlFile(&attestation.FetchOptions{}, tc.srcData, nil)
+			atts, err := parseJsonlFile(t.Context(), &attestation.FetchOptions{}, tc.srcData, nil)
 			if tc.mustErr {
 				require.Error(t, err)
 				return
@@ -35,6 +37,17 @@ func TestParseJsonlFile(t *testing.T) {
 	}
 }
 
+func TestParseJsonlFileEvents(t *testing.T) {
+	t.Parallel()
+	rec := observer.NewRecorder()
+	ctx := observer.WithObserver(t.Context(), rec)
+	atts, err := parseJsonlFile(ctx, &attestation.FetchOptions{}, "testdata/multiple.jsonl", nil)
+	require.NoError(t, err)
+	require.Len(t, rec.ParsedEnvelopes, len(atts))
+	require.Equal(t, "testdata/multiple.jsonl#0", rec.ParsedEnvelopes[0].Source)
+	require.Equal(t, 1, rec.ParsedEnvelopes[0].Count)
+}
+
 func TestReadAttestations(t *testing.T) {
 	t.Parallel()
 	for _, tc := range []struct {
@@ -56,7 +69,7 @@ func TestReadAttestations(t *testing.T) {
 					Paths:       []string{},
 				},
 			}
-			atts, err := c.readAttestations(&attestation.FetchOptions{}, tc.files, nil)
+			atts, err := c.readAttestations(t.Context(), &attestation.FetchOptions{}, tc.files, nil)
 			if tc.mustErr {
 				require.Error(t, err)
 				return
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -26,6 +27,7 @@ import (
 	"github.com/carabiner-dev/collector/envelope"
 	"github.com/carabiner-dev/collector/filters"
 	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
 	"github.com/carabiner-dev/collector/predicate/generic"
 	"github.com/carabiner-dev/collector/repository/filesystem"
 	"github.com/carabiner-dev/collector/statement/intoto"
@@ -75,14 +77,14 @@ func (c *Collector) SetKeys(keys []key.PublicKeyProvider) {
 // Fetch retrieves attestations for the configured package URL. When the
 // collector is in global mode (no package URL configured) it returns
 // nothing — global mode is subject-driven, use FetchBySubject instead.
-func (c *Collector) Fetch(_ context.Context, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
+func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
 	if !c.Options.HasPackageURL() {
 		return nil, nil
 	}
 	// In configured mode the collector's BaseURL is authoritative — it
 	// already reflects any "repository_url" qualifier captured at
 	// construction time and preserves explicit WithBaseURL overrides.
-	return c.fetchForPurl(opts, &c.Options.PackageURL, c.Options.BaseURL)
+	return c.fetchForPurl(ctx, opts, &c.Options.PackageURL, c.Options.BaseURL)
 }
 
 // fetchForPurl runs the full attestation lookup for a single Maven purl
@@ -90,7 +92,7 @@ func (c *Collector) Fetch(_ context.Context, opts attestation.FetchOptions) ([]a
 // configured mode passes the collector's BaseURL as-is; global mode
 // resolves per-subject via baseURLForPurl so a "repository_url"
 // qualifier on a subject purl can target its own repository.
-func (c *Collector) fetchForPurl(opts attestation.FetchOptions, purl *gopurl.PackageURL, baseURL string) ([]attestation.Envelope, error) {
+func (c *Collector) fetchForPurl(ctx context.Context, opts attestation.FetchOptions, purl *gopurl.PackageURL, baseURL string) ([]attestation.Envelope, error) {
 	dirURL := directoryURL(purl, baseURL)
 	artifactID := purl.Name
 	agent := http.NewAgent().WithFailOnHTTPError(true)
@@ -102,19 +104,19 @@ func (c *Collector) fetchForPurl(opts attestation.FetchOptions, purl *gopurl.Pac
 
 	var ret []attestation.Envelope
 
-	ascEnvs, err := c.fetchSignature(agent, dirURL, purl, md, opts)
+	ascEnvs, err := c.fetchSignature(ctx, agent, dirURL, purl, md, opts)
 	if err != nil {
 		return nil, err
 	}
 	ret = append(ret, ascEnvs...)
 
-	jsonlEnvs, err := c.fetchJSONLAttestations(agent, dirURL, artifactID, md, opts)
+	jsonlEnvs, err := c.fetchJSONLAttestations(ctx, agent, dirURL, artifactID, md, opts)
 	if err != nil {
 		return nil, err
 	}
 	ret = append(ret, jsonlEnvs...)
 
-	sbomEnvs, err := c.fetchSBOMs(agent, dirURL, artifactID, md, opts)
+	sbomEnvs, err := c.fetchSBOMs(ctx, agent, dirURL, artifactID, md, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -152,7 +154,7 @@ func (c *Collector) FetchBySubject(ctx context.Context, opts attestation.FetchOp
 			// Global mode: a per-subject purl can carry its own
 			// "repository_url" qualifier; fall back to the collector's
 			// BaseURL when it doesn't.
-			envs, err := c.fetchForPurl(opts, p, baseURLForPurl(p, c.Options.BaseURL))
+			envs, err := c.fetchForPurl(ctx, opts, p, baseURLForPurl(p, c.Options.BaseURL))
 			if err != nil {
 				// A missing or unreachable package for one subject shouldn't
 				// fail the whole query — log and continue.
@@ -284,7 +286,7 @@ func findSnapshotVersion(md *mavenMetadata, extension, classifier string) (snaps
 // purl "type" and "classifier" qualifiers so the hashed subject matches
 // the artifact the purl refers to. Returns nil without error if the
 // artifacts are not in the metadata or no keys are configured.
-func (c *Collector) fetchSignature(agent *http.Agent, dirURL string, purl *gopurl.PackageURL, md *mavenMetadata, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
+func (c *Collector) fetchSignature(ctx context.Context, agent *http.Agent, dirURL string, purl *gopurl.PackageURL, md *mavenMetadata, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
 	if len(c.Keys) == 0 {
 		return nil, nil
 	}
@@ -310,14 +312,17 @@ func (c *Collector) fetchSignature(agent *http.Agent, dirURL string, purl *gopur
 	ascFile := resolveFilename(artifactID, ascSV)
 	maxSize := readlimit.Resolve(opts.MaxReadSize)
 
-	// Fetch the artifact and its signature in parallel.
-	urls := []string{dirURL + artFile, dirURL + ascFile}
-	datas, errs := agent.GetGroup(urls)
-
-	for i, e := range errs {
-		if e != nil {
-			return nil, fmt.Errorf("fetching %s: %w", urls[i], e)
+	// Fetch the artifact and its signature
+	datas := [][]byte{}
+	for _, u := range []string{dirURL + artFile, dirURL + ascFile} {
+		data, ok, err := getFile(ctx, agent, u, maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", u, err)
+		}
+		if !ok {
+			return nil, nil
 		}
+		datas = append(datas, data)
 	}
 
 	artData, sigData := datas[0], datas[1]
@@ -343,7 +348,7 @@ func (c *Collector) fetchSignature(agent *http.Agent, dirURL string, purl *gopur
 
 // fetchJSONLAttestations looks for intoto.jsonl in the metadata and parses
 // it for attestation envelopes. Returns nil without error if not present.
-func (c *Collector) fetchJSONLAttestations(agent *http.Agent, dirURL, artifactID string, md *mavenMetadata, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
+func (c *Collector) fetchJSONLAttestations(ctx context.Context, agent *http.Agent, dirURL, artifactID string, md *mavenMetadata, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
 	sv, ok := findSnapshotVersion(md, "intoto.jsonl", "")
 	if !ok {
 		return nil, nil
@@ -352,16 +357,54 @@ func (c *Collector) fetchJSONLAttestations(agent *http.Agent, dirURL, artifactID
 	filename := resolveFilename(artifactID, sv)
 	maxSize := readlimit.Resolve(opts.MaxReadSize)
 
-	data, err := agent.Get(dirURL + filename)
+	data, ok, err := getFile(ctx, agent, dirURL+filename, maxSize)
 	if err != nil {
 		return nil, fmt.Errorf("fetching %s: %w", filename, err)
 	}
+	if !ok {
+		return nil, nil
+	}
 
 	if int64(len(data)) > maxSize {
 		return nil, fmt.Errorf("JSONL file %s exceeds max read size (%d bytes)", filename, maxSize)
 	}
 
-	return envelope.NewJSONL().Parse(data)
+	envs, err := envelope.NewJSONL().Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+		Source: dirURL + filename, Count: len(envs), Bytes: int64(len(data)),
+	})
+	return envs, nil
+}
+
+// getFile downloads a file from the repository, charging it to the read
+// budget of the fetch as it is read. It returns false when the budget has
+// no room for the file. Files over maxSize are cut at maxSize+1 bytes for
+// the callers to reject them.
+func getFile(ctx context.Context, agent *http.Agent, url string, maxSize int64) ([]byte, bool, error) {
+	if readlimit.Stop(ctx, url) {
+		return nil, false, nil
+	}
+	resp, err := agent.GetRequest(url)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, false, fmt.Errorf("HTTP error %s for %s", resp.Status, url)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(readlimit.ConsumeReader(ctx, resp.Body, url), maxSize+1))
+	if errors.Is(err, readlimit.ErrExhausted) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("reading %s: %w", url, err)
+	}
+	return data, true, nil
 }
 
 // sbomExtensions lists the metadata extensions we recognize as SBOMs,
@@ -377,7 +420,7 @@ var sbomExtensions = []struct {
 
 // fetchSBOMs looks for unsigned SBOM files in the metadata.
 // Returns nil without error if none are present.
-func (c *Collector) fetchSBOMs(agent *http.Agent, dirURL, artifactID string, md *mavenMetadata, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
+func (c *Collector) fetchSBOMs(ctx context.Context, agent *http.Agent, dirURL, artifactID string, md *mavenMetadata, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
 	maxSize := readlimit.Resolve(opts.MaxReadSize)
 	var ret []attestation.Envelope
 
@@ -388,10 +431,13 @@ func (c *Collector) fetchSBOMs(agent *http.Agent, dirURL, artifactID string, md
 		}
 
 		filename := resolveFilename(artifactID, sv)
-		data, err := agent.Get(dirURL + filename)
+		data, ok, err := getFile(ctx, agent, dirURL+filename, maxSize)
 		if err != nil {
 			return nil, fmt.Errorf("fetching SBOM %s: %w", filename, err)
 		}
+		if !ok {
+			break
+		}
 
 		if int64(len(data)) > maxSize {
 			return nil, fmt.Errorf("SBOM %s exceeds max read size (%d bytes)", filename, maxSize)
@@ -401,6 +447,9 @@ func (c *Collector) fetchSBOMs(agent *http.Agent, dirURL, artifactID string, md
 		if err != nil {
 			return nil, fmt.Errorf("parsing SBOM %s: %w", filename, err)
 		}
+		observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+			Source: dirURL + filename, Count: len(envs), Bytes: int64(len(data)),
+		})
 
 		ret = append(ret, envs...)
 	}
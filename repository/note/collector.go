@@ -21,6 +21,8 @@ import (
 	"github.com/carabiner-dev/collector/envelope"
 	"github.com/carabiner-dev/collector/filters"
 	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
+	"github.com/carabiner-dev/collector/repository"
 )
 
 var TypeMoniker = "note"
@@ -63,6 +65,10 @@ type Options struct {
 
 	// DynamicRepoURL holds the repostory URL for the dynamic notes collector
 	DynamicRepoURL string
+
+	// Offline restricts the collector to local file:// repositories and
+	// disables pushing.
+	Offline bool
 }
 
 var defaultOptions Options
@@ -98,6 +104,17 @@ func WithHttpAuth(username, password string) optFn {
 	}
 }
 
+func WithOffline(offline bool) optFn {
+	return func(opts *Options) {
+		opts.Offline = offline
+	}
+}
+
+// SetOffline implements the repository.OfflineAware interface.
+func (c *Collector) SetOffline(offline bool) {
+	c.Options.Offline = offline
+}
+
 func (o *Options) Validate() error {
 	return nil
 }
@@ -111,7 +128,9 @@ func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([
 		return nil, err
 	}
 
-	for i, r := range jsonl.IterateBundle(readlimit.Reader(reader, opts.MaxReadSize)) {
+	// The notes are charged to the read budget of the fetch as they are read
+	reader = readlimit.ConsumeReader(ctx, reader, c.Options.Locator)
+	for i, r := range jsonl.IterateBundle(readlimit.ReaderContext(ctx, reader, opts.MaxReadSize, c.Options.Locator)) {
 		if r == nil {
 			continue
 		}
@@ -121,6 +140,9 @@ func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([
 		if err != nil {
 			return nil, fmt.Errorf("parsing attestation %d in %q: %w", i, c.Options.Locator, err)
 		}
+		observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+			Source: fmt.Sprintf("%s#%d", c.Options.Locator, i), Count: len(envelopes),
+		})
 
 		// If the json did not return anything (not likely)
 		if len(envelopes) == 0 {
@@ -167,6 +189,10 @@ func (c *Collector) extractCommitBundle() (io.Reader, error) {
 		return nil, fmt.Errorf("VCS locator must specify a commit sha")
 	}
 
+	if c.Options.Offline && components.Transport != vcslocator.TransportFile {
+		return nil, fmt.Errorf("reading notes from %s: %w", components.RepoURL(), repository.ErrOfflineMode)
+	}
+
 	path := components.Commit[0:2] + "/" + components.Commit[2:]
 
 	// We need two locators because we will check for sharded notes data
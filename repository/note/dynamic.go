@@ -50,6 +50,11 @@ func NewDynamic(funcs ...optFn) (*Dynamic, error) {
 	}, nil
 }
 
+// SetOffline implements the repository.OfflineAware interface.
+func (c *Dynamic) SetOffline(offline bool) {
+	c.Options.Offline = offline
+}
+
 // Fetch is a noop only to implement the main interface
 func (c *Dynamic) Fetch(ctx context.Context, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
 	return []attestation.Envelope{}, nil
@@ -91,6 +96,7 @@ func (c *Dynamic) FetchBySubject(ctx context.Context, opts attestation.FetchOpti
 		notesCollector, err := New(
 			WithLocator(fmt.Sprintf("%s@%s", c.Options.DynamicRepoURL, commit)),
 			WithHttpAuth(c.Options.HttpUsername, c.Options.HttpPassword),
+			WithOffline(c.Options.Offline),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("building collector for commit %s: %w", commit, err)
@@ -130,6 +136,7 @@ func (c *Dynamic) Store(ctx context.Context, opts attestation.StoreOptions, enve
 			WithLocator(fmt.Sprintf("%s@%s", c.Options.DynamicRepoURL, commit)),
 			WithHttpAuth(c.Options.HttpUsername, c.Options.HttpPassword),
 			WithPush(*c.Options.Push),
+			WithOffline(c.Options.Offline),
 		)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("building collector for commit %s: %w", commit, err))
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package note
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/carabiner-dev/vcslocator"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/carabiner-dev/collector/repository"
+)
+
+var (
+	_ repository.Prober       = (*Collector)(nil)
+	_ repository.Prober       = (*Dynamic)(nil)
+	_ repository.OfflineAware = (*Collector)(nil)
+	_ repository.OfflineAware = (*Dynamic)(nil)
+)
+
+// Probe checks that the git repository is reachable and has a notes ref.
+func (c *Collector) Probe(ctx context.Context) error {
+	return probeNotes(ctx, c.Options.Locator, &c.Options)
+}
+
+// Probe checks that the git repository is reachable and has a notes ref.
+func (c *Dynamic) Probe(ctx context.Context) error {
+	return probeNotes(ctx, c.Options.DynamicRepoURL, &c.Options)
+}
+
+// probeNotes looks for the notes ref in the repository pointed by locator.
+// Local repositories are opened, remote ones are only listed, not cloned.
+func probeNotes(ctx context.Context, locator string, opts *Options) error {
+	if locator == "" {
+		return errors.New("no VCS locator set")
+	}
+
+	components, err := vcslocator.Locator(locator).Parse()
+	if err != nil {
+		return fmt.Errorf("parsing VCS locator: %w", err)
+	}
+
+	if components.Transport == vcslocator.TransportFile {
+		localPath, err := vcslocator.Locator(locator).LocalPath()
+		if err != nil {
+			return fmt.Errorf("resolving local path: %w", err)
+		}
+		repo, err := git.PlainOpen(localPath)
+		if err != nil {
+			return fmt.Errorf("opening local repository: %w", err)
+		}
+		if _, err := repo.Reference(plumbing.ReferenceName(notesRef), true); err != nil {
+			return fmt.Errorf("reading %s: %w", notesRef, err)
+		}
+		return nil
+	}
+
+	if opts.Offline {
+		return fmt.Errorf("listing remote refs: %w", repository.ErrOfflineMode)
+	}
+
+	auth, err := vcslocator.GetAuthMethod(
+		locator, vcslocator.WithHttpAuth(opts.HttpUsername, opts.HttpPassword),
+	)
+	if err != nil {
+		return fmt.Errorf("getting auth method: %w", err)
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{components.RepoURL()},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("listing remote refs: %w", err)
+	}
+	for _, ref := range refs {
+		if ref.Name().String() == notesRef {
+			return nil
+		}
+	}
+	return fmt.Errorf("remote repository has no %s ref", notesRef)
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package note
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/carabiner-dev/vcslocator"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+	repoPath := filepath.Join(t.TempDir(), "test-repo")
+	repo, err := git.PlainInit(repoPath, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "test.txt"), []byte("test content"), 0o600))
+	_, err = wt.Add("test.txt")
+	require.NoError(t, err)
+	commitHash, err := wt.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Carabiner Test Robot", Email: "bot@carabeenr.com"},
+	})
+	require.NoError(t, err)
+
+	locator := string(vcslocator.NewFromPath(repoPath)) + "@" + commitHash.String()
+	collector, err := New(WithLocator(locator), WithPush(false))
+	require.NoError(t, err)
+
+	// No notes yet
+	require.Error(t, collector.Probe(t.Context()))
+
+	require.NoError(t, collector.Store(t.Context(), attestation.StoreOptions{}, []attestation.Envelope{createTestAttestation(t)}))
+	require.NoError(t, collector.Probe(t.Context()))
+
+	require.Error(t, (&Collector{}).Probe(t.Context()))
+}
@@ -21,6 +21,8 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/carabiner-dev/collector/repository"
 )
 
 var _ attestation.Storer = (*Collector)(nil)
@@ -51,6 +53,10 @@ func (c *Collector) Store(ctx context.Context, opts attestation.StoreOptions, en
 		shouldPush = *c.Options.Push
 	}
 
+	if c.Options.Offline && (shouldPush || components.Transport != vcslocator.TransportFile) {
+		return fmt.Errorf("writing notes to %s: %w", c.Options.Locator, repository.ErrOfflineMode)
+	}
+
 	// Open or clone the repository
 	repo, err := c.openOrCloneRepoForNotes(components)
 	if err != nil {
@@ -14,6 +14,7 @@ import (
 	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
 	"github.com/regclient/regclient/types/descriptor"
 	"github.com/regclient/regclient/types/manifest"
 	"github.com/regclient/regclient/types/ref"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/carabiner-dev/collector/envelope/bundle"
 	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
 )
 
 const (
@@ -116,6 +118,11 @@ func (c *Collector) newRegClient() *regclient.RegClient {
 	if len(rcOpts) == 0 {
 		rcOpts = []regclient.Opt{regclient.WithDockerCreds(), regclient.WithDockerCerts()}
 	}
+	if c.Options.Insecure {
+		if r, err := ref.New(c.Options.Reference); err == nil {
+			rcOpts = append(rcOpts, regclient.WithConfigHost(config.Host{Name: r.Registry, TLS: config.TLSDisabled}))
+		}
+	}
 	return regclient.New(rcOpts...)
 }
 
@@ -258,13 +265,20 @@ func (c *Collector) fetchReferrer(
 
 	var atts []attestation.Envelope
 	for j := range layers {
+		source := rRef.CommonName() + "#" + layers[j].Digest.String()
+
+		// Stop pulling layers when the fetch read budget runs out
+		if readlimit.Stop(ctx, source) || !readlimit.Consume(ctx, source, layers[j].Size) {
+			break
+		}
+
 		blob, err := rc.BlobGet(ctx, rRef, layers[j])
 		if err != nil {
 			logrus.Debugf("oci: skipping layer %d: pulling blob: %v", j, err)
 			continue
 		}
 
-		data, err := io.ReadAll(readlimit.Reader(blob, opts.MaxReadSize))
+		data, err := io.ReadAll(readlimit.ReaderContext(ctx, blob, opts.MaxReadSize, source))
 		if err := blob.Close(); err != nil {
 			logrus.Debugf("oci: closing blob %d: %v", j, err)
 		}
@@ -276,10 +290,16 @@ func (c *Collector) fetchReferrer(
 		envs, err := parser.Parse(data)
 		if err != nil {
 			logrus.Debugf("oci: skipping layer %d: parsing bundle: %v", j, err)
+			observer.FromContext(ctx).ParseSkipped(ctx, observer.ParseSkippedEvent{
+				Source: source, Reason: "unparseable bundle", Error: err,
+			})
 			continue
 		}
+		observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+			Source: source, Count: len(envs), Bytes: int64(len(data)),
+		})
 
-		atts = append(atts, envs...)
+		atts = append(atts, envs[:readlimit.ConsumeEnvelopes(ctx, source, len(envs))]...)
 	}
 
 	return atts, nil
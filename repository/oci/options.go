@@ -14,7 +14,9 @@ type (
 	optFn   = func(*Options) error
 	Options struct {
 		Reference string
-		regOpts   []regclient.Opt // optional regclient overrides (e.g. for testing)
+		// Insecure connects to the registry over plain HTTP.
+		Insecure bool
+		regOpts  []regclient.Opt // optional regclient overrides (e.g. for testing)
 	}
 )
 
@@ -31,6 +33,14 @@ func WithReference(r string) optFn {
 	}
 }
 
+// WithInsecure connects to the registry of the reference over plain HTTP.
+func WithInsecure(insecure bool) optFn {
+	return func(o *Options) error {
+		o.Insecure = insecure
+		return nil
+	}
+}
+
 // WithRegClientOpts sets custom regclient options (e.g. for testing).
 func WithRegClientOpts(opts ...regclient.Opt) optFn {
 	return func(o *Options) error {
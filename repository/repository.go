@@ -3,7 +3,17 @@
 
 package repository
 
-import "github.com/carabiner-dev/signer/key"
+import (
+	"context"
+	"errors"
+
+	"github.com/carabiner-dev/signer/key"
+	"github.com/sigstore/sigstore-go/pkg/root"
+)
+
+// ErrOfflineMode is returned by repositories running in offline mode when an
+// operation requires network access.
+var ErrOfflineMode = errors.New("operation requires network access, not allowed in offline mode")
 
 // SignatureVerifier is implemented by repositories that support key-based
 // signature verification. The agent distributes its keys to any
@@ -11,3 +21,27 @@ import "github.com/carabiner-dev/signer/key"
 type SignatureVerifier interface {
 	SetKeys(keys []key.PublicKeyProvider)
 }
+
+// Prober is implemented by repositories that can check their health before
+// being used, for example that a registry is reachable, that a token is
+// valid or that a notes ref exists. Probe returns nil when the repository
+// is ready to be read.
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
+// OfflineAware is implemented by repositories that can work without network
+// access. When the agent runs in offline mode it calls SetOffline(true) on
+// them, after which any operation requiring the network either degrades
+// gracefully or fails with ErrOfflineMode. Repositories not implementing
+// this interface are not used in offline mode.
+type OfflineAware interface {
+	SetOffline(offline bool)
+}
+
+// TrustedRootSetter is implemented by repositories that verify sigstore
+// signatures. The agent sends its trust root to them so they don't need to
+// fetch one from the network.
+type TrustedRootSetter interface {
+	SetTrustedRoot(tm root.TrustedMaterial)
+}
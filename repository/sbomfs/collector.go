@@ -19,8 +19,10 @@ import (
 	"github.com/protobom/protobom/pkg/reader"
 	"github.com/protobom/protobom/pkg/sbom"
 	"github.com/protobom/protobom/pkg/writer"
+	"github.com/sigstore/sigstore-go/pkg/root"
 
 	"github.com/carabiner-dev/collector/filters"
+	"github.com/carabiner-dev/collector/repository"
 	"github.com/carabiner-dev/collector/repository/filesystem"
 )
 
@@ -31,15 +33,19 @@ var Build = func(istr string) (attestation.Repository, error) {
 }
 
 var (
-	_ attestation.Fetcher = (*Collector)(nil)
-	_ attestation.Storer  = (*Collector)(nil)
+	_ attestation.Fetcher          = (*Collector)(nil)
+	_ attestation.Storer           = (*Collector)(nil)
+	_ repository.OfflineAware      = (*Collector)(nil)
+	_ repository.TrustedRootSetter = (*Collector)(nil)
 )
 
 type Collector struct {
-	Options Options
-	Keys    []key.PublicKeyProvider
-	doc     *sbom.Document
-	fs      *sbomfslib.FS
+	Options     Options
+	Keys        []key.PublicKeyProvider
+	offline     bool
+	trustedRoot root.TrustedMaterial
+	doc         *sbom.Document
+	fs          *sbomfslib.FS
 }
 
 func New(funcs ...optFn) (*Collector, error) {
@@ -75,11 +81,24 @@ func (c *Collector) SetKeys(keys []key.PublicKeyProvider) {
 	c.Keys = keys
 }
 
+// SetOffline implements repository.OfflineAware. The SBOM is read from
+// disk, offline mode is passed to the inner driver to verify signatures.
+func (c *Collector) SetOffline(offline bool) {
+	c.offline = offline
+}
+
+// SetTrustedRoot implements repository.TrustedRootSetter.
+func (c *Collector) SetTrustedRoot(tm root.TrustedMaterial) {
+	c.trustedRoot = tm
+}
+
 // Fetch queries the sbomfs and retrieves any attestations stored as properties.
 func (c *Collector) Fetch(ctx context.Context, opts attestation.FetchOptions) ([]attestation.Envelope, error) {
 	driver, err := filesystem.New(
 		filesystem.WithFS(c.fs),
 		filesystem.WithKey(c.Keys...),
+		filesystem.WithOffline(c.offline),
+		filesystem.WithTrustedRoot(c.trustedRoot),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("creating filesystem collector driver: %w", err)
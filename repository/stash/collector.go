@@ -16,6 +16,8 @@ import (
 	stashconfig "github.com/carabiner-dev/stash/pkg/client/config"
 
 	"github.com/carabiner-dev/collector/envelope"
+	"github.com/carabiner-dev/collector/internal/readlimit"
+	"github.com/carabiner-dev/collector/observer"
 )
 
 // TypeMoniker is the string identifying the stash repository type.
@@ -247,14 +249,26 @@ func (c *Collector) query(ctx context.Context, opts attestation.FetchOptions, fi
 
 	envelopes := make([]attestation.Envelope, 0, len(ids))
 	for _, id := range ids {
+		// The client returns whole attestations, they are charged to the
+		// read budget of the fetch once downloaded.
+		source := c.Options.Org + "/" + c.Options.Namespace + "#" + id
+		if readlimit.Stop(ctx, source) {
+			break
+		}
 		raw, err := c.client.GetAttestationRaw(ctx, c.Options.Org, c.Options.Namespace, id)
 		if err != nil {
 			return nil, fmt.Errorf("getting raw attestation %s: %w", id, err)
 		}
+		if !readlimit.Consume(ctx, source, int64(len(raw))) {
+			break
+		}
 		envs, err := c.parsers.Parse(bytes.NewReader(raw))
 		if err != nil {
 			return nil, fmt.Errorf("parsing attestation %s: %w", id, err)
 		}
+		observer.FromContext(ctx).EnvelopeParsed(ctx, observer.EnvelopeParsedEvent{
+			Source: source, Count: len(envs), Bytes: int64(len(raw)),
+		})
 		envelopes = append(envelopes, envs...)
 	}
 	if opts.Limit > 0 && len(envelopes) > opts.Limit {
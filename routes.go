@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/carabiner-dev/attestation"
+)
+
+// StoreRoute is a rule that decides which envelopes are sent to a storer
+// repository. An envelope matches a route when it satisfies all of the
+// route's non-empty criteria.
+//
+// Storers with routes only receive the envelopes matching any of them.
+// Storers without routes receive all envelopes.
+type StoreRoute struct {
+	// Repository selects the storers the route applies to. It can be
+	// an init string (dnote:.) to match a single repository or a type
+	// moniker (dnote) to match all repositories of that type.
+	Repository string
+
+	// PredicateTypes matches envelopes of any of the predicate types.
+	PredicateTypes []attestation.PredicateType
+
+	// DigestAlgorithms matches envelopes with at least one subject
+	// having a digest of any of the algorithms (eg gitCommit, sha256).
+	DigestAlgorithms []string
+
+	// SubjectNames matches envelopes with at least one subject whose name
+	// matches any of the patterns. Patterns use the path.Match syntax.
+	SubjectNames []string
+}
+
+// Validate checks the route definition.
+func (r *StoreRoute) Validate() error {
+	errs := []error{}
+	if r.Repository == "" {
+		errs = append(errs, errors.New("store route has no repository"))
+	}
+	for _, p := range r.SubjectNames {
+		if _, err := path.Match(p, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid subject name pattern %q: %w", p, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Matches returns true if an envelope satisfies the route criteria.
+func (r *StoreRoute) Matches(env attestation.Envelope) bool {
+	s := env.GetStatement()
+	if s == nil {
+		return len(r.PredicateTypes) == 0 && len(r.DigestAlgorithms) == 0 && len(r.SubjectNames) == 0
+	}
+
+	if len(r.PredicateTypes) > 0 && !slices.Contains(r.PredicateTypes, s.GetPredicateType()) {
+		return false
+	}
+
+	if len(r.DigestAlgorithms) > 0 && !slices.ContainsFunc(s.GetSubjects(), func(subject attestation.Subject) bool {
+		for algo := range subject.GetDigest() {
+			if slices.ContainsFunc(r.DigestAlgorithms, func(a string) bool {
+				return strings.EqualFold(a, algo)
+			}) {
+				return true
+			}
+		}
+		return false
+	}) {
+		return false
+	}
+
+	if len(r.SubjectNames) > 0 && !slices.ContainsFunc(s.GetSubjects(), func(subject attestation.Subject) bool {
+		for _, p := range r.SubjectNames {
+			if ok, err := path.Match(p, subject.GetName()); err == nil && ok {
+				return true
+			}
+		}
+		return false
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// storeRoutesFor returns the routes that apply to a repository. The init
+// strings of the routes are redacted like those of the repositories.
+func (agent *Agent) storeRoutesFor(repo any) []StoreRoute {
+	src := agent.repoSource(repo)
+	ret := []StoreRoute{}
+	for _, route := range agent.Options.StoreRoutes {
+		if route.Repository == src.Moniker || (src.Init != "" && redactInit(route.Repository) == src.Init) {
+			ret = append(ret, route)
+		}
+	}
+	return ret
+}
+
+// routeEnvelopes returns the envelopes that must be sent to a storer
+// according to the configured store routes.
+func (agent *Agent) routeEnvelopes(repo any, envelopes []attestation.Envelope) []attestation.Envelope {
+	routes := agent.storeRoutesFor(repo)
+	if len(routes) == 0 {
+		return envelopes
+	}
+
+	ret := []attestation.Envelope{}
+	for _, env := range envelopes {
+		if slices.ContainsFunc(routes, func(r StoreRoute) bool { return r.Matches(env) }) {
+			ret = append(ret, env)
+		}
+	}
+	return ret
+}
+
+// unroutedEnvelopes returns the envelopes that the store routes send to none
+// of the storers.
+func (agent *Agent) unroutedEnvelopes(repos []attestation.Storer, envelopes []attestation.Envelope) []attestation.Envelope {
+	ret := []attestation.Envelope{}
+	for _, env := range envelopes {
+		if !slices.ContainsFunc(repos, func(repo attestation.Storer) bool {
+			return len(agent.routeEnvelopes(repo, []attestation.Envelope{env})) > 0
+		}) {
+			ret = append(ret, env)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"crypto/x509"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+
+	"github.com/carabiner-dev/collector/envelope/bundle"
+)
+
+// SortOrder defines the order of the envelopes returned by the agent.
+type SortOrder string
+
+const (
+	// SortByRepository returns the envelopes grouped by repository, in the
+	// order the repositories were registered. The envelopes of each
+	// repository are sorted by content digest. This is the default.
+	SortByRepository SortOrder = "repository"
+
+	// SortByDigest sorts all envelopes by content digest, regardless of
+	// the repository that returned them.
+	SortByDigest SortOrder = "digest"
+
+	// SortNewestFirst sorts the envelopes by their transparency log
+	// integrated time or, if they have none, their signing certificate
+	// issuance time. Envelopes without a known time go last.
+	SortNewestFirst SortOrder = "newest-first"
+
+	// SortByPredicateType sorts the envelopes alphabetically by predicate
+	// type.
+	SortByPredicateType SortOrder = "predicate-type"
+)
+
+// sortResults sorts the fetched envelopes in the order configured in the
+// agent. The input is expected in repository order, the sorts are stable so
+// ties keep it. The input slice is not modified as it may be shared with the
+// cache.
+func (agent *Agent) sortResults(atts []attestation.Envelope) []attestation.Envelope {
+	switch agent.Options.SortOrder {
+	case SortByDigest:
+		return sortByDigest(atts)
+	case SortNewestFirst:
+		return sortByKey(atts, envelopeTime, func(a, b time.Time) int { return b.Compare(a) })
+	case SortByPredicateType:
+		return sortByKey(atts, envelopePredicateType, strings.Compare)
+	default:
+		return atts
+	}
+}
+
+// sortByDigest sorts envelopes by their content digest. The agent sorts the
+// results of each repository with it to make them independent of the order
+// the drivers return them.
+func sortByDigest(atts []attestation.Envelope) []attestation.Envelope {
+	return sortByKey(atts, envelopeKey, strings.Compare)
+}
+
+// sortByKey returns a copy of the envelopes sorted stably by a key computed
+// once per envelope.
+func sortByKey[K any](atts []attestation.Envelope, key func(attestation.Envelope) K, cmp func(a, b K) int) []attestation.Envelope {
+	type keyed struct {
+		env attestation.Envelope
+		key K
+	}
+	list := make([]keyed, len(atts))
+	for i, env := range atts {
+		list[i] = keyed{env: env, key: key(env)}
+	}
+	slices.SortStableFunc(list, func(a, b keyed) int {
+		return cmp(a.key, b.key)
+	})
+	ret := make([]attestation.Envelope, len(list))
+	for i := range list {
+		ret[i] = list[i].env
+	}
+	return ret
+}
+
+// envelopePredicateType returns the predicate type of an envelope's statement.
+func envelopePredicateType(env attestation.Envelope) string {
+	if env == nil || env.GetStatement() == nil {
+		return ""
+	}
+	return string(env.GetStatement().GetPredicateType())
+}
+
+// envelopeTime returns the time an envelope was signed. Only sigstore bundles
+// record it: the integrated time of the transparency log entry is preferred,
+// then the issuance time of the signing certificate. The zero time is
+// returned when it is unknown.
+func envelopeTime(env attestation.Envelope) time.Time {
+	b, ok := env.(*bundle.Envelope)
+	if !ok {
+		return time.Time{}
+	}
+
+	var newest int64
+	for _, entry := range b.GetVerificationMaterial().GetTlogEntries() {
+		newest = max(newest, entry.GetIntegratedTime())
+	}
+	if newest > 0 {
+		return time.Unix(newest, 0)
+	}
+
+	raw := b.GetVerificationMaterial().GetCertificate().GetRawBytes()
+	if chain := b.GetVerificationMaterial().GetX509CertificateChain().GetCertificates(); raw == nil && len(chain) > 0 {
+		raw = chain[0].GetRawBytes()
+	}
+	if raw == nil {
+		return time.Time{}
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return time.Time{}
+	}
+	return cert.NotBefore
+}
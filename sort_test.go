@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/bundle"
+)
+
+// typedStatement returns a statement of the predicate type with the value
+// in the predicate.
+func typedStatement(pt string, value int) string {
+	return fmt.Sprintf(
+		`{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"test","digest":{"sha256":"8b1a9953c4611296a827abf8c47804d7e6c49c6b0b1e1d5e8b0e0ea0eb7bd9a6"}}],"predicateType":%q,"predicate":{"value":%d}}`,
+		pt, value,
+	)
+}
+
+// timedBundle returns a bundle with a transparency log entry integrated at
+// the unix time.
+func timedBundle(payload string, integrated int64) *bundle.Envelope {
+	b := testBundle(payload, true)
+	b.VerificationMaterial.TlogEntries[0].IntegratedTime = integrated
+	return b
+}
+
+func TestSortResults(t *testing.T) {
+	t.Parallel()
+	a := timedBundle(typedStatement("https://example.com/b/v1", 1), 100)
+	b := timedBundle(typedStatement("https://example.com/a/v1", 2), 300)
+	c := testDSSE(typedStatement("https://example.com/c/v1", 3))
+	d := timedBundle(typedStatement("https://example.com/a/v1", 4), 200)
+
+	for _, tc := range []struct {
+		name   string
+		order  SortOrder
+		expect []attestation.Envelope
+	}{
+		{"repository", SortByRepository, []attestation.Envelope{a, b, c, d}},
+		{"newest-first", SortNewestFirst, []attestation.Envelope{b, d, a, c}},
+		{"predicate-type", SortByPredicateType, []attestation.Envelope{b, d, a, c}},
+		{"digest", SortByDigest, sortByDigest([]attestation.Envelope{d, c, b, a})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			agent, err := New(WithSortOrder(tc.order))
+			require.NoError(t, err)
+			input := []attestation.Envelope{a, b, c, d}
+			require.Equal(t, tc.expect, agent.sortResults(input))
+			require.Equal(t, []attestation.Envelope{a, b, c, d}, input)
+		})
+	}
+
+	_, err := New(WithSortOrder("random"))
+	require.Error(t, err)
+}
+
+func TestFetchOrderIsDeterministic(t *testing.T) {
+	t.Parallel()
+	envs := []attestation.Envelope{}
+	for i := range 10 {
+		envs = append(envs, testDSSE(typedStatement("https://example.com/test/v1", i)))
+	}
+
+	// reversed returns the envelopes in a different order on every call
+	calls := 0
+	reversed := &fakeFetcher{fetchFunc: func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+		calls++
+		ret := append([]attestation.Envelope{}, envs...)
+		if calls%2 == 0 {
+			for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+				ret[i], ret[j] = ret[j], ret[i]
+			}
+		}
+		return ret, nil
+	}}
+
+	agent, err := New(WithRepository(reversed))
+	require.NoError(t, err)
+
+	first, err := agent.Fetch(t.Context(), WithLimit(3))
+	require.NoError(t, err)
+	second, err := agent.Fetch(t.Context(), WithLimit(3))
+	require.NoError(t, err)
+	require.Len(t, first, 3)
+	require.Equal(t, first, second)
+}
@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/carabiner-dev/attestation"
+	intoto "github.com/in-toto/attestation/go/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// SourcesAnnotation is the key of the annotation in the predicate origin
+// where the agent records the repositories an envelope was fetched from.
+const SourcesAnnotation = "collector.carabiner.dev/sources"
+
+// Source describes where the agent fetched an envelope from.
+type Source struct {
+	// Moniker is the type of the repository driver (oci, github, etc).
+	Moniker string
+
+	// Init is the init string used to configure the repository with the
+	// passwords in its location redacted. It is empty when the repository
+	// was not built from an init string.
+	Init string
+
+	// Location points to the envelope within the repository (a file path,
+	// a URL, an OCI referrer, etc). It is taken from the origin recorded by
+	// the collector and falls back to the envelope digest.
+	Location string
+}
+
+// String returns a human readable representation of the source.
+func (s Source) String() string {
+	repo := s.Init
+	if repo == "" {
+		repo = s.Moniker
+	}
+	if s.Location == "" {
+		return repo
+	}
+	return repo + " " + s.Location
+}
+
+// Sources returns the list of repositories an envelope was fetched from
+// as recorded by the agent in its predicate origin. An envelope has more
+// than one source when duplicate copies were merged.
+func Sources(env attestation.Envelope) []Source {
+	ret := []Source{}
+	rd := originDescriptor(env)
+	if rd == nil || rd.GetAnnotations() == nil {
+		return ret
+	}
+
+	v, ok := rd.GetAnnotations().GetFields()[SourcesAnnotation]
+	if !ok {
+		return ret
+	}
+
+	for _, item := range v.GetListValue().GetValues() {
+		fields := item.GetStructValue().GetFields()
+		ret = append(ret, Source{
+			Moniker:  fields["moniker"].GetStringValue(),
+			Init:     fields["init"].GetStringValue(),
+			Location: fields["location"].GetStringValue(),
+		})
+	}
+	return ret
+}
+
+// addSources records a list of sources in the envelope's predicate origin.
+// Sources already recorded are not duplicated. If the predicate origin is
+// not an in-toto resource descriptor, it is converted to one preserving its
+// name, URI and digests.
+func addSources(env attestation.Envelope, sources ...Source) {
+	if env == nil || env.GetPredicate() == nil {
+		return
+	}
+
+	existing := Sources(env)
+	rd := originDescriptor(env)
+	if rd == nil {
+		rd = toResourceDescriptor(env.GetPredicate().GetOrigin())
+		env.GetPredicate().SetOrigin(rd)
+	}
+
+	list := []*structpb.Value{}
+	if rd.GetAnnotations() != nil {
+		list = rd.GetAnnotations().GetFields()[SourcesAnnotation].GetListValue().GetValues()
+	}
+
+	for _, src := range sources {
+		if slices.Contains(existing, src) {
+			continue
+		}
+		existing = append(existing, src)
+		list = append(list, structpb.NewStructValue(&structpb.Struct{
+			Fields: map[string]*structpb.Value{
+				"moniker":  structpb.NewStringValue(src.Moniker),
+				"init":     structpb.NewStringValue(src.Init),
+				"location": structpb.NewStringValue(src.Location),
+			},
+		}))
+	}
+
+	if rd.Annotations == nil {
+		rd.Annotations = &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	}
+	if rd.Annotations.Fields == nil {
+		rd.Annotations.Fields = map[string]*structpb.Value{}
+	}
+	rd.Annotations.Fields[SourcesAnnotation] = structpb.NewListValue(&structpb.ListValue{Values: list})
+}
+
+// cloneEnvelope returns a deep copy of an envelope. Envelopes returned by
+// the cache or shared between concurrent callers must be copied before
+// their predicate origin is annotated. The copy is parsed again from the
+// serialized envelope and keeps the origin and the verification results.
+func cloneEnvelope(env attestation.Envelope) (attestation.Envelope, error) {
+	data, err := marshalCachedEnvelope(env)
+	if err != nil {
+		return nil, err
+	}
+
+	pred := env.GetPredicate()
+	var origin []byte
+	if pred != nil && pred.GetOrigin() != nil {
+		rd := originDescriptor(env)
+		if rd == nil {
+			rd = toResourceDescriptor(pred.GetOrigin())
+		}
+		origin, err = protojson.Marshal(rd)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling predicate origin: %w", err)
+		}
+	}
+
+	clone, err := parseCachedEnvelope(data, origin)
+	if err != nil {
+		return nil, err
+	}
+	if pred != nil && pred.GetVerification() != nil && clone.GetPredicate() != nil {
+		clone.GetPredicate().SetVerification(pred.GetVerification())
+	}
+	return clone, nil
+}
+
+// originDescriptor returns the predicate origin of an envelope if it is an
+// in-toto resource descriptor.
+func originDescriptor(env attestation.Envelope) *intoto.ResourceDescriptor {
+	if env == nil || env.GetPredicate() == nil {
+		return nil
+	}
+	rd, ok := env.GetPredicate().GetOrigin().(*intoto.ResourceDescriptor)
+	if !ok {
+		return nil
+	}
+	return rd
+}
+
+// toResourceDescriptor copies the data of a subject into a new resource
+// descriptor.
+func toResourceDescriptor(s attestation.Subject) *intoto.ResourceDescriptor {
+	rd := &intoto.ResourceDescriptor{}
+	if s == nil {
+		return rd
+	}
+	rd.Name = s.GetName()
+	rd.Uri = s.GetUri()
+	if d := s.GetDigest(); len(d) > 0 {
+		rd.Digest = map[string]string{}
+		for algo, val := range d {
+			rd.Digest[algo] = val
+		}
+	}
+	return rd
+}
+
+// originLocation returns the location of an envelope within its repository
+// as recorded by the collector in the predicate origin.
+func originLocation(env attestation.Envelope) string {
+	if env == nil || env.GetPredicate() == nil || env.GetPredicate().GetOrigin() == nil {
+		return ""
+	}
+	origin := env.GetPredicate().GetOrigin()
+	if origin.GetUri() != "" {
+		return origin.GetUri()
+	}
+	if origin.GetName() != "" {
+		return origin.GetName()
+	}
+
+	// Fall back to the strongest digest of the envelope
+	digests := origin.GetDigest()
+	for _, algo := range []string{
+		string(intoto.AlgorithmSHA256), string(intoto.AlgorithmSHA512),
+		string(intoto.AlgorithmSHA384), string(intoto.AlgorithmSHA1),
+	} {
+		if v, ok := digests[algo]; ok {
+			return fmt.Sprintf("%s:%s", algo, v)
+		}
+	}
+	return ""
+}
+
+// repoSource returns the source data of a repository without the location.
+func (agent *Agent) repoSource(r any) Source {
+	if init, ok := agent.repoInit(r); ok {
+		moniker, _, _ := strings.Cut(init, ":")
+		return Source{Moniker: moniker, Init: init}
+	}
+	return Source{Moniker: repoMoniker(r)}
+}
+
+// annotateSources records the repository in the sources of the envelopes
+// it returned.
+func (agent *Agent) annotateSources(r any, envs []attestation.Envelope) {
+	src := agent.repoSource(r)
+	for _, env := range envs {
+		s := src
+		s.Location = originLocation(env)
+		addSources(env, s)
+	}
+}
@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/nozzle/throttler"
+
+	"github.com/carabiner-dev/collector/observer"
+)
+
+// StorePolicy defines when the agent considers a store operation successful
+// if some of the storer repositories fail.
+type StorePolicy string
+
+const (
+	// StorePolicyAllOrNothing fails the store if any storer fails. As the
+	// agent cannot roll back the writes, the report lists the repositories
+	// that accepted the attestations and may need cleanup.
+	StorePolicyAllOrNothing StorePolicy = "all-or-nothing"
+
+	// StorePolicyBestEffort only fails the store if all storers fail.
+	StorePolicyBestEffort StorePolicy = "best-effort"
+
+	// StorePolicyQuorum fails the store unless at least Options.StoreQuorum
+	// storers accept the attestations. If no quorum is set, a majority of
+	// the storers is required.
+	StorePolicyQuorum StorePolicy = "quorum"
+)
+
+// ErrQuorumNotReached is returned when fewer storers than the configured
+// quorum accepted the attestations.
+var ErrQuorumNotReached = errors.New("store quorum not reached")
+
+// ErrNotRouted is returned when the store routes send some of the envelopes
+// to none of the storers.
+var ErrNotRouted = errors.New("envelopes not routed to any storer")
+
+// StoreReport records the outcome of a store operation in each of the
+// storer repositories.
+type StoreReport struct {
+	// Policy is the store policy used to evaluate the results.
+	Policy StorePolicy
+
+	// Repositories has one entry per storer, in the order they are
+	// registered in the agent. Count is the number of attestations sent
+	// to the repository. Storers that received no envelopes because of
+	// the store routes are not listed.
+	Repositories []RepositoryReport
+
+	// Unrouted has the envelopes not sent to any storer because none of
+	// the store routes matched them.
+	Unrouted []attestation.Envelope
+}
+
+// Failed returns the reports of the repositories that returned an error.
+func (r *StoreReport) Failed() []RepositoryReport {
+	ret := []RepositoryReport{}
+	if r == nil {
+		return ret
+	}
+	for _, rr := range r.Repositories {
+		if rr.Error != nil {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// Succeeded returns the reports of the repositories that stored the
+// attestations.
+func (r *StoreReport) Succeeded() []RepositoryReport {
+	ret := []RepositoryReport{}
+	if r == nil {
+		return ret
+	}
+	for _, rr := range r.Repositories {
+		if rr.Error == nil {
+			ret = append(ret, rr)
+		}
+	}
+	return ret
+}
+
+// NeedsCleanup returns the repositories that accepted the attestations when
+// an all-or-nothing store failed, either because a storer failed or because
+// some envelopes were not routed. As the agent cannot undo writes, callers
+// may need to remove the attestations from them.
+func (r *StoreReport) NeedsCleanup() []RepositoryReport {
+	if r == nil || r.Policy != StorePolicyAllOrNothing || (len(r.Failed()) == 0 && len(r.Unrouted) == 0) {
+		return []RepositoryReport{}
+	}
+	return r.Succeeded()
+}
+
+// Err returns an error joining the errors of all failed repositories or nil
+// if all repositories stored the attestations.
+func (r *StoreReport) Err() error {
+	errs := []error{}
+	for _, rr := range r.Failed() {
+		errs = append(errs, fmt.Errorf("%s: %w", rr.Repository, rr.Error))
+	}
+	return errors.Join(errs...)
+}
+
+// StoreWithReport stores a list of envelopes in the configured storer
+// repositories, writing to up to Options.ParallelStores repositories at a
+// time. It returns a report with the outcome of each repository. The
+// returned error is set when the results don't satisfy the agent's store
+// policy.
+//
+// Envelopes that the store routes send to no storer are listed in the
+// report. If nothing was routed, or with the all-or-nothing and quorum
+// policies, they make the store fail with ErrNotRouted.
+func (agent *Agent) StoreWithReport(ctx context.Context, envelopes []attestation.Envelope, optFn ...StoreOptionsFunc) (*StoreReport, error) {
+	repos := agent.storerRepos()
+	if len(repos) == 0 {
+		return nil, ErrNoStorerConfigured
+	}
+
+	opts := agent.Options.Store
+	for _, f := range optFn {
+		f(&opts)
+	}
+
+	policy := agent.Options.StorePolicy
+	if policy == "" {
+		policy = StorePolicyAllOrNothing
+	}
+
+	// Decide which envelopes go to each storer. Storers that don't get any
+	// envelopes from the routes are skipped.
+	type storeJob struct {
+		repo      attestation.Storer
+		envelopes []attestation.Envelope
+	}
+	jobs := []storeJob{}
+	for _, repo := range repos {
+		routed := agent.routeEnvelopes(repo, envelopes)
+		if len(routed) == 0 {
+			continue
+		}
+		jobs = append(jobs, storeJob{repo: repo, envelopes: routed})
+	}
+
+	report := &StoreReport{
+		Policy:       policy,
+		Repositories: make([]RepositoryReport, len(jobs)),
+		Unrouted:     agent.unroutedEnvelopes(repos, envelopes),
+	}
+
+	var unroutedErr error
+	if n := len(report.Unrouted); n > 0 {
+		unroutedErr = fmt.Errorf("%w: %d of %d envelopes", ErrNotRouted, n, len(envelopes))
+	}
+
+	if len(jobs) == 0 {
+		return report, unroutedErr
+	}
+
+	ctx = agent.observedContext(ctx)
+	t := throttler.New(workers(agent.Options.ParallelStores), len(jobs))
+	for i, job := range jobs {
+		go func(i int, job storeJob) {
+			name := agent.repoName(job.repo)
+			rctx := observer.WithRepository(ctx, name)
+			start := time.Now()
+			err := agent.checkOffline(job.repo)
+			if err == nil {
+				err = job.repo.Store(rctx, opts, job.envelopes)
+			}
+			report.Repositories[i] = RepositoryReport{
+				Repository: name,
+				Count:      len(job.envelopes),
+				Latency:    time.Since(start),
+				Error:      err,
+			}
+			observer.FromContext(rctx).StoreResult(rctx, observer.StoreResultEvent{
+				Repository: name,
+				Count:      len(job.envelopes),
+				Latency:    report.Repositories[i].Latency,
+				Error:      err,
+			})
+			t.Done(nil)
+		}(i, job)
+		t.Throttle()
+	}
+
+	err := agent.checkStorePolicy(report)
+	if policy != StorePolicyBestEffort {
+		err = errors.Join(err, unroutedErr)
+	}
+	if len(report.Succeeded()) > 0 {
+		if ierr := agent.invalidateCache(envelopes); ierr != nil {
+			err = errors.Join(err, fmt.Errorf("invalidating cache: %w", ierr))
+		}
+	}
+	return report, err
+}
+
+// invalidateCache drops the cached results that may be affected by newly
+// stored envelopes. Entries are matched by the subjects and predicate types
+// of the envelope statements.
+func (agent *Agent) invalidateCache(envelopes []attestation.Envelope) error {
+	invalidator, ok := agent.Cache.(CacheInvalidator)
+	if !ok {
+		return nil
+	}
+	subjects := []attestation.Subject{}
+	predicateTypes := []attestation.PredicateType{}
+	for _, env := range envelopes {
+		statement := env.GetStatement()
+		if statement == nil {
+			continue
+		}
+		subjects = append(subjects, statement.GetSubjects()...)
+		if pt := statement.GetPredicateType(); pt != "" {
+			predicateTypes = append(predicateTypes, pt)
+		}
+	}
+	if len(subjects) == 0 && len(predicateTypes) == 0 {
+		return nil
+	}
+	return invalidator.Invalidate(subjects, predicateTypes)
+}
+
+// checkStorePolicy evaluates a store report against the store policy.
+func (agent *Agent) checkStorePolicy(report *StoreReport) error {
+	failed := len(report.Failed())
+	if failed == 0 {
+		return nil
+	}
+
+	switch report.Policy {
+	case StorePolicyBestEffort:
+		if failed < len(report.Repositories) {
+			return nil
+		}
+	case StorePolicyQuorum:
+		// Without an explicit quorum, a majority is required
+		required := agent.Options.StoreQuorum
+		if required <= 0 {
+			required = len(report.Repositories)/2 + 1
+		}
+		ok := len(report.Succeeded())
+		if ok >= required {
+			return nil
+		}
+		return fmt.Errorf(
+			"%w (%d of %d required): %w",
+			ErrQuorumNotReached, ok, required, report.Err(),
+		)
+	}
+	return fmt.Errorf("storing attestation: %w", report.Err())
+}
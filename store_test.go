@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/bare"
+)
+
+var _ attestation.Storer = (*fakeStorer)(nil)
+
+type fakeStorer struct {
+	storeFunc func(context.Context, attestation.StoreOptions, []attestation.Envelope) error
+}
+
+func (fs *fakeStorer) Store(ctx context.Context, opts attestation.StoreOptions, envs []attestation.Envelope) error {
+	return fs.storeFunc(ctx, opts, envs)
+}
+
+func storerThatErrs(fail bool) *fakeStorer {
+	return &fakeStorer{storeFunc: func(context.Context, attestation.StoreOptions, []attestation.Envelope) error {
+		if fail {
+			return errors.New("synth error")
+		}
+		return nil
+	}}
+}
+
+func TestStoreWithReport(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name      string
+		opts      []InitFunction
+		fails     []bool
+		mustErr   bool
+		quorumErr bool
+		cleanup   int
+	}{
+		{name: "all-ok", fails: []bool{false, false, false}},
+		{name: "all-or-nothing", fails: []bool{false, true, false}, mustErr: true, cleanup: 2},
+		{name: "best-effort-partial", opts: []InitFunction{WithStorePolicy(StorePolicyBestEffort)}, fails: []bool{false, true, true}},
+		{name: "best-effort-all-fail", opts: []InitFunction{WithStorePolicy(StorePolicyBestEffort)}, fails: []bool{true, true}, mustErr: true},
+		{name: "quorum-reached", opts: []InitFunction{WithStoreQuorum(2)}, fails: []bool{false, true, false}},
+		{name: "quorum-not-reached", opts: []InitFunction{WithStoreQuorum(2)}, fails: []bool{true, true, false}, mustErr: true, quorumErr: true},
+		{name: "zero-parallel-stores", opts: []InitFunction{WithParallelStores(0)}, fails: []bool{false, false}},
+		{name: "quorum-majority", opts: []InitFunction{WithStorePolicy(StorePolicyQuorum)}, fails: []bool{false, true, true}, mustErr: true, quorumErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			agent, err := New(tc.opts...)
+			require.NoError(t, err)
+			for _, fail := range tc.fails {
+				require.NoError(t, agent.AddRepository(storerThatErrs(fail)))
+			}
+
+			report, err := agent.StoreWithReport(t.Context(), []attestation.Envelope{&bare.Envelope{}})
+			require.NotNil(t, report)
+			require.Len(t, report.Repositories, len(tc.fails))
+			for i, fail := range tc.fails {
+				require.Equal(t, fail, report.Repositories[i].Error != nil)
+				require.Equal(t, 1, report.Repositories[i].Count)
+			}
+			require.Len(t, report.NeedsCleanup(), tc.cleanup)
+			if !tc.mustErr {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Equal(t, tc.quorumErr, errors.Is(err, ErrQuorumNotReached))
+		})
+	}
+}
+
+func TestStoreParallel(t *testing.T) {
+	t.Parallel()
+	agent, err := New(WithParallelStores(2))
+	require.NoError(t, err)
+
+	// Both storers block until the other has started, a sequential store
+	// would never finish.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for range 2 {
+		require.NoError(t, agent.AddRepository(&fakeStorer{
+			storeFunc: func(context.Context, attestation.StoreOptions, []attestation.Envelope) error {
+				wg.Done()
+				wg.Wait()
+				return nil
+			},
+		}))
+	}
+
+	done := make(chan error)
+	go func() { done <- agent.Store(t.Context(), []attestation.Envelope{&bare.Envelope{}}) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("storers did not run in parallel")
+	}
+}
+
+func TestStoreNoStorers(t *testing.T) {
+	t.Parallel()
+	agent, err := New()
+	require.NoError(t, err)
+	err = agent.Store(t.Context(), []attestation.Envelope{})
+	require.ErrorIs(t, err, ErrNoStorerConfigured)
+}
@@ -0,0 +1,336 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/nozzle/throttler"
+	"github.com/sirupsen/logrus"
+
+	"github.com/carabiner-dev/collector/internal/readlimit"
+)
+
+// fetchResult is the outcome of querying a single repository, sent from the
+// fetcher goroutines to the stream consumer.
+type fetchResult struct {
+	envelopes []attestation.Envelope
+	err       error
+}
+
+// FetchStream is the streaming variant of Fetch. Instead of waiting for all
+// repositories to respond, the returned iterator yields envelopes as soon as
+// each collector finishes.
+//
+// Repository errors are yielded with a nil envelope and do not stop the
+// stream, the consumer decides whether to keep reading. Breaking out of the
+// loop cancels the context passed to the collectors still running. When the
+// read budget runs out, the stream ends with an ErrReadBudgetExhausted
+// error listing the truncated sources.
+func (agent *Agent) FetchStream(ctx context.Context, optFn ...FetchOptionsFunc) iter.Seq2[attestation.Envelope, error] {
+	repos := agent.fetcherRepos()
+	if len(repos) == 0 {
+		return errorSeq(ErrNoFetcherConfigured)
+	}
+	opts := agent.fetchOptions(optFn...)
+	return func(yield func(attestation.Envelope, error) bool) {
+		edge := agent.newStreamEdge(opts, yield)
+		for atts, err := range agent.stream(ctx, repos, opts, fetchAll, anyResults) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			if !edge.emit(atts) {
+				return
+			}
+		}
+	}
+}
+
+// FetchAttestationsBySubjectStream is the streaming variant of
+// FetchAttestationsBySubject. Envelopes are yielded as each repository
+// responds. Results served from the cache are yielded directly and a
+// complete, error-free stream is stored in the cache.
+func (agent *Agent) FetchAttestationsBySubjectStream(ctx context.Context, subjects []attestation.Subject, optFn ...FetchOptionsFunc) iter.Seq2[attestation.Envelope, error] {
+	repos := agent.fetcherRepos()
+	if len(repos) == 0 {
+		if agent.Options.FailIfNoFetchers {
+			return errorSeq(ErrNoFetcherConfigured)
+		}
+		logrus.Debugf("WARN: No fetcher repos configured")
+		return errorSeq(nil)
+	}
+
+	opts := agent.fetchOptions(optFn...)
+	return agent.cachedStream(
+		ctx, opts, "FetchAttestationsBySubject",
+		func(ctx context.Context) (*[]attestation.Envelope, error) {
+			return agent.Cache.GetAttestationsBySubject(ctx, subjects)
+		},
+		func(ctx context.Context, atts *[]attestation.Envelope) error {
+			return agent.Cache.StoreAttestationsBySubject(ctx, subjects, atts)
+		},
+		agent.stream(ctx, repos, noQueryNoLimit(opts), subjectFetchFunc(subjects), anyResults),
+	)
+}
+
+// FetchAttestationsByPredicateTypeStream is the streaming variant of
+// FetchAttestationsByPredicateType. Envelopes are yielded as each repository
+// responds.
+func (agent *Agent) FetchAttestationsByPredicateTypeStream(ctx context.Context, pt []attestation.PredicateType, optFn ...FetchOptionsFunc) iter.Seq2[attestation.Envelope, error] {
+	repos := agent.fetcherRepos()
+	if len(repos) == 0 {
+		return errorSeq(ErrNoFetcherConfigured)
+	}
+
+	opts := agent.fetchOptions(optFn...)
+	return agent.cachedStream(
+		ctx, opts, "FetchAttestationsByPredicateType",
+		func(ctx context.Context) (*[]attestation.Envelope, error) {
+			return agent.Cache.GetAttestationsByPredicateType(ctx, pt)
+		},
+		func(ctx context.Context, atts *[]attestation.Envelope) error {
+			return agent.Cache.StoreAttestationsByPredicateType(ctx, pt, atts)
+		},
+		agent.stream(ctx, repos, noQueryNoLimit(opts), predicateTypeFetchFunc(pt), coversPredicateTypes(pt)),
+	)
+}
+
+// FetchAttestationsBySubjectAndPredicateTypeStream is the streaming variant
+// of FetchAttestationsBySubjectAndPredicateType. Envelopes are yielded as each
+// repository responds.
+func (agent *Agent) FetchAttestationsBySubjectAndPredicateTypeStream(ctx context.Context, subjects []attestation.Subject, pt []attestation.PredicateType, optFn ...FetchOptionsFunc) iter.Seq2[attestation.Envelope, error] {
+	repos := agent.fetcherRepos()
+	if len(repos) == 0 {
+		return errorSeq(ErrNoFetcherConfigured)
+	}
+
+	opts := agent.fetchOptions(optFn...)
+	return agent.cachedStream(
+		ctx, opts, "FetchAttestationsBySubjectAndPredicateType",
+		func(ctx context.Context) (*[]attestation.Envelope, error) {
+			return agent.Cache.GetAttestationsBySubjectAndPredicateType(ctx, subjects, pt)
+		},
+		func(ctx context.Context, atts *[]attestation.Envelope) error {
+			return agent.Cache.StoreAttestationsBySubjectAndPredicateType(ctx, subjects, pt, atts)
+		},
+		agent.stream(ctx, repos, noQueryNoLimit(opts), subjectAndPredicateTypeFetchFunc(subjects, pt), coversPredicateTypes(pt)),
+	)
+}
+
+// noQueryNoLimit returns a copy of opts without the query and limit. It is
+// used to fetch complete result sets that can be cached, the query and
+// limit are then applied by the stream consumer.
+func noQueryNoLimit(opts attestation.FetchOptions) attestation.FetchOptions {
+	opts.Query = nil
+	opts.Limit = 0
+	return opts
+}
+
+// errorSeq returns an iterator that yields a single error. If err is nil
+// the iterator yields nothing.
+func errorSeq(err error) iter.Seq2[attestation.Envelope, error] {
+	return func(yield func(attestation.Envelope, error) bool) {
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// stream runs fetch on all the repositories in parallel (up to
+// ParallelFetches at a time) and returns an iterator that yields the
+// envelopes of each repository as they arrive. The query in opts is not
+// applied, see streamEdge. Priority tiers are queried in order until the
+// envelopes of a tier pass the done check.
+func (agent *Agent) stream(ctx context.Context, repos []attestation.Fetcher, opts attestation.FetchOptions, fetch repoFetchFunc, done tierCheck) iter.Seq2[[]attestation.Envelope, error] {
+	return func(yield func([]attestation.Envelope, error) bool) {
+		// The derived context is canceled when the consumer stops reading
+		// to release the collectors still working.
+		sctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// Collectors return the full slice of attestations, the query and
+		// limit are applied as results are yielded.
+		repoOpts := opts
+		repoOpts.Query = nil
+
+		// All the repositories share the read budget of the stream
+		bctx := readlimit.WithBudget(sctx, agent.readBudget())
+
+		// truncated collects the sources not read completely
+		truncated := []string{}
+
+		results := make(chan fetchResult)
+		go func() {
+			defer close(results)
+			// Tiers run one after the other, the envelopes of each tier are
+			// collected to decide if the next one is needed.
+			var mtx sync.Mutex
+			found := []attestation.Envelope{}
+			for _, tier := range agent.fetchTiers(repos) {
+				t := throttler.New(workers(agent.Options.ParallelFetches), len(tier))
+				for _, i := range tier {
+					go func(r attestation.Fetcher) {
+						atts, rr := agent.fetchRepo(bctx, r, repoOpts, fetch)
+						err := rr.Error
+						mtx.Lock()
+						for _, src := range rr.Truncated {
+							truncated = append(truncated, rr.Repository+": "+src)
+						}
+						mtx.Unlock()
+						if err == nil {
+							agent.annotateSources(r, atts)
+							atts = agent.verifyEnvelopes(atts)
+							atts = sortByDigest(atts)
+							mtx.Lock()
+							found = append(found, atts...)
+							mtx.Unlock()
+						}
+						select {
+						case results <- fetchResult{envelopes: atts, err: err}:
+						case <-sctx.Done():
+						}
+						t.Done(nil)
+					}(repos[i])
+					t.Throttle()
+				}
+				if done(found) || sctx.Err() != nil {
+					return
+				}
+			}
+		}()
+
+		for res := range results {
+			if res.err != nil {
+				if !yield(nil, res.err) {
+					return
+				}
+				continue
+			}
+			if len(res.envelopes) > 0 && !yield(res.envelopes, nil) {
+				return
+			}
+		}
+
+		// If the caller's context was canceled, some results may have been
+		// dropped. Let the consumer know.
+		if err := ctx.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		// The results channel is closed, all writers are done
+		if len(truncated) > 0 {
+			yield(nil, fmt.Errorf("%w, sources truncated: %s", ErrReadBudgetExhausted, strings.Join(truncated, ", ")))
+		}
+	}
+}
+
+// streamEdge applies the processing of the agent to the envelopes of a
+// stream before yielding them to the consumer: deduplication, query and
+// limit.
+type streamEdge struct {
+	opts  attestation.FetchOptions
+	yield func(attestation.Envelope, error) bool
+	dedup *streamDeduplicator
+	n     int
+}
+
+func (agent *Agent) newStreamEdge(opts attestation.FetchOptions, yield func(attestation.Envelope, error) bool) *streamEdge {
+	edge := &streamEdge{opts: opts, yield: yield}
+	if agent.Options.Deduplicate {
+		edge.dedup = newStreamDeduplicator()
+	}
+	return edge
+}
+
+// emit yields a batch of envelopes. Duplicates within the batch are
+// collapsed keeping the best copy, copies of envelopes yielded in previous
+// batches are dropped. It returns false when the consumer is done or the
+// limit was reached.
+func (edge *streamEdge) emit(atts []attestation.Envelope) bool {
+	if edge.dedup != nil {
+		atts = deduplicate(atts)
+	}
+	if edge.opts.Query != nil {
+		atts = edge.opts.Query.Run(atts)
+	}
+	if edge.dedup != nil {
+		atts = edge.dedup.filter(atts)
+	}
+	for _, att := range atts {
+		if !edge.yield(att, nil) {
+			return false
+		}
+		edge.n++
+		if edge.opts.Limit > 0 && edge.n >= edge.opts.Limit {
+			return false
+		}
+	}
+	return true
+}
+
+// cachedStream wraps a repository stream with the agent cache. If the cache
+// has data, it is yielded instead of querying the repositories. Otherwise,
+// the upstream envelopes are yielded as they arrive and, if the stream
+// completes without errors, they are stored in the cache. The query and
+// limit in opts are applied to the yielded envelopes, upstream is expected
+// to return the full result set.
+func (agent *Agent) cachedStream(
+	ctx context.Context, opts attestation.FetchOptions, method string,
+	get func(context.Context) (*[]attestation.Envelope, error),
+	store func(context.Context, *[]attestation.Envelope) error,
+	upstream iter.Seq2[[]attestation.Envelope, error],
+) iter.Seq2[attestation.Envelope, error] {
+	return func(yield func(attestation.Envelope, error) bool) {
+		useCache := agent.Options.UseCache && agent.Cache != nil
+		ctx := withCacheScope(ctx, agent.cacheScope(opts))
+		edge := agent.newStreamEdge(opts, yield)
+
+		if useCache {
+			rctx, read := withCacheRead(ctx)
+			cached, err := get(rctx)
+			if err != nil {
+				yield(nil, fmt.Errorf("querying attestations cache: %w", err))
+				return
+			}
+			envs, ok := agent.cachedEnvelopes(cached, read)
+			agent.observeCache(ctx, method, ok)
+			if ok {
+				edge.emit(envs)
+				return
+			}
+		}
+
+		// The cache gets the raw results, as in the non-streaming methods
+		all := []attestation.Envelope{}
+		complete := true
+		for atts, err := range upstream {
+			if err != nil {
+				complete = false
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			all = append(all, atts...)
+			if !edge.emit(atts) {
+				return
+			}
+		}
+
+		if useCache && complete {
+			if err := store(ctx, &all); err != nil {
+				yield(nil, fmt.Errorf("storing data in cache: %w", err))
+			}
+		}
+	}
+}
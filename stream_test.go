@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/bare"
+)
+
+func TestFetchStream(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name       string
+		fn         []func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error)
+		opts       []FetchOptionsFunc
+		expect     int
+		expectErrs int
+	}{
+		{
+			name: "dual-repo-dual-return",
+			fn: []func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error){
+				func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+					return []attestation.Envelope{&bare.Envelope{}}, nil
+				},
+				func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+					return []attestation.Envelope{&bare.Envelope{}}, nil
+				},
+			},
+			expect: 2,
+		},
+		{
+			name: "one-errs-other-returns",
+			fn: []func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error){
+				func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+					return []attestation.Envelope{&bare.Envelope{}, &bare.Envelope{}}, nil
+				},
+				func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+					return nil, errors.New("synth error")
+				},
+			},
+			expect:     2,
+			expectErrs: 1,
+		},
+		{
+			name: "limit",
+			fn: []func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error){
+				func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+					return []attestation.Envelope{&bare.Envelope{}, &bare.Envelope{}}, nil
+				},
+				func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+					return []attestation.Envelope{&bare.Envelope{}, &bare.Envelope{}}, nil
+				},
+			},
+			opts:   []FetchOptionsFunc{WithLimit(3)},
+			expect: 3,
+		},
+		{
+			name: "query",
+			fn: []func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error){
+				func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+					return []attestation.Envelope{&bare.Envelope{}, &bare.Envelope{}}, nil
+				},
+			},
+			// The filter drops every envelope
+			opts: []FetchOptionsFunc{WithQuery(attestation.NewQuery().WithFilter(
+				&noMatchFilter{},
+			))},
+			expect: 0,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			agent, err := New()
+			require.NoError(t, err)
+			for _, fn := range tc.fn {
+				agent.Repositories = append(agent.Repositories, &fakeFetcher{fetchFunc: fn})
+			}
+
+			got, errs := 0, 0
+			for att, err := range agent.FetchStream(t.Context(), tc.opts...) {
+				if err != nil {
+					errs++
+					continue
+				}
+				require.NotNil(t, att)
+				got++
+			}
+			require.Equal(t, tc.expect, got)
+			require.Equal(t, tc.expectErrs, errs)
+		})
+	}
+}
+
+// noMatchFilter never matches, used to test queries in streams.
+type noMatchFilter struct{}
+
+func (*noMatchFilter) Matches(attestation.Envelope) bool { return false }
+
+func TestFetchStreamCancelsOnBreak(t *testing.T) {
+	t.Parallel()
+	agent, err := New(WithParallelFetches(2))
+	require.NoError(t, err)
+
+	canceled := make(chan struct{})
+	agent.Repositories = append(agent.Repositories,
+		&fakeFetcher{fetchFunc: func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+			return []attestation.Envelope{&bare.Envelope{}}, nil
+		}},
+		&fakeFetcher{fetchFunc: func(ctx context.Context, _ attestation.FetchOptions) ([]attestation.Envelope, error) {
+			// The slow repository only returns when the stream is abandoned
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}},
+	)
+
+	for att, err := range agent.FetchStream(t.Context()) {
+		require.NoError(t, err)
+		require.NotNil(t, att)
+		break
+	}
+	<-canceled
+}
+
+func TestFetchAttestationsBySubjectStreamCache(t *testing.T) {
+	t.Parallel()
+	agent, err := New()
+	require.NoError(t, err)
+
+	calls := 0
+	agent.Repositories = append(agent.Repositories, &fakeFetcher{
+		fetchBySubjectFunc: func(context.Context, attestation.FetchOptions, []attestation.Subject) ([]attestation.Envelope, error) {
+			calls++
+			return []attestation.Envelope{&bare.Envelope{}, &bare.Envelope{}}, nil
+		},
+	})
+
+	for range 2 {
+		got := 0
+		for att, err := range agent.FetchAttestationsBySubjectStream(t.Context(), []attestation.Subject{}) {
+			require.NoError(t, err)
+			require.NotNil(t, att)
+			got++
+		}
+		require.Equal(t, 2, got)
+	}
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/carabiner-dev/attestation"
+)
+
+// WithRepositoryTier adds repositories to the agent in a priority tier. See
+// Agent.SetRepositoryTier for details.
+func WithRepositoryTier(tier int, repos ...attestation.Repository) InitFunction {
+	return func(agent *Agent) error {
+		if err := agent.AddRepository(repos...); err != nil {
+			return err
+		}
+		agent.SetRepositoryTier(tier, repos...)
+		return nil
+	}
+}
+
+// SetRepositoryTier assigns repositories to a priority tier. When fetching,
+// the agent queries the tiers in ascending order and stops at the first one
+// that returns results, the repositories in lower priority tiers are only
+// queried when the higher ones have nothing. When fetching by predicate
+// type, a tier has to return attestations of all the requested types.
+//
+// Repositories are in tier 0 unless assigned to another one, so by default
+// all repositories are queried at once.
+func (agent *Agent) SetRepositoryTier(tier int, repos ...attestation.Repository) {
+	agent.initsMutex.Lock()
+	defer agent.initsMutex.Unlock()
+	if agent.repoTiers == nil {
+		agent.repoTiers = map[any]int{}
+	}
+	for _, r := range repos {
+		if !reflect.TypeOf(r).Comparable() {
+			continue
+		}
+		agent.repoTiers[r] = tier
+	}
+}
+
+// repoTier returns the priority tier of a repository.
+func (agent *Agent) repoTier(r any) int {
+	if !reflect.TypeOf(r).Comparable() {
+		return 0
+	}
+	agent.initsMutex.Lock()
+	defer agent.initsMutex.Unlock()
+	return agent.repoTiers[r]
+}
+
+// fetchTiers groups the indexes of the repositories by priority tier. The
+// tiers are sorted by priority and the repositories in each tier keep their
+// order.
+func (agent *Agent) fetchTiers(repos []attestation.Fetcher) [][]int {
+	byTier := map[int][]int{}
+	for i, r := range repos {
+		tier := agent.repoTier(r)
+		byTier[tier] = append(byTier[tier], i)
+	}
+
+	keys := []int{}
+	for tier := range byTier {
+		keys = append(keys, tier)
+	}
+	slices.Sort(keys)
+
+	ret := make([][]int, 0, len(keys))
+	for _, tier := range keys {
+		ret = append(ret, byTier[tier])
+	}
+	return ret
+}
+
+// tierCheck reports if the envelopes returned by a tier answer the fetch,
+// in which case lower priority tiers are not queried.
+type tierCheck func([]attestation.Envelope) bool
+
+// anyResults is the tierCheck of fetches that are answered by any envelope.
+func anyResults(atts []attestation.Envelope) bool {
+	return len(atts) > 0
+}
+
+// coversPredicateTypes returns a tierCheck that requires envelopes of all
+// the predicate types.
+func coversPredicateTypes(pt []attestation.PredicateType) tierCheck {
+	return func(atts []attestation.Envelope) bool {
+		for _, t := range pt {
+			if !slices.ContainsFunc(atts, func(env attestation.Envelope) bool {
+				return envelopePredicateType(env) == string(t)
+			}) {
+				return false
+			}
+		}
+		return len(atts) > 0
+	}
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/stretchr/testify/require"
+)
+
+// countingFetcher returns a fixed set of envelopes and counts its calls
+type countingFetcher struct {
+	envs  []attestation.Envelope
+	calls atomic.Int32
+}
+
+func (cf *countingFetcher) Fetch(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+	cf.calls.Add(1)
+	return cf.envs, nil
+}
+
+func TestFetchTiers(t *testing.T) {
+	t.Parallel()
+	typeA := typedStatement("https://example.com/a/v1", 1)
+	typeB := typedStatement("https://example.com/b/v1", 2)
+
+	for _, tc := range []struct {
+		name        string
+		mirror      []string
+		pt          []attestation.PredicateType
+		remoteCalls int32
+		expect      int
+	}{
+		{"mirror-answers", []string{typeA}, nil, 0, 1},
+		{"mirror-empty", []string{}, nil, 1, 2},
+		{"mirror-covers-types", []string{typeA}, []attestation.PredicateType{"https://example.com/a/v1"}, 0, 1},
+		{"mirror-misses-type", []string{typeA}, []attestation.PredicateType{"https://example.com/a/v1", "https://example.com/b/v1"}, 1, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mirror := &countingFetcher{}
+			for _, s := range tc.mirror {
+				mirror.envs = append(mirror.envs, testDSSE(s))
+			}
+			remote := &countingFetcher{envs: []attestation.Envelope{testDSSE(typeA), testDSSE(typeB)}}
+
+			// Register the remote first to check tiers override the order
+			agent, err := New(WithRepositoryTier(1, remote), WithRepository(mirror))
+			require.NoError(t, err)
+
+			var res []attestation.Envelope
+			var report *FetchReport
+			if tc.pt == nil {
+				res, report, err = agent.FetchWithReport(t.Context())
+			} else {
+				res, report, err = agent.FetchAttestationsByPredicateTypeWithReport(t.Context(), tc.pt)
+			}
+			require.NoError(t, err)
+			require.Len(t, res, tc.expect)
+			require.Equal(t, int32(1), mirror.calls.Load())
+			require.Equal(t, tc.remoteCalls, remote.calls.Load())
+			require.Len(t, report.Repositories, 2)
+			require.Equal(t, tc.remoteCalls == 0, report.Repositories[0].Skipped)
+
+			// Streams honor the tiers too
+			remote.calls.Store(0)
+			n := 0
+			for _, err := range agent.FetchStream(t.Context()) {
+				require.NoError(t, err)
+				n++
+			}
+			if tc.pt == nil {
+				require.Equal(t, tc.expect, n)
+				require.Equal(t, tc.remoteCalls, remote.calls.Load())
+			}
+		})
+	}
+}
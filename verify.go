@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/carabiner-dev/attestation"
+	"github.com/nozzle/throttler"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/carabiner-dev/collector/envelope/bundle"
+)
+
+// VerificationAnnotation is the key of the annotation in the predicate
+// origin where the agent records why an envelope could not be verified
+// when using VerificationPolicyAnnotate.
+const VerificationAnnotation = "collector.carabiner.dev/verification"
+
+// VerificationPolicy defines what the agent does with fetched envelopes that
+// are unsigned or fail signature verification.
+type VerificationPolicy string
+
+const (
+	// VerificationPolicyKeep returns all envelopes. The results of the
+	// verification are attached to the predicates.
+	VerificationPolicyKeep VerificationPolicy = "keep"
+
+	// VerificationPolicyAnnotate returns all envelopes and records the
+	// reason of the verification failure in the VerificationAnnotation
+	// of the unverified ones.
+	VerificationPolicyAnnotate VerificationPolicy = "annotate"
+
+	// VerificationPolicyDrop discards the envelopes that are unsigned or
+	// fail verification.
+	VerificationPolicyDrop VerificationPolicy = "drop"
+)
+
+// verifyEnvelopes checks the signatures of the envelopes in parallel and
+// applies the verification policy to the result. DSSE envelopes are checked
+// against the agent keys and sigstore bundles against the configured trust
+// root or, if none is set, the sigstore public good instance. Envelopes that
+// already carry verification data from their repository are not verified
+// again. The results are recorded in the envelopes, which must not be shared
+// with the cache or other callers.
+func (agent *Agent) verifyEnvelopes(envs []attestation.Envelope) []attestation.Envelope {
+	if !agent.Options.VerifySignatures || len(envs) == 0 {
+		return envs
+	}
+
+	failures := agent.checkSignatures(envs)
+	ret := make([]attestation.Envelope, 0, len(envs))
+	for i, env := range envs {
+		if failures[i] == "" {
+			ret = append(ret, env)
+			continue
+		}
+		switch agent.Options.VerificationPolicy {
+		case VerificationPolicyDrop:
+			continue
+		case VerificationPolicyAnnotate:
+			annotateVerificationFailure(env, failures[i])
+		}
+		ret = append(ret, env)
+	}
+	return ret
+}
+
+// checkSignatures verifies the envelopes in parallel, recording the results
+// in them. It returns the reason each envelope is not verified or an empty
+// string if it is.
+func (agent *Agent) checkSignatures(envs []attestation.Envelope) []string {
+	failures := make([]string, len(envs))
+	if len(envs) == 0 {
+		return failures
+	}
+	t := throttler.New(workers(agent.Options.ParallelFetches), len(envs))
+	for i, env := range envs {
+		go func(i int, env attestation.Envelope) {
+			failures[i] = agent.verifyEnvelope(env)
+			t.Done(nil)
+		}(i, env)
+		t.Throttle()
+	}
+	return failures
+}
+
+// verifyEnvelope verifies a single envelope. It returns the reason why the
+// envelope is not verified or an empty string if it is.
+func (agent *Agent) verifyEnvelope(env attestation.Envelope) string {
+	if env == nil || env.GetPredicate() == nil {
+		return "envelope has no predicate"
+	}
+
+	if env.GetVerification() == nil {
+		if len(env.GetSignatures()) == 0 {
+			return "unsigned"
+		}
+
+		var err error
+		switch env.(type) {
+		case *bundle.Envelope:
+			switch {
+			case agent.Options.TrustedRoot != nil:
+				err = env.Verify(agent.Options.TrustedRoot)
+			case agent.Options.Offline:
+				// The default trust root may be refreshed from the network
+				err = fmt.Errorf("no trusted root configured: %w", ErrOfflineMode)
+			default:
+				err = env.Verify()
+			}
+		default:
+			err = env.Verify(agent.Options.Keys)
+		}
+		if err != nil {
+			return fmt.Sprintf("verification failed: %v", err)
+		}
+	}
+
+	if v := env.GetVerification(); v == nil || !v.GetVerified() {
+		return "no signature could be verified"
+	}
+	return ""
+}
+
+// annotateVerificationFailure records the reason an envelope could not be
+// verified in its predicate origin.
+func annotateVerificationFailure(env attestation.Envelope, reason string) {
+	rd := originDescriptor(env)
+	if rd == nil {
+		rd = toResourceDescriptor(env.GetPredicate().GetOrigin())
+		env.GetPredicate().SetOrigin(rd)
+	}
+	if rd.Annotations == nil {
+		rd.Annotations = &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	}
+	if rd.Annotations.Fields == nil {
+		rd.Annotations.Fields = map[string]*structpb.Value{}
+	}
+	rd.Annotations.Fields[VerificationAnnotation] = structpb.NewStringValue(reason)
+}
+
+// VerificationFailure returns the reason recorded by the agent when an
+// envelope could not be verified. It returns an empty string if the
+// envelope has no verification annotation.
+func VerificationFailure(env attestation.Envelope) string {
+	rd := originDescriptor(env)
+	if rd == nil || rd.GetAnnotations() == nil {
+		return ""
+	}
+	return rd.GetAnnotations().GetFields()[VerificationAnnotation].GetStringValue()
+}
+
+// loadTrustedRoot reads a sigstore trusted root from a JSON file.
+func loadTrustedRoot(path string) (root.TrustedMaterial, error) {
+	tr, err := root.NewTrustedRootFromPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading trusted root from %q: %w", path, err)
+	}
+	return tr, nil
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: Copyright 2026 Carabiner Systems, Inc
+// SPDX-License-Identifier: Apache-2.0
+
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/carabiner-dev/attestation"
+	sapi "github.com/carabiner-dev/signer/api/v1"
+	sigstoreProtoDSSE "github.com/sigstore/protobuf-specs/gen/pb-go/dsse"
+	"github.com/stretchr/testify/require"
+
+	"github.com/carabiner-dev/collector/envelope/dsse"
+)
+
+// verifiedDSSE returns an envelope carrying verification data, as returned
+// by repositories that verify signatures themselves.
+func verifiedDSSE(payload string) attestation.Envelope {
+	env := testDSSE(payload)
+	env.GetPredicate().SetVerification(&sapi.Verification{
+		Signature: &sapi.SignatureVerification{Verified: true},
+	})
+	return env
+}
+
+func unsignedDSSE(payload string) attestation.Envelope {
+	return &dsse.Envelope{
+		Envelope: &sigstoreProtoDSSE.Envelope{
+			Payload:     []byte(payload),
+			PayloadType: "application/vnd.in-toto+json",
+		},
+	}
+}
+
+func TestVerifyEnvelopes(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		policy  VerificationPolicy
+		envs    func() []attestation.Envelope
+		expect  int
+		failure []string
+	}{
+		{
+			name:   "keep",
+			policy: VerificationPolicyKeep,
+			envs: func() []attestation.Envelope {
+				return []attestation.Envelope{verifiedDSSE(testStatement), unsignedDSSE(testStatement)}
+			},
+			expect:  2,
+			failure: []string{"", ""},
+		},
+		{
+			name:   "annotate",
+			policy: VerificationPolicyAnnotate,
+			envs: func() []attestation.Envelope {
+				return []attestation.Envelope{verifiedDSSE(testStatement), unsignedDSSE(testStatement)}
+			},
+			expect:  2,
+			failure: []string{"", "unsigned"},
+		},
+		{
+			name:   "drop-unsigned",
+			policy: VerificationPolicyDrop,
+			envs: func() []attestation.Envelope {
+				return []attestation.Envelope{unsignedDSSE(testStatement), verifiedDSSE(testStatement)}
+			},
+			expect:  1,
+			failure: []string{""},
+		},
+		{
+			name:   "drop-no-keys",
+			policy: VerificationPolicyDrop,
+			envs: func() []attestation.Envelope {
+				return []attestation.Envelope{testDSSE(testStatement)}
+			},
+			expect: 0,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			agent, err := New(WithVerification(tc.policy))
+			require.NoError(t, err)
+
+			res := agent.verifyEnvelopes(tc.envs())
+			require.Len(t, res, tc.expect)
+			for i, f := range tc.failure {
+				require.Equal(t, f, VerificationFailure(res[i]))
+			}
+		})
+	}
+}
+
+func TestFetchWithVerification(t *testing.T) {
+	t.Parallel()
+	agent, err := New(WithVerification(VerificationPolicyDrop))
+	require.NoError(t, err)
+	require.NoError(t, agent.AddRepository(&fakeFetcher{
+		fetchFunc: func(context.Context, attestation.FetchOptions) ([]attestation.Envelope, error) {
+			return []attestation.Envelope{unsignedDSSE(testStatement), verifiedDSSE(testStatement)}, nil
+		},
+	}))
+
+	res, err := agent.Fetch(t.Context())
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.True(t, res[0].GetVerification().GetVerified())
+
+	_, err = New(WithVerification("bogus"))
+	require.Error(t, err)
+}